@@ -62,6 +62,8 @@ EOF
 echo -e "${GREEN}--- Building Linux Binary ---${NC}"
 LINUX_BIN="gotube-linux-amd64"
 go build -ldflags "-s -w -X 'gotube/internal/models.AppVersion=v$VERSION'" -o dist/$LINUX_BIN ./cmd/gotube
+# Headless build for servers without OpenGL/X11
+go build -ldflags "-s -w -X 'gotube/internal/models.AppVersion=v$VERSION'" -o dist/gotube-cli-linux-amd64 ./cmd/gotube-cli
 
 # --- 2. Windows Binary (Standardized Name) ---
 if [ "$BUILD_WINDOWS" = true ]; then
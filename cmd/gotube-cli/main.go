@@ -0,0 +1,12 @@
+// Command gotube-cli runs GoTube's subcommands without linking the GUI, so
+// it works on servers that have no OpenGL or X11 libraries.
+package main
+
+import (
+	"gotube/internal/cli"
+	"os"
+)
+
+func main() {
+	os.Exit(cli.Run(os.Args[1:], os.Stdout, os.Stderr))
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"gotube/internal/cli"
 	"gotube/internal/gui"
 	"os"
 	"path/filepath"
@@ -9,6 +10,11 @@ import (
 )
 
 func main() {
+	// Subcommands run headless, before anything touches Fyne
+	if len(os.Args) > 1 && cli.IsCommand(os.Args[1]) {
+		os.Exit(cli.Run(os.Args[1:], os.Stdout, os.Stderr))
+	}
+
 	home, _ := os.UserHomeDir()
 	configPath := filepath.Join(home, ".config", "gotube")
 	os.MkdirAll(configPath, 0755)
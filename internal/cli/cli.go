@@ -0,0 +1,163 @@
+// Package cli implements GoTube's headless subcommands. It shares the
+// engine, database and updater with the GUI but never touches Fyne.
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"gotube/internal/database"
+	"gotube/internal/downloader"
+	"gotube/internal/models"
+	"gotube/internal/updater"
+	"gotube/internal/utils"
+	"io"
+	"os"
+	"text/tabwriter"
+)
+
+// Exit codes
+const (
+	ExitOK          = 0
+	ExitFailed      = 1 // The command or download failed
+	ExitUsage       = 2 // Bad arguments
+	ExitPartial     = 3 // Some batch items failed
+	ExitInterrupted = 130
+)
+
+type command struct {
+	name    string
+	args    string
+	summary string
+	run     func(env *env, args []string) int
+}
+
+var commands = []command{
+	{"download", "[flags] <url>", "Download a video or playlist", runDownload},
+	{"batch", "[flags] <file|->", "Download every URL in a file, one per line", runBatch},
+	{"info", "[--json] [--formats] <url>", "Show metadata and available formats", runInfo},
+	{"history", "[--json] [--limit n]", "List finished downloads", runHistory},
+	{"serve", "[--addr host:port] [--token t]", "Serve the download queue as an HTTP API and web UI", runServe},
+	{"update-core", "[--json]", "Download the latest yt-dlp", runUpdateCore},
+	{"version", "", "Print the GoTube version", runVersion},
+}
+
+// IsCommand reports whether arg names a subcommand, so main can skip the GUI
+func IsCommand(arg string) bool {
+	if arg == "help" || arg == "-h" || arg == "--help" {
+		return true
+	}
+	for _, c := range commands {
+		if c.name == arg {
+			return true
+		}
+	}
+	return false
+}
+
+// env holds what the commands share, set up like the GUI does
+type env struct {
+	stdout   io.Writer
+	stderr   io.Writer
+	db       *database.DB // nil if the database can't be opened
+	binMgr   *updater.BinaryManager
+	engine   *downloader.Engine
+	settings models.AppSettings
+}
+
+// Run executes a subcommand (args excludes the program name) and returns
+// the process exit code
+func Run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 || !IsCommand(args[0]) || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		printUsage(stderr)
+		if len(args) > 0 && IsCommand(args[0]) {
+			return ExitOK
+		}
+		return ExitUsage
+	}
+
+	e := &env{stdout: stdout, stderr: stderr, binMgr: updater.NewBinaryManager()}
+	db, err := database.InitDB()
+	if err != nil {
+		fmt.Fprintln(stderr, "warning: database unavailable, history and archive are disabled:", err)
+	} else {
+		e.db = db
+		e.settings = db.LoadSettings()
+	}
+	if e.settings.LastSavePath == "" {
+		e.settings.LastSavePath, _ = os.Getwd()
+	}
+	e.engine = downloader.NewEngine(e.binMgr.GetYtDlpPath())
+	if yt, err := e.engine.Backend(downloader.BackendYtDlp); err == nil {
+		yt.(*downloader.YtDlp).SelfUpdate = e.binMgr.UpdateBinary
+	}
+	e.engine.SetRetryPolicy(downloader.RetryPolicyFromSettings(e.settings))
+	utils.SetNetwork(e.settings.Network)
+
+	for _, c := range commands {
+		if c.name == args[0] {
+			return c.run(e, args[1:])
+		}
+	}
+	return ExitUsage
+}
+
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: gotube <command> [flags]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Without a command gotube starts the GUI; gotube-cli, the headless build,")
+	fmt.Fprintln(w, "only runs commands. Commands:")
+	tw := tabwriter.NewWriter(w, 0, 4, 3, ' ', 0)
+	for _, c := range commands {
+		fmt.Fprintf(tw, "  %s %s\t%s\n", c.name, c.args, c.summary)
+	}
+	tw.Flush()
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Run 'gotube <command> -h' for the flags of a command.")
+}
+
+// newFlagSet returns a flag set that reports errors instead of exiting
+func newFlagSet(e *env, name string) *flag.FlagSet {
+	fs := flag.NewFlagSet("gotube "+name, flag.ContinueOnError)
+	fs.SetOutput(e.stderr)
+	return fs
+}
+
+// parseArgs parses flags that may appear before or after the positional
+// arguments, e.g. "gotube download <url> -o ~/Videos"
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// flagExit maps a parse error to an exit code; -h is not an error
+func flagExit(err error) int {
+	if errors.Is(err, flag.ErrHelp) {
+		return ExitOK
+	}
+	return ExitUsage
+}
+
+// usageError reports bad arguments the way the flag package does
+func usageError(e *env, fs *flag.FlagSet, msg string) int {
+	fmt.Fprintln(e.stderr, msg)
+	fs.Usage()
+	return ExitUsage
+}
+
+func writeJSON(w io.Writer, v any) {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
@@ -0,0 +1,91 @@
+package cli
+
+import (
+	"flag"
+	"gotube/internal/models"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseArgsInterspersed(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	flags := addConfigFlags(fs, models.AppSettings{LastSavePath: "/home/me", FormatPrefs: models.FormatPrefs{Container: "mkv"}})
+	pos, err := parseArgs(fs, []string{"--mode", "audio", "https://youtu.be/x", "-o", "/music", "--force"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(pos, []string{"https://youtu.be/x"}) {
+		t.Errorf("positional = %q", pos)
+	}
+	c := flags.config(pos[0])
+	if c.DownloadMode != "Audio" || c.Quality != "mp3" || c.OutputPath != "/music" || !c.ForceRedownload || c.Prefs.Container != "mkv" {
+		t.Errorf("unexpected config %+v", c)
+	}
+
+	if _, err := parseArgs(fs, []string{"--nope"}); err == nil {
+		t.Error("unknown flag accepted")
+	}
+	if _, err := parseArgs(fs, []string{"--mode", "auido", "u"}); err == nil {
+		t.Error("unknown mode accepted")
+	}
+}
+
+func TestReadURLs(t *testing.T) {
+	urls, err := readURLs(strings.NewReader("# favourites\nhttps://a/1\n\n  https://a/2  \n#https://a/3\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"https://a/1", "https://a/2"}; !slices.Equal(urls, want) {
+		t.Errorf("got %q, want %q", urls, want)
+	}
+}
+
+func TestRangeFlags(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	flags := addConfigFlags(fs, models.AppSettings{})
+	if _, err := parseArgs(fs, []string{"--range", "1:30-3:00", "--range", "1:00-2:00", "--trim-start", "10:00", "--concat-ranges", "u"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := flags.validate(0); err != nil {
+		t.Fatal(err)
+	}
+	c := flags.config("u")
+	want := []models.TimeRange{{Start: 60, End: 180}, {Start: 600}}
+	if !slices.Equal(c.Ranges, want) || !c.ConcatRanges {
+		t.Errorf("ranges = %v, concat = %v; want %v", c.Ranges, c.ConcatRanges, want)
+	}
+	if _, err := flags.validate(300); err == nil {
+		t.Error("range past the end of a 5 minute video accepted")
+	}
+}
+
+func TestSponsorBlockFlags(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	flags := addConfigFlags(fs, models.AppSettings{SponsorBlock: map[string]string{"sponsor": "mark"}})
+	if _, err := parseArgs(fs, []string{"--sponsorblock", "u"}); err != nil {
+		t.Fatal(err)
+	}
+	if c := flags.config("u"); !c.UseSponsorBlock || c.SponsorBlock["sponsor"] != "mark" {
+		t.Errorf("saved actions not applied: %+v", c.SponsorBlock)
+	}
+
+	if _, err := parseArgs(fs, []string{"--sponsorblock-remove", "sponsor, intro", "--sponsorblock-mark", "outro", "u"}); err != nil {
+		t.Fatal(err)
+	}
+	c := flags.config("u")
+	if c.SponsorBlock["sponsor"] != "remove" || c.SponsorBlock["intro"] != "remove" || c.SponsorBlock["outro"] != "mark" || c.SponsorBlock["filler"] != "ignore" {
+		t.Errorf("actions = %v", c.SponsorBlock)
+	}
+
+	if _, err := parseArgs(fs, []string{"--sponsorblock-remove", "ads", "u"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := flags.validate(0); err == nil {
+		t.Error("unknown category accepted")
+	}
+}
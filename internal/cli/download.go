@@ -0,0 +1,490 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"gotube/internal/downloader"
+	"gotube/internal/models"
+	"gotube/internal/queue"
+	"gotube/internal/utils"
+	"io"
+	"maps"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// configFlags mirrors models.DownloadConfig, with defaults from the GUI settings
+type configFlags struct {
+	output, mode, quality, format           string
+	trimStart, trimEnd                      string
+	sponsorBlock, safe, force               bool
+	client, cookies, backend                string
+	playlist                                bool
+	items                                   string
+	subs, autoSubs                          bool
+	subLang                                 string
+	container, vcodec, acodec, dynamicRange string
+	maxFPS                                  int
+	template                                string
+	at                                      string
+	rate                                    int64
+	proxy, sourceAddress, caBundle          string
+	ipv4, ipv6                              bool
+	chapters                                []string
+	splitChapters, chapterMarkers           bool
+	ranges                                  []string
+	concatRanges                            bool
+	timeRanges                              []models.TimeRange // Parsed by validate
+	sponsorRemove, sponsorMark              string
+	subLangs, subFormat                     string
+	writeSubs                               bool
+	savedSponsorBlock                       map[string]string
+}
+
+func addConfigFlags(fs *flag.FlagSet, s models.AppSettings) *configFlags {
+	c := &configFlags{savedSponsorBlock: s.SponsorBlock, mode: "video"}
+	fs.StringVar(&c.output, "o", s.LastSavePath, "save folder")
+	fs.Func("mode", "video or audio (default video)", func(s string) error {
+		if !strings.EqualFold(s, "video") && !strings.EqualFold(s, "audio") {
+			return errors.New("must be video or audio")
+		}
+		c.mode = s
+		return nil
+	})
+	fs.StringVar(&c.quality, "quality", "", "Best, 4k, 1080p, 720p; audio: Best, mp3, m4a, opus, flac, wav, ogg")
+	fs.StringVar(&c.format, "format", "", "exact yt-dlp format selector, e.g. 137+140 (overrides -quality)")
+	fs.StringVar(&c.trimStart, "trim-start", "", "start of the section to download (same as -range start-end)")
+	fs.StringVar(&c.trimEnd, "trim-end", "", "end of the section to download")
+	fs.BoolVar(&c.sponsorBlock, "sponsorblock", false, "apply the SponsorBlock settings (default: remove all segments)")
+	fs.StringVar(&c.sponsorRemove, "sponsorblock-remove", "", "SponsorBlock categories to cut out, e.g. sponsor,selfpromo")
+	fs.StringVar(&c.sponsorMark, "sponsorblock-mark", "", "SponsorBlock categories to mark as chapters, e.g. intro,outro")
+	fs.StringVar(&c.client, "client", s.ClientSpoof, "YouTube client: Web, Android or iOS")
+	fs.StringVar(&c.cookies, "cookies", s.CookiesPath, "cookies.txt file")
+	fs.BoolVar(&c.safe, "safe", false, "safe mode: best single file, minimal options")
+	fs.BoolVar(&c.playlist, "playlist", false, "download the whole playlist")
+	fs.StringVar(&c.items, "items", "", "playlist items to download, e.g. 1,3,5-7")
+	fs.BoolVar(&c.subs, "subs", false, "embed subtitles")
+	fs.BoolVar(&c.autoSubs, "auto-subs", false, "include auto-generated subtitles")
+	fs.StringVar(&c.subLang, "sub-lang", "en", "subtitle language: en, de or all")
+	fs.StringVar(&c.subLangs, "sub-langs", "", "exact subtitle languages as listed by 'info', e.g. en,de-DE (overrides -sub-lang)")
+	fs.BoolVar(&c.writeSubs, "write-subs", false, "save subtitles as files next to the video")
+	fs.StringVar(&c.subFormat, "sub-format", "srt", "format of saved subtitle files: srt, vtt or ass")
+	fs.StringVar(&c.backend, "backend", "", "yt-dlp or http (default: by URL)")
+	fs.StringVar(&c.container, "container", s.FormatPrefs.Container, "mp4, mkv or webm")
+	fs.StringVar(&c.vcodec, "vcodec", s.FormatPrefs.VideoCodec, "preferred video codec: avc1, vp9 or av01")
+	fs.StringVar(&c.acodec, "acodec", s.FormatPrefs.AudioCodec, "preferred audio codec: aac or opus")
+	fs.StringVar(&c.dynamicRange, "dynamic-range", s.FormatPrefs.DynamicRange, "hdr or sdr")
+	fs.IntVar(&c.maxFPS, "max-fps", s.FormatPrefs.MaxFPS, "preferred maximum frame rate")
+	fs.StringVar(&c.template, "template", s.OutputTemplate, "output file name template, e.g. %(uploader)s/%(title)s.%(ext)s")
+	fs.BoolVar(&c.force, "force", false, "download even if the archive lists the video")
+	fs.StringVar(&c.at, "at", "", "start at HH:MM or \"YYYY-MM-DD HH:MM\" instead of now")
+	fs.Func("limit-rate", "maximum speed per download in bytes/s, e.g. 500K or 2M", func(s string) error {
+		rate, err := downloader.ParseRate(s)
+		c.rate = rate
+		return err
+	})
+	fs.StringVar(&c.proxy, "proxy", "", "proxy URL, e.g. socks5://127.0.0.1:1080 (default: from the settings)")
+	fs.StringVar(&c.sourceAddress, "source-address", "", "local IP address to connect from")
+	fs.BoolVar(&c.ipv4, "force-ipv4", false, "connect over IPv4 only")
+	fs.BoolVar(&c.ipv6, "force-ipv6", false, "connect over IPv6 only")
+	fs.StringVar(&c.caBundle, "ca-bundle", "", "PEM file of extra trusted CA certificates")
+	fs.Func("chapter", "download only this chapter, as its own file (repeatable; titles as listed by 'info')", func(s string) error {
+		c.chapters = append(c.chapters, s)
+		return nil
+	})
+	fs.BoolVar(&c.splitChapters, "split-chapters", false, "also write one file per chapter")
+	fs.BoolVar(&c.chapterMarkers, "chapter-markers", false, "embed chapter markers")
+	fs.Func("range", "download only this time range, e.g. 1:00-2:30, 90-, or 25%-50% (repeatable)", func(s string) error {
+		c.ranges = append(c.ranges, s)
+		return nil
+	})
+	fs.BoolVar(&c.concatRanges, "concat-ranges", false, "join the ranges into one file instead of one file each")
+	return c
+}
+
+func (c *configFlags) config(url string) models.DownloadConfig {
+	mode := "Video"
+	quality := c.quality
+	if strings.EqualFold(c.mode, "audio") {
+		mode = "Audio"
+		if quality == "" {
+			quality = "mp3"
+		}
+	}
+	if quality == "" {
+		quality = "Best"
+	}
+	return models.DownloadConfig{
+		URL:             url,
+		OutputPath:      c.output,
+		DownloadMode:    mode,
+		Quality:         quality,
+		UseSponsorBlock: c.sponsorBlock || c.sponsorRemove != "" || c.sponsorMark != "",
+		SponsorBlock:    c.sponsorActions(),
+		Client:          c.client,
+		CookiesPath:     c.cookies,
+		SafeMode:        c.safe,
+		IsPlaylist:      c.playlist || c.items != "",
+		PlaylistItems:   c.items,
+		EmbedSubs:       c.subs,
+		AutoSubs:        c.autoSubs,
+		SubLanguage:     c.subLang,
+		SubLanguages:    c.subLanguages(),
+		WriteSubs:       c.writeSubs,
+		SubFormat:       c.subFormat,
+		Backend:         c.backend,
+		FormatID:        c.format,
+		Prefs: models.FormatPrefs{
+			Container:    c.container,
+			VideoCodec:   c.vcodec,
+			AudioCodec:   c.acodec,
+			DynamicRange: c.dynamicRange,
+			MaxFPS:       c.maxFPS,
+		},
+		OutputTemplate:  c.template,
+		ForceRedownload: c.force,
+		RateLimit:       c.rate,
+		Network:         c.network(),
+
+		Chapters:      c.chapters,
+		SplitChapters: c.splitChapters,
+		EmbedChapters: c.chapterMarkers,
+		Ranges:        c.timeRanges,
+		ConcatRanges:  c.concatRanges,
+	}
+}
+
+// sponsorActions returns the SponsorBlock actions: those of
+// -sponsorblock-remove and -sponsorblock-mark, or else the saved ones
+func (c *configFlags) sponsorActions() map[string]string {
+	if c.sponsorRemove == "" && c.sponsorMark == "" {
+		if !c.sponsorBlock {
+			return nil
+		}
+		return maps.Clone(c.savedSponsorBlock)
+	}
+	actions := make(map[string]string)
+	for _, cat := range models.SponsorCategories {
+		actions[cat] = models.SponsorIgnore
+	}
+	for _, cat := range strings.Split(c.sponsorMark, ",") {
+		if cat = strings.TrimSpace(cat); cat != "" {
+			actions[cat] = models.SponsorMark
+		}
+	}
+	for _, cat := range strings.Split(c.sponsorRemove, ",") {
+		if cat = strings.TrimSpace(cat); cat != "" {
+			actions[cat] = models.SponsorRemove
+		}
+	}
+	return actions
+}
+
+func (c *configFlags) subLanguages() []string {
+	var langs []string
+	for _, lang := range strings.Split(c.subLangs, ",") {
+		if lang = strings.TrimSpace(lang); lang != "" {
+			langs = append(langs, lang)
+		}
+	}
+	return langs
+}
+
+// rangeList joins -range and -trim-start/-trim-end for downloader.ParseRanges
+func (c *configFlags) rangeList() string {
+	ranges := c.ranges
+	if c.trimStart != "" || c.trimEnd != "" {
+		ranges = append(ranges, c.trimStart+"-"+c.trimEnd)
+	}
+	return strings.Join(ranges, ",")
+}
+
+// network returns the per-job network settings; the saved ones fill the gaps
+func (c *configFlags) network() models.NetworkConfig {
+	n := models.NetworkConfig{Proxy: c.proxy, SourceAddress: c.sourceAddress, CABundle: c.caBundle}
+	if c.ipv4 {
+		n.IPVersion = 4
+	}
+	if c.ipv6 {
+		n.IPVersion = 6
+	}
+	return n
+}
+
+// validate checks the flags that can't be checked while parsing and
+// returns the start time. The ranges are checked against duration, unless
+// it is 0.
+func (c *configFlags) validate(duration int) (time.Time, error) {
+	if c.ipv4 && c.ipv6 {
+		return time.Time{}, errors.New("-force-ipv4 and -force-ipv6 are exclusive")
+	}
+	if len(c.chapters) > 0 && c.rangeList() != "" {
+		return time.Time{}, errors.New("-chapter and -range are exclusive")
+	}
+	if !slices.Contains(downloader.SubFormats, c.subFormat) {
+		return time.Time{}, errors.New("-sub-format must be srt, vtt or ass")
+	}
+	if err := downloader.ValidateSponsorBlock(c.sponsorActions()); err != nil {
+		return time.Time{}, err
+	}
+	ranges, err := downloader.ParseRanges(c.rangeList(), duration)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("-range %w", err)
+	}
+	c.timeRanges = ranges
+	if err := utils.ValidateNetwork(c.network()); err != nil {
+		return time.Time{}, err
+	}
+	return queue.ParseStartTime(c.at, time.Now())
+}
+
+// jobResult is the JSON output for one download
+type jobResult struct {
+	URL       string `json:"url"`
+	Title     string `json:"title"`
+	State     string `json:"state"`
+	Error     string `json:"error,omitempty"`
+	ErrorKind string `json:"error_kind,omitempty"`
+	Output    string `json:"output"`
+}
+
+func runDownload(e *env, args []string) int {
+	fs := newFlagSet(e, "download")
+	flags := addConfigFlags(fs, e.settings)
+	asJSON := fs.Bool("json", false, "print the result as JSON")
+	urls, err := parseArgs(fs, args)
+	if err != nil {
+		return flagExit(err)
+	}
+	if len(urls) != 1 {
+		return usageError(e, fs, "expected exactly one URL")
+	}
+
+	// Ranges are checked against the video's length
+	var duration int
+	if flags.rangeList() != "" {
+		config := flags.config(urls[0])
+		b, err := e.engine.BackendFor(config)
+		if err != nil {
+			return usageError(e, fs, err.Error())
+		}
+		ctx := downloader.WithNetwork(context.Background(), utils.Network().Merge(config.Network))
+		meta, err := b.GetMetadata(ctx, config.URL)
+		if err != nil {
+			fmt.Fprintln(e.stderr, "error:", err)
+			return ExitFailed
+		}
+		duration = meta.Duration
+	}
+	at, err := flags.validate(duration)
+	if err != nil {
+		return usageError(e, fs, err.Error())
+	}
+
+	results, interrupted := runJobs(e, []models.DownloadConfig{flags.config(urls[0])}, at, 1, *asJSON)
+	if *asJSON {
+		writeJSON(e.stdout, results[0])
+	}
+	switch {
+	case interrupted:
+		return ExitInterrupted
+	case results[0].State != string(queue.StateDone):
+		return ExitFailed
+	}
+	return ExitOK
+}
+
+func runBatch(e *env, args []string) int {
+	fs := newFlagSet(e, "batch")
+	flags := addConfigFlags(fs, e.settings)
+	asJSON := fs.Bool("json", false, "print the results as JSON")
+	parallel := fs.Int("parallel", max(e.settings.MaxConcurrent, 1), "downloads to run at once")
+	files, err := parseArgs(fs, args)
+	if err != nil {
+		return flagExit(err)
+	}
+	if len(files) != 1 {
+		return usageError(e, fs, "expected one file of URLs, or - for stdin")
+	}
+	if *parallel < 1 {
+		return usageError(e, fs, "-parallel must be at least 1")
+	}
+	// Videos differ in length, so only the format of the ranges is checked
+	at, err := flags.validate(0)
+	if err != nil {
+		return usageError(e, fs, err.Error())
+	}
+
+	var r io.Reader = os.Stdin
+	if files[0] != "-" {
+		f, err := os.Open(files[0])
+		if err != nil {
+			fmt.Fprintln(e.stderr, err)
+			return ExitFailed
+		}
+		defer f.Close()
+		r = f
+	}
+	urls, err := readURLs(r)
+	if err != nil {
+		fmt.Fprintln(e.stderr, err)
+		return ExitFailed
+	}
+	if len(urls) == 0 {
+		fmt.Fprintln(e.stderr, "no URLs in", files[0])
+		return ExitUsage
+	}
+
+	configs := make([]models.DownloadConfig, len(urls))
+	for i, u := range urls {
+		configs[i] = flags.config(u)
+	}
+	results, interrupted := runJobs(e, configs, at, *parallel, *asJSON)
+	if *asJSON {
+		writeJSON(e.stdout, results)
+	}
+
+	failed := 0
+	for _, r := range results {
+		if r.State != string(queue.StateDone) {
+			failed++
+		}
+	}
+	switch {
+	case interrupted:
+		return ExitInterrupted
+	case failed == len(results):
+		return ExitFailed
+	case failed > 0:
+		return ExitPartial
+	}
+	return ExitOK
+}
+
+// readURLs returns the non-empty lines of r, skipping # comments
+func readURLs(r io.Reader) ([]string, error) {
+	var urls []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			urls = append(urls, line)
+		}
+	}
+	return urls, scanner.Err()
+}
+
+// runJobs downloads through a queue.Manager, so the archive and history
+// work like in the GUI, and waits until every job has finished. The GUI's
+// saved queue is left alone. Jobs wait until at, if set, and share the
+// global speed limit but ignore the download windows. Progress goes to
+// stderr unless quiet. Ctrl+C stops the downloads but keeps their partial
+// files.
+func runJobs(e *env, configs []models.DownloadConfig, at time.Time, workers int, quiet bool) ([]jobResult, bool) {
+	m := queue.NewManager(e.engine, e.db, workers)
+	m.SetPolicy(queue.Policy{Limit: queue.PolicyFromSettings(e.settings).Limit})
+
+	var mu sync.Mutex
+	ids := make(map[int]bool)
+	lastPrint := make(map[int]time.Time)
+	lastState := make(map[int]queue.State)
+	changed := make(chan struct{}, 1)
+	m.Subscribe(func(job queue.Job) {
+		select {
+		case changed <- struct{}{}:
+		default:
+		}
+		if quiet {
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		// Throttle progress lines, but always report a new state
+		if lastState[job.ID] == job.State && time.Since(lastPrint[job.ID]) < time.Second {
+			return
+		}
+		lastState[job.ID] = job.State
+		lastPrint[job.ID] = time.Now()
+		fmt.Fprintf(e.stderr, "[%d/%d] %s: %s\n", job.ID, len(configs), job.Title, describeJob(job))
+	})
+
+	if !at.IsZero() && !quiet {
+		fmt.Fprintf(e.stderr, "Waiting until %s\n", at.Format("2006-01-02 15:04"))
+	}
+	for _, config := range configs {
+		ids[m.SubmitAt(config, nil, queue.PriorityNormal, at).ID] = true
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	interrupted := false
+wait:
+	for !allFinished(m, ids) {
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			interrupted = true
+			break wait
+		}
+	}
+	m.Shutdown()
+
+	var results []jobResult
+	for _, job := range m.Jobs() {
+		r := jobResult{URL: job.Config.URL, Title: job.Title, State: string(job.State), Error: job.Err, Output: job.Config.OutputPath}
+		if job.State == queue.StateFailed {
+			r.ErrorKind = job.ErrKind.String()
+		}
+		if interrupted && !job.State.Finished() {
+			r.State = "interrupted"
+		}
+		results = append(results, r)
+	}
+	return results, interrupted
+}
+
+func allFinished(m *queue.Manager, ids map[int]bool) bool {
+	for id := range ids {
+		if job, ok := m.Get(id); ok && !job.State.Finished() {
+			return false
+		}
+	}
+	return true
+}
+
+// describeJob renders a job's state for the terminal, e.g.
+// "downloading 42.0% • 3.1 MiB/s • ETA 0:12"
+func describeJob(job queue.Job) string {
+	parts := []string{string(job.State)}
+	switch job.State {
+	case queue.StateDownloading:
+		u := job.Progress
+		if u.Percent > 0 {
+			parts = append(parts, fmt.Sprintf("%.1f%%", u.Percent*100))
+		}
+		if u.Speed > 0 {
+			parts = append(parts, utils.FormatBytes(int64(u.Speed))+"/s")
+		}
+		if u.ETA > 0 {
+			parts = append(parts, "ETA "+utils.FormatETA(u.ETA))
+		}
+		if u.PlaylistCount > 1 {
+			parts = append(parts, fmt.Sprintf("item %d/%d", u.PlaylistIndex, u.PlaylistCount))
+		}
+	case queue.StatePostProcessing:
+		if job.Progress.PostProcessor != "" {
+			parts = append(parts, job.Progress.PostProcessor)
+		}
+	case queue.StateFailed:
+		parts = append(parts, job.Err)
+	}
+	return strings.Join(parts, " • ")
+}
@@ -0,0 +1,194 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"gotube/internal/downloader"
+	"gotube/internal/models"
+	"gotube/internal/utils"
+	"strings"
+	"text/tabwriter"
+)
+
+func runInfo(e *env, args []string) int {
+	fs := newFlagSet(e, "info")
+	asJSON := fs.Bool("json", false, "print the full metadata as JSON")
+	formats := fs.Bool("formats", false, "list the available formats")
+	subs := fs.Bool("subs", false, "list the available subtitle languages")
+	backend := fs.String("backend", "", "yt-dlp or http (default: by URL)")
+	urls, err := parseArgs(fs, args)
+	if err != nil {
+		return flagExit(err)
+	}
+	if len(urls) != 1 {
+		return usageError(e, fs, "expected exactly one URL")
+	}
+
+	b, err := e.engine.BackendFor(models.DownloadConfig{URL: urls[0], Backend: *backend})
+	if err != nil {
+		return usageError(e, fs, err.Error())
+	}
+	meta, err := b.GetMetadata(context.Background(), urls[0])
+	if err != nil {
+		fmt.Fprintln(e.stderr, "error:", err)
+		return ExitFailed
+	}
+	if *asJSON {
+		writeJSON(e.stdout, meta)
+		return ExitOK
+	}
+
+	w := tabwriter.NewWriter(e.stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "Title:\t%s\n", meta.Title)
+	fmt.Fprintf(w, "ID:\t%s\n", meta.ID)
+	if meta.Uploader != "" {
+		fmt.Fprintf(w, "Uploader:\t%s\n", meta.Uploader)
+	}
+	if meta.Type == "playlist" {
+		fmt.Fprintf(w, "Playlist:\t%d videos\n", meta.EntryCount)
+	} else if meta.Duration > 0 {
+		fmt.Fprintf(w, "Duration:\t%s\n", utils.FormatETA(meta.Duration))
+	}
+	if tracks := meta.SubtitleTracks(); len(tracks) > 0 {
+		fmt.Fprintf(w, "Subtitles:\t%d languages\n", len(tracks))
+	}
+	w.Flush()
+
+	if meta.Type == "playlist" {
+		for i, entry := range meta.Entries {
+			fmt.Fprintf(e.stdout, "%4d. %s\n", i+1, entry.Title)
+		}
+	}
+	if len(meta.Chapters) > 0 {
+		fmt.Fprintln(e.stdout, "Chapters:")
+		for i, c := range meta.Chapters {
+			fmt.Fprintf(e.stdout, "%4d. %s  %s-%s\n", i+1, c.Title, utils.FormatETA(int(c.Start)), utils.FormatETA(int(c.End)))
+		}
+	}
+	if tracks := meta.SubtitleTracks(); *subs && len(tracks) > 0 {
+		fmt.Fprintln(e.stdout)
+		w = tabwriter.NewWriter(e.stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "LANGUAGE\tNAME\tKIND")
+		for _, t := range tracks {
+			kind := "manual"
+			if t.Auto {
+				kind = "auto-generated"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\n", t.Language, t.Name, kind)
+		}
+		w.Flush()
+	}
+	if *formats && len(meta.Formats) > 0 {
+		fmt.Fprintln(e.stdout)
+		w = tabwriter.NewWriter(e.stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tEXT\tRESOLUTION\tFPS\tVCODEC\tACODEC\tTBR\tSIZE\tNOTE")
+		for _, f := range meta.Formats {
+			size := "-"
+			if n := f.Size(meta.Duration); n > 0 {
+				size = utils.FormatBytes(n)
+				if f.FileSize == 0 {
+					size = "~" + size
+				}
+			}
+			note := f.Note
+			if f.IsHDR() {
+				note = strings.TrimSpace(note + " " + f.DynamicRange)
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", f.ID, f.Ext, dash(f.Resolution), number(f.FPS),
+				dash(f.VCodec), dash(f.ACodec), number(f.TBR), size, note)
+		}
+		w.Flush()
+	}
+	return ExitOK
+}
+
+func runHistory(e *env, args []string) int {
+	fs := newFlagSet(e, "history")
+	asJSON := fs.Bool("json", false, "print the history as JSON")
+	limit := fs.Int("limit", 0, "show at most this many entries")
+	if _, err := parseArgs(fs, args); err != nil {
+		return flagExit(err)
+	}
+	if e.db == nil {
+		fmt.Fprintln(e.stderr, "error: database unavailable")
+		return ExitFailed
+	}
+
+	history := e.db.GetHistory()
+	if *limit > 0 && len(history) > *limit {
+		history = history[:*limit]
+	}
+	if *asJSON {
+		type entry struct {
+			Title string `json:"title"`
+			URL   string `json:"url"`
+			Path  string `json:"path"`
+		}
+		entries := make([]entry, len(history))
+		for i, h := range history {
+			entries[i] = entry{Title: h.Title, URL: h.URL, Path: h.FilePath}
+		}
+		writeJSON(e.stdout, entries)
+		return ExitOK
+	}
+	w := tabwriter.NewWriter(e.stdout, 0, 4, 2, ' ', 0)
+	for _, h := range history {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", h.Title, h.URL, h.FilePath)
+	}
+	w.Flush()
+	return ExitOK
+}
+
+func runUpdateCore(e *env, args []string) int {
+	fs := newFlagSet(e, "update-core")
+	asJSON := fs.Bool("json", false, "print the new version as JSON")
+	if _, err := parseArgs(fs, args); err != nil {
+		return flagExit(err)
+	}
+
+	b, err := e.engine.Backend(downloader.BackendYtDlp)
+	if err == nil {
+		err = b.Update(context.Background(), func(msg string) {
+			if !*asJSON {
+				fmt.Fprintln(e.stderr, msg)
+			}
+		})
+	}
+	if err != nil {
+		fmt.Fprintln(e.stderr, "error:", err)
+		return ExitFailed
+	}
+
+	// The binary may have moved from PATH to the config folder
+	yt := downloader.NewYtDlp(e.binMgr.GetYtDlpPath())
+	version, err := yt.Version(context.Background())
+	if err != nil {
+		fmt.Fprintln(e.stderr, "error: updated, but the new binary does not run:", err)
+		return ExitFailed
+	}
+	if *asJSON {
+		writeJSON(e.stdout, map[string]string{"version": version, "path": yt.BinaryPath})
+	} else {
+		fmt.Fprintf(e.stdout, "yt-dlp %s (%s)\n", version, yt.BinaryPath)
+	}
+	return ExitOK
+}
+
+func runVersion(e *env, args []string) int {
+	fmt.Fprintln(e.stdout, "GoTube", models.AppVersion)
+	return ExitOK
+}
+
+func dash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func number(f float64) string {
+	if f == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.0f", f)
+}
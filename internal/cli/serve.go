@@ -0,0 +1,122 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gotube/internal/database"
+	"gotube/internal/models"
+	"gotube/internal/queue"
+	"gotube/internal/server"
+	"gotube/internal/subscriptions"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// DefaultAddr only listens on this machine
+const DefaultAddr = "127.0.0.1:8765"
+
+// runServe runs the queue with the saved jobs and the subscriptions, and
+// serves the HTTP API until interrupted. It refuses to start while the GUI
+// or another daemon owns the saved queue.
+func runServe(e *env, args []string) int {
+	fs := newFlagSet(e, "serve")
+	addr := fs.String("addr", DefaultAddr, "address to listen on")
+	token := fs.String("token", "", "API token (default: the saved one, generated on first start)")
+	newToken := fs.Bool("new-token", false, "generate and save a new API token")
+	parallel := fs.Int("parallel", max(e.settings.MaxConcurrent, 1), "downloads to run at once")
+	if _, err := parseArgs(fs, args); err != nil {
+		return flagExit(err)
+	}
+	if *parallel < 1 {
+		return usageError(e, fs, "-parallel must be at least 1")
+	}
+	if e.db == nil {
+		fmt.Fprintln(e.stderr, "error: database unavailable")
+		return ExitFailed
+	}
+
+	unlock, err := database.LockQueue()
+	if err != nil {
+		fmt.Fprintln(e.stderr, "error:", err)
+		return ExitFailed
+	}
+	defer unlock()
+
+	if *token == "" {
+		*token = e.db.GetSetting("APIToken")
+		if *token == "" || *newToken {
+			t, err := server.NewToken()
+			if err == nil {
+				err = e.db.SaveSetting("APIToken", t)
+			}
+			if err != nil {
+				fmt.Fprintln(e.stderr, "error: could not create an API token:", err)
+				return ExitFailed
+			}
+			*token = t
+		}
+	}
+
+	ln, err := net.Listen("tcp", *addr)
+	if err != nil {
+		fmt.Fprintln(e.stderr, "error:", err)
+		return ExitFailed
+	}
+	if host, _, _ := net.SplitHostPort(ln.Addr().String()); !net.ParseIP(host).IsLoopback() {
+		fmt.Fprintln(e.stderr, "warning: the API is reachable from other machines; anyone with the token can start downloads")
+	}
+
+	m := queue.NewManager(e.engine, e.db, *parallel)
+	m.SetPolicy(queue.PolicyFromSettings(e.settings))
+	if n, err := m.Restore(); err != nil {
+		fmt.Fprintln(e.stderr, "warning: could not restore the queue:", err)
+	} else if n > 0 {
+		fmt.Fprintf(e.stderr, "Resumed %d queued download(s)\n", n)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	subs := subscriptions.NewScheduler(e.engine, e.db, m)
+	subs.OnChecked = func(sub models.Subscription, queued int, err error) {
+		if err != nil {
+			fmt.Fprintf(e.stderr, "Subscription %s: %v\n", sub.URL, err)
+		} else if queued > 0 {
+			fmt.Fprintf(e.stderr, "Subscription %s: %d new video(s) queued\n", sub.Title, queued)
+		}
+	}
+	go subs.Run(ctx)
+
+	srv := &http.Server{
+		Handler:           server.New(m, e.engine, e.db, *token),
+		ReadHeaderTimeout: 10 * time.Second,
+		// Ends the event streams on Ctrl+C
+		BaseContext: func(net.Listener) context.Context { return ctx },
+	}
+	fmt.Fprintf(e.stderr, "Listening on http://%s\n", ln.Addr())
+	fmt.Fprintf(e.stderr, "API token: %s\n", *token)
+	fmt.Fprintf(e.stderr, "Web UI: http://%s/#token=%s\n", ln.Addr(), *token)
+
+	errc := make(chan error, 1)
+	go func() { errc <- srv.Serve(ln) }()
+
+	code := ExitOK
+	select {
+	case err := <-errc:
+		fmt.Fprintln(e.stderr, "error:", err)
+		code = ExitFailed
+	case <-ctx.Done():
+		fmt.Fprintln(e.stderr, "Shutting down")
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil && !errors.Is(err, context.DeadlineExceeded) {
+		fmt.Fprintln(e.stderr, "error:", err)
+	}
+	m.Shutdown()
+	return code
+}
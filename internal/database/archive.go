@@ -0,0 +1,100 @@
+package database
+
+import (
+	"bufio"
+	"fmt"
+	"gotube/internal/models"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// The archive remembers downloaded videos per library (save folder), keyed
+// like the lines of a yt-dlp --download-archive file: "<extractor> <id>".
+
+// WriteArchiveFile exports a library's archive as a yt-dlp archive file
+func (d *DB) WriteArchiveFile(library, path string) error {
+	rows, err := d.conn.Query("SELECT extractor, video_id FROM archive WHERE library = ?", filepath.Clean(library))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	for rows.Next() {
+		var extractor, id string
+		if err := rows.Scan(&extractor, &id); err != nil {
+			f.Close()
+			return err
+		}
+		fmt.Fprintf(w, "%s %s\n", extractor, id)
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return rows.Err()
+}
+
+// ImportArchiveFile adds the entries of a yt-dlp archive file to a library
+// and returns how many were new
+func (d *DB) ImportArchiveFile(library, path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	added := 0
+	now := time.Now().Unix()
+	for _, line := range strings.Split(string(data), "\n") {
+		extractor, id, ok := strings.Cut(strings.TrimSpace(line), " ")
+		if !ok {
+			continue
+		}
+		res, err := d.conn.Exec("INSERT OR IGNORE INTO archive (extractor, video_id, library, added) VALUES (?, ?, ?, ?)",
+			strings.ToLower(extractor), strings.TrimSpace(id), filepath.Clean(library), now)
+		if err != nil {
+			return added, err
+		}
+		if n, _ := res.RowsAffected(); n > 0 {
+			added++
+		}
+	}
+	return added, nil
+}
+
+// ListArchive returns every archived video, newest first
+func (d *DB) ListArchive() ([]models.ArchiveEntry, error) {
+	rows, err := d.conn.Query("SELECT extractor, video_id, library, added FROM archive ORDER BY added DESC, rowid DESC")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []models.ArchiveEntry
+	for rows.Next() {
+		var e models.ArchiveEntry
+		if err := rows.Scan(&e.Extractor, &e.VideoID, &e.Library, &e.Added); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+func (d *DB) DeleteArchiveEntry(e models.ArchiveEntry) error {
+	_, err := d.conn.Exec("DELETE FROM archive WHERE extractor = ? AND video_id = ? AND library = ?", e.Extractor, e.VideoID, e.Library)
+	return err
+}
+
+func (d *DB) ClearArchive() error {
+	_, err := d.conn.Exec("DELETE FROM archive")
+	return err
+}
@@ -0,0 +1,33 @@
+package database
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+// ErrQueueLocked means another GoTube process, the GUI or 'gotube serve',
+// already owns the saved download queue
+var ErrQueueLocked = errors.New("the download queue is in use by another GoTube process")
+
+// LockQueue takes the lock file next to the database so only one process
+// restores and saves the queue table. Call the returned function to release
+// it; the OS also releases it when the process exits.
+func LockQueue() (func(), error) {
+	dir := configDir()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(filepath.Join(dir, "queue.lock"), os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if err := lockFile(f); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return func() {
+		unlockFile(f)
+		f.Close()
+	}, nil
+}
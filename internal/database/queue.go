@@ -0,0 +1,47 @@
+package database
+
+import (
+	"encoding/json"
+	"gotube/internal/models"
+)
+
+// SaveQueueEntry inserts or updates a queue job, including its full config
+func (d *DB) SaveQueueEntry(e models.QueueEntry) error {
+	config, err := json.Marshal(e.Config)
+	if err != nil {
+		return err
+	}
+	_, err = d.conn.Exec(`INSERT OR REPLACE INTO queue (id, config, title, thumbnail, state, priority, position, attempts, last_error, added, start_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		e.ID, string(config), e.Title, e.ThumbnailURL, e.State, e.Priority, e.Position, e.Attempts, e.LastError, e.Added, e.StartAt)
+	return err
+}
+
+func (d *DB) DeleteQueueEntry(id int) error {
+	_, err := d.conn.Exec("DELETE FROM queue WHERE id = ?", id)
+	return err
+}
+
+// LoadQueue returns every saved job in queue order
+func (d *DB) LoadQueue() ([]models.QueueEntry, error) {
+	rows, err := d.conn.Query(`SELECT id, config, COALESCE(title, ''), COALESCE(thumbnail, ''), state, priority, position, attempts, COALESCE(last_error, ''), added, COALESCE(start_at, 0)
+		FROM queue ORDER BY position, id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []models.QueueEntry
+	for rows.Next() {
+		var e models.QueueEntry
+		var config string
+		if err := rows.Scan(&e.ID, &config, &e.Title, &e.ThumbnailURL, &e.State, &e.Priority, &e.Position, &e.Attempts, &e.LastError, &e.Added, &e.StartAt); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(config), &e.Config); err != nil {
+			continue // Skip rows written by an incompatible version
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
@@ -2,19 +2,27 @@ package database
 
 import (
 	"database/sql"
+	"encoding/json"
 	"gotube/internal/models"
 	_ "github.com/mattn/go-sqlite3"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 )
 
 type DB struct {
 	conn *sql.DB
 }
 
-func InitDB() (*DB, error) {
+// configDir holds the database and the queue lock
+func configDir() string {
 	home, _ := os.UserHomeDir()
-	dbPath := filepath.Join(home, ".config", "gotube")
+	return filepath.Join(home, ".config", "gotube")
+}
+
+func InitDB() (*DB, error) {
+	dbPath := configDir()
 	os.MkdirAll(dbPath, 0755)
 	
 	db, err := sql.Open("sqlite3", filepath.Join(dbPath, "data.db"))
@@ -23,9 +31,16 @@ func InitDB() (*DB, error) {
 	createTables := `
 	CREATE TABLE IF NOT EXISTS settings (key TEXT PRIMARY KEY, value TEXT);
 	CREATE TABLE IF NOT EXISTS history (id INTEGER PRIMARY KEY, title TEXT, url TEXT, path TEXT, timestamp INTEGER);
+	CREATE TABLE IF NOT EXISTS queue (id INTEGER PRIMARY KEY, config TEXT, title TEXT, thumbnail TEXT, state TEXT, priority INTEGER, position INTEGER, attempts INTEGER, last_error TEXT, added INTEGER);
+	CREATE TABLE IF NOT EXISTS archive (extractor TEXT, video_id TEXT, library TEXT, added INTEGER, PRIMARY KEY (extractor, video_id, library));
+	CREATE TABLE IF NOT EXISTS subscriptions (id INTEGER PRIMARY KEY, url TEXT, title TEXT, config TEXT, interval INTEGER, date_after TEXT, max_items INTEGER, last_seen TEXT, last_checked INTEGER, enabled INTEGER);
 	`
 	_, err = db.Exec(createTables)
-	return &DB{conn: db}, err
+	if err != nil { return &DB{conn: db}, err }
+
+	// Columns added after a table was first created; fails harmlessly once they exist
+	db.Exec("ALTER TABLE queue ADD COLUMN start_at INTEGER DEFAULT 0")
+	return &DB{conn: db}, nil
 }
 
 func (d *DB) SaveHistory(title, url, path string) {
@@ -60,9 +75,114 @@ func (d *DB) GetSetting(key string) string {
 
 func (d *DB) LoadSettings() models.AppSettings {
 	return models.AppSettings{
-		LastSavePath: d.GetSetting("LastSavePath"),
-		ClientSpoof:  d.GetSetting("ClientSpoof"),
-		CookiesPath:  d.GetSetting("CookiesPath"),
-		Language:     d.GetSetting("Language"),
+		LastSavePath:  d.GetSetting("LastSavePath"),
+		ClientSpoof:   d.GetSetting("ClientSpoof"),
+		CookiesPath:   d.GetSetting("CookiesPath"),
+		Language:      d.GetSetting("Language"),
+		MaxConcurrent: d.getIntSetting("MaxConcurrent"),
+		FormatPrefs: models.FormatPrefs{
+			Container:    d.GetSetting("Container"),
+			VideoCodec:   d.GetSetting("VideoCodec"),
+			AudioCodec:   d.GetSetting("AudioCodec"),
+			DynamicRange: d.GetSetting("DynamicRange"),
+			MaxFPS:       d.getIntSetting("MaxFPS"),
+		},
+		OutputTemplate:  d.GetSetting("OutputTemplate"),
+		OutputTemplates: d.getListSetting("OutputTemplates"),
+		DownloadWindows: d.getListSetting("DownloadWindows"),
+		OffPeakMode:     d.GetSetting("OffPeakMode"),
+		OffPeakRate:     d.getIntSetting("OffPeakRate"),
+		RateLimit:       d.getIntSetting("RateLimit"),
+		Network: models.NetworkConfig{
+			Proxy:         d.GetSetting("Proxy"),
+			SourceAddress: d.GetSetting("SourceAddress"),
+			IPVersion:     d.getIntSetting("IPVersion"),
+			CABundle:      d.GetSetting("CABundle"),
+		},
+		SponsorBlock: d.getSponsorBlockSettings(),
+
+		RetryMaxAttempts:    d.getIntSetting("RetryMaxAttempts"),
+		RetryBaseDelay:      d.getIntSetting("RetryBaseDelay"),
+		RetryMaxDelay:       d.getIntSetting("RetryMaxDelay"),
+		RetryFactor:         d.getFloatSetting("RetryFactor"),
+		RetryJitter:         d.getFloatSetting("RetryJitter"),
+		RetryOverrides:      d.getRetryOverrides(),
+	}
+}
+
+// getRetryOverrides reads the overrides saved under "Retry.<kind>.MaxAttempts"
+// and "Retry.<kind>.BaseDelay", or nil if none is saved
+func (d *DB) getRetryOverrides() map[string]models.RetryOverride {
+	overrides := make(map[string]models.RetryOverride)
+	// Saved before every retryable kind had its own settings
+	if delay := d.getIntSetting("RetryRateLimitDelay"); delay > 0 {
+		overrides["rate_limited"] = models.RetryOverride{BaseDelay: delay}
+	}
+	rows, err := d.conn.Query("SELECT key, value FROM settings WHERE key LIKE 'Retry.%'")
+	if err != nil {
+		return nil
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var key, value string
+		if rows.Scan(&key, &value) != nil {
+			continue
+		}
+		kind, field, ok := strings.Cut(strings.TrimPrefix(key, "Retry."), ".")
+		n, err := strconv.Atoi(value)
+		if !ok || err != nil {
+			continue
+		}
+		o := overrides[kind]
+		switch field {
+		case "MaxAttempts":
+			o.MaxAttempts = n
+		case "BaseDelay":
+			o.BaseDelay = n
+		}
+		overrides[kind] = o
 	}
+	if len(overrides) == 0 {
+		return nil
+	}
+	return overrides
+}
+
+// getIntSetting returns 0 for missing or malformed values
+func (d *DB) getIntSetting(key string) int {
+	n, _ := strconv.Atoi(d.GetSetting(key))
+	return n
+}
+
+func (d *DB) getFloatSetting(key string) float64 {
+	f, _ := strconv.ParseFloat(d.GetSetting(key), 64)
+	return f
+}
+
+// getSponsorBlockSettings reads the action saved for each category under
+// "SponsorBlock.<category>", or nil if none is saved
+func (d *DB) getSponsorBlockSettings() map[string]string {
+	var actions map[string]string
+	for _, c := range models.SponsorCategories {
+		if v := d.GetSetting("SponsorBlock." + c); v != "" {
+			if actions == nil {
+				actions = make(map[string]string)
+			}
+			actions[c] = v
+		}
+	}
+	return actions
+}
+
+// getListSetting reads a list stored with SaveListSetting
+func (d *DB) getListSetting(key string) []string {
+	var list []string
+	json.Unmarshal([]byte(d.GetSetting(key)), &list)
+	return list
+}
+
+func (d *DB) SaveListSetting(key string, values []string) error {
+	data, err := json.Marshal(values)
+	if err != nil { return err }
+	return d.SaveSetting(key, string(data))
 }
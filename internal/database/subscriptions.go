@@ -0,0 +1,59 @@
+package database
+
+import (
+	"encoding/json"
+	"gotube/internal/models"
+)
+
+// SaveSubscription inserts a new subscription (ID 0), setting its ID, or
+// updates an existing one
+func (d *DB) SaveSubscription(s *models.Subscription) error {
+	config, err := json.Marshal(s.Config)
+	if err != nil {
+		return err
+	}
+	if s.ID == 0 {
+		res, err := d.conn.Exec(`INSERT INTO subscriptions (url, title, config, interval, date_after, max_items, last_seen, last_checked, enabled)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			s.URL, s.Title, string(config), s.Interval, s.DateAfter, s.MaxItems, s.LastSeenID, s.LastChecked, s.Enabled)
+		if err != nil {
+			return err
+		}
+		id, err := res.LastInsertId()
+		s.ID = int(id)
+		return err
+	}
+	_, err = d.conn.Exec(`UPDATE subscriptions SET url = ?, title = ?, config = ?, interval = ?, date_after = ?, max_items = ?, last_seen = ?, last_checked = ?, enabled = ?
+		WHERE id = ?`,
+		s.URL, s.Title, string(config), s.Interval, s.DateAfter, s.MaxItems, s.LastSeenID, s.LastChecked, s.Enabled, s.ID)
+	return err
+}
+
+func (d *DB) DeleteSubscription(id int) error {
+	_, err := d.conn.Exec("DELETE FROM subscriptions WHERE id = ?", id)
+	return err
+}
+
+// ListSubscriptions returns every subscription in the order they were added
+func (d *DB) ListSubscriptions() ([]models.Subscription, error) {
+	rows, err := d.conn.Query(`SELECT id, url, COALESCE(title, ''), config, interval, COALESCE(date_after, ''), max_items, COALESCE(last_seen, ''), last_checked, enabled
+		FROM subscriptions ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var subs []models.Subscription
+	for rows.Next() {
+		var s models.Subscription
+		var config string
+		if err := rows.Scan(&s.ID, &s.URL, &s.Title, &config, &s.Interval, &s.DateAfter, &s.MaxItems, &s.LastSeenID, &s.LastChecked, &s.Enabled); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(config), &s.Config); err != nil {
+			continue // Skip rows written by an incompatible version
+		}
+		subs = append(subs, s)
+	}
+	return subs, rows.Err()
+}
@@ -0,0 +1,80 @@
+package downloader
+
+import (
+	"context"
+	"gotube/internal/models"
+	"gotube/internal/utils"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// Names of the built-in backends, as used in models.DownloadConfig.Backend
+const (
+	BackendYtDlp = "yt-dlp"
+	BackendHTTP  = "http"
+)
+
+// Backend fetches media for the Engine. Retries and cancellation cleanup are
+// handled by the Engine, so Download only performs a single attempt.
+type Backend interface {
+	Name() string
+	GetMetadata(ctx context.Context, url string) (*models.VideoMetadata, error)
+	ListFormats(ctx context.Context, url string) ([]models.Format, error)
+	// Download returns the files it started writing, so the Engine can
+	// remove their partial data if the job is cancelled. callback must not
+	// be called concurrently.
+	Download(ctx context.Context, config models.DownloadConfig, callback func(models.ProgressUpdate)) ([]string, error)
+	Version(ctx context.Context) (string, error)
+	Update(ctx context.Context, progress func(string)) error
+}
+
+type networkKey struct{}
+
+// WithNetwork makes GetMetadata and ListFormats use n instead of the global
+// network settings, e.g. utils.Network().Merge(config.Network) for a job
+func WithNetwork(ctx context.Context, n models.NetworkConfig) context.Context {
+	return context.WithValue(ctx, networkKey{}, n)
+}
+
+func networkFrom(ctx context.Context) models.NetworkConfig {
+	if n, ok := ctx.Value(networkKey{}).(models.NetworkConfig); ok {
+		return n
+	}
+	return utils.Network()
+}
+
+// Extensions we download as plain files when no backend is chosen explicitly
+var directExtensions = map[string]bool{
+	".mp4": true, ".m4v": true, ".mkv": true, ".webm": true, ".mov": true, ".avi": true,
+	".mp3": true, ".m4a": true, ".aac": true, ".flac": true, ".wav": true, ".ogg": true, ".opus": true,
+}
+
+// ytDlpOption names the first option of config that only yt-dlp carries
+// out, or returns "" if a plain file download does the job
+func ytDlpOption(config models.DownloadConfig) string {
+	switch {
+	case config.DownloadMode == "Audio":
+		return "audio extraction"
+	case config.FormatID != "":
+		return "format selection"
+	case len(config.Ranges) > 0 || config.TrimStart != "":
+		return "time ranges"
+	case len(config.Chapters) > 0 || config.SplitChapters:
+		return "chapters"
+	case len(sponsorArgs(config)) > 0:
+		return "SponsorBlock"
+	case config.EmbedSubs || config.WriteSubs:
+		return "subtitles"
+	}
+	return ""
+}
+
+// isDirectFileURL reports whether rawURL points straight at a media file
+func isDirectFileURL(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return false
+	}
+	return directExtensions[strings.ToLower(path.Ext(u.Path))]
+}
@@ -0,0 +1,342 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gotube/internal/models"
+	"gotube/internal/utils"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// HTTPBackend downloads plain media files (e.g. https://host/video.mp4)
+// without yt-dlp. Interrupted downloads resume with a Range request.
+type HTTPBackend struct {
+	// Client, if set, replaces the one built from the network settings
+	Client *http.Client
+}
+
+func NewHTTPBackend() *HTTPBackend {
+	return &HTTPBackend{}
+}
+
+func (h *HTTPBackend) client(n models.NetworkConfig) (*http.Client, error) {
+	if h.Client != nil {
+		return h.Client, nil
+	}
+	return utils.NewHTTPClient(n, 0)
+}
+
+func (h *HTTPBackend) Name() string {
+	return BackendHTTP
+}
+
+func (h *HTTPBackend) GetMetadata(ctx context.Context, rawURL string) (*models.VideoMetadata, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, rawURL, nil)
+	if err != nil {
+		return nil, &Error{Kind: KindUnsupportedURL, Message: err.Error(), Err: err}
+	}
+	client, err := h.client(networkFrom(ctx))
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, networkError(err)
+	}
+	resp.Body.Close()
+	if err := statusError(resp); err != nil {
+		return nil, err
+	}
+	name := fileName(rawURL, resp)
+	format := models.Format{ID: "direct", Ext: strings.TrimPrefix(path.Ext(name), "."), Note: "original file"}
+	if resp.ContentLength > 0 {
+		format.FileSize = resp.ContentLength
+	}
+	return &models.VideoMetadata{
+		ID:      name,
+		Title:   strings.TrimSuffix(name, path.Ext(name)),
+		Type:    "video",
+		Formats: []models.Format{format},
+	}, nil
+}
+
+func (h *HTTPBackend) ListFormats(ctx context.Context, rawURL string) ([]models.Format, error) {
+	meta, err := h.GetMetadata(ctx, rawURL)
+	if err != nil {
+		return nil, err
+	}
+	return meta.Formats, nil
+}
+
+func (h *HTTPBackend) Version(ctx context.Context) (string, error) {
+	return "built-in", nil
+}
+
+func (h *HTTPBackend) Update(ctx context.Context, progress func(string)) error {
+	return nil // Part of the app, nothing to update
+}
+
+// Download fetches the file into config.OutputPath, writing to a .part file
+// first so a later attempt can continue where this one stopped.
+func (h *HTTPBackend) Download(ctx context.Context, config models.DownloadConfig, callback func(models.ProgressUpdate)) ([]string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, config.URL, nil)
+	if err != nil {
+		return nil, &Error{Kind: KindUnsupportedURL, Message: err.Error(), Err: err}
+	}
+
+	// The .part file is always named after the URL, so a later attempt finds
+	// it before any response could name the file differently. It is renamed
+	// to the final name once complete.
+	part := destination(config, fileName(config.URL, nil))
+	var offset int64
+	if info, err := os.Stat(part + ".part"); err == nil {
+		offset = info.Size()
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	client, err := h.client(config.Network)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, networkError(err)
+	}
+	if resp.StatusCode == http.StatusRequestedRangeNotSatisfiable && offset > 0 {
+		size, ok := rangeSize(resp)
+		resp.Body.Close()
+		if ok && size == offset {
+			// The .part file is already complete
+			return []string{part}, finishPart(part, destination(config, fileName(config.URL, resp)))
+		}
+		// The .part file doesn't match the remote one, so start over
+		if err := os.Remove(part + ".part"); err != nil {
+			return nil, fileError(err)
+		}
+		callback(models.ProgressUpdate{Text: "[download] Partial file does not match the server copy, restarting"})
+		req.Header.Del("Range")
+		offset = 0
+		if resp, err = client.Do(req); err != nil {
+			return nil, networkError(err)
+		}
+	}
+	defer resp.Body.Close()
+	dest := destination(config, fileName(config.URL, resp))
+	if err := statusError(resp); err != nil {
+		return nil, err
+	}
+
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if resp.StatusCode == http.StatusPartialContent {
+		flags = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	} else {
+		offset = 0 // Server ignored the Range header
+	}
+	callback(models.ProgressUpdate{Text: "[download] Destination: " + dest})
+
+	if err := os.MkdirAll(filepath.Dir(part), 0755); err != nil {
+		return nil, fileError(err)
+	}
+	f, err := os.OpenFile(part+".part", flags, 0644)
+	if err != nil {
+		return nil, fileError(err)
+	}
+	total := offset + resp.ContentLength
+	if resp.ContentLength < 0 {
+		total = 0
+	}
+	pw := &progressWriter{w: f, done: offset, total: total, start: time.Now(), callback: callback}
+	var body io.Reader = resp.Body
+	limiter := limiterFrom(ctx)
+	if limiter == nil && config.RateLimit > 0 {
+		limiter = NewLimiter(config.RateLimit)
+	}
+	if limiter != nil {
+		body = &throttledReader{ctx: ctx, r: resp.Body, limiter: limiter}
+	}
+	_, err = io.Copy(pw, body)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			return []string{part}, ctx.Err()
+		}
+		if errors.Is(err, syscall.ENOSPC) {
+			return []string{part}, fileError(err)
+		}
+		return []string{part}, networkError(err)
+	}
+	pw.report(true)
+	return []string{part}, finishPart(part, dest)
+}
+
+// rangeSize reads the full length from a 416 response's "bytes */N" Content-Range
+func rangeSize(resp *http.Response) (int64, bool) {
+	total, ok := strings.CutPrefix(resp.Header.Get("Content-Range"), "bytes */")
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(total, 10, 64)
+	return n, err == nil
+}
+
+// finishPart moves the completed part+".part" file to dest
+func finishPart(part, dest string) error {
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return fileError(err)
+	}
+	if err := os.Rename(part+".part", dest); err != nil {
+		return fileError(err)
+	}
+	return nil
+}
+
+// destination places a downloaded file per the job's output template
+func destination(config models.DownloadConfig, name string) string {
+	if ValidateTemplate(config.OutputTemplate) != nil {
+		return filepath.Join(config.OutputPath, name)
+	}
+	ext := path.Ext(name)
+	meta := &models.VideoMetadata{ID: name, Title: strings.TrimSuffix(name, ext)}
+	fields := TemplateFields(meta, strings.TrimPrefix(ext, "."))
+	return filepath.Join(config.OutputPath, RenderTemplate(config.OutputTemplate, fields))
+}
+
+// fileName picks a file name from Content-Disposition, falling back to the URL path
+func fileName(rawURL string, resp *http.Response) string {
+	if resp != nil {
+		if _, params, err := mime.ParseMediaType(resp.Header.Get("Content-Disposition")); err == nil && params["filename"] != "" {
+			return utils.SanitizeFilename(filepath.Base(params["filename"]))
+		}
+	}
+	name := "download"
+	if u, err := url.Parse(rawURL); err == nil {
+		if base := path.Base(u.Path); base != "/" && base != "." {
+			name = base
+		}
+	}
+	if unescaped, err := url.PathUnescape(name); err == nil {
+		name = unescaped
+	}
+	return utils.SanitizeFilename(name)
+}
+
+// statusError maps HTTP status codes to the same error kinds yt-dlp failures get
+func statusError(resp *http.Response) error {
+	if resp.StatusCode < 400 {
+		return nil
+	}
+	e := &Error{Kind: KindUnknown, Message: "HTTP " + resp.Status}
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		e.Kind = KindRateLimited
+		if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+			e.RetryAfter = time.Duration(secs) * time.Second
+		}
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		e.Kind = KindAuthRequired
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		e.Kind = KindUnavailable
+	case resp.StatusCode == http.StatusUnavailableForLegalReasons:
+		e.Kind = KindGeoBlocked
+	case resp.StatusCode >= 500:
+		e.Kind = KindNetwork
+	}
+	return e
+}
+
+func networkError(err error) error {
+	return &Error{Kind: KindNetwork, Message: err.Error(), Err: err}
+}
+
+func fileError(err error) error {
+	if errors.Is(err, syscall.ENOSPC) {
+		return &Error{Kind: KindDiskFull, Message: err.Error(), Err: err}
+	}
+	return err
+}
+
+// throttledReader keeps the average read rate at or below the limiter's.
+// A new rate is measured from the moment it is set.
+type throttledReader struct {
+	ctx     context.Context
+	r       io.Reader
+	limiter *Limiter
+	rate    int64
+	start   time.Time
+	read    int64
+}
+
+func (t *throttledReader) Read(b []byte) (int, error) {
+	if rate := t.limiter.Rate(); rate != t.rate || t.start.IsZero() {
+		t.rate, t.start, t.read = rate, time.Now(), 0
+	}
+	if t.rate <= 0 {
+		return t.r.Read(b)
+	}
+	// Small chunks keep the rate smooth instead of bursting
+	if chunk := max(t.rate/10, 1024); int64(len(b)) > chunk {
+		b = b[:chunk]
+	}
+	n, err := t.r.Read(b)
+	t.read += int64(n)
+	due := t.start.Add(time.Duration(float64(t.read) / float64(t.rate) * float64(time.Second)))
+	if wait := time.Until(due); wait > 0 && !sleepContext(t.ctx, wait) {
+		return n, t.ctx.Err()
+	}
+	return n, err
+}
+
+// progressWriter reports download progress at most every 500ms
+type progressWriter struct {
+	w        io.Writer
+	done     int64
+	total    int64
+	start    time.Time
+	startAt  int64
+	last     time.Time
+	callback func(models.ProgressUpdate)
+}
+
+func (p *progressWriter) Write(b []byte) (int, error) {
+	if p.last.IsZero() {
+		p.startAt = p.done
+	}
+	n, err := p.w.Write(b)
+	p.done += int64(n)
+	if time.Since(p.last) >= 500*time.Millisecond {
+		p.report(false)
+	}
+	return n, err
+}
+
+func (p *progressWriter) report(final bool) {
+	p.last = time.Now()
+	u := models.ProgressUpdate{Stage: "Downloading", DownloadedBytes: p.done, TotalBytes: p.total}
+	if elapsed := time.Since(p.start).Seconds(); elapsed > 0 {
+		u.Speed = float64(p.done-p.startAt) / elapsed
+	}
+	if p.total > 0 {
+		u.Percent = float64(p.done) / float64(p.total)
+		if u.Speed > 0 {
+			u.ETA = int(float64(p.total-p.done) / u.Speed)
+		}
+	}
+	if final {
+		u.Percent = 1.0
+	}
+	u.Text = describeProgress(u)
+	p.callback(u)
+}
@@ -0,0 +1,118 @@
+package downloader
+
+import (
+	"context"
+	"gotube/internal/models"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestHTTPBackendUsesProxy(t *testing.T) {
+	// A plain HTTP proxy receives the absolute URL of the target
+	var requested string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = r.URL.String()
+		w.Write([]byte("video data"))
+	}))
+	defer proxy.Close()
+
+	dir := t.TempDir()
+	config := models.DownloadConfig{
+		URL:        "http://media.invalid/clip.mp4",
+		OutputPath: dir,
+		Network:    models.NetworkConfig{Proxy: proxy.URL, IPVersion: 4},
+	}
+	if _, err := NewHTTPBackend().Download(context.Background(), config, func(models.ProgressUpdate) {}); err != nil {
+		t.Fatal(err)
+	}
+	if requested != config.URL {
+		t.Errorf("proxy got %q, want %q", requested, config.URL)
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "clip.mp4")); err != nil || string(data) != "video data" {
+		t.Errorf("file = %q, %v", data, err)
+	}
+
+	config.Network.Proxy = "ftp://proxy"
+	if _, err := NewHTTPBackend().Download(context.Background(), config, func(models.ProgressUpdate) {}); err == nil {
+		t.Error("invalid proxy accepted")
+	}
+}
+
+func TestHTTPBackendResumesUnderURLName(t *testing.T) {
+	// The server names the file differently than the URL does; the partial
+	// file from an earlier attempt must still be found
+	var gotRange string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotRange = r.Header.Get("Range")
+		w.Header().Set("Content-Disposition", `attachment; filename="Real Name.mp4"`)
+		w.Header().Set("Content-Range", "bytes 6-9/10")
+		w.WriteHeader(http.StatusPartialContent)
+		w.Write([]byte("data"))
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "clip.mp4.part"), []byte("video "), 0644); err != nil {
+		t.Fatal(err)
+	}
+	config := models.DownloadConfig{URL: srv.URL + "/clip.mp4", OutputPath: dir}
+	if _, err := NewHTTPBackend().Download(context.Background(), config, func(models.ProgressUpdate) {}); err != nil {
+		t.Fatal(err)
+	}
+	if gotRange != "bytes=6-" {
+		t.Errorf("Range = %q, want bytes=6-", gotRange)
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "Real Name.mp4")); err != nil || string(data) != "video data" {
+		t.Errorf("file = %q, %v", data, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "clip.mp4.part")); !os.IsNotExist(err) {
+		t.Error(".part file left behind")
+	}
+}
+
+func TestHTTPBackendRangeNotSatisfiable(t *testing.T) {
+	const remote = "fresh data"
+	var ranges []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ranges = append(ranges, r.Header.Get("Range"))
+		if r.Header.Get("Range") != "" {
+			w.Header().Set("Content-Range", "bytes */10")
+			w.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
+			return
+		}
+		w.Write([]byte(remote))
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name   string
+		part   string
+		want   string
+		ranges int
+	}{
+		{"complete part", "video data", "video data", 1},
+		{"larger than remote", "stale video data", remote, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ranges = nil
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, "clip.mp4.part"), []byte(tt.part), 0644); err != nil {
+				t.Fatal(err)
+			}
+			config := models.DownloadConfig{URL: srv.URL + "/clip.mp4", OutputPath: dir}
+			if _, err := NewHTTPBackend().Download(context.Background(), config, func(models.ProgressUpdate) {}); err != nil {
+				t.Fatal(err)
+			}
+			if len(ranges) != tt.ranges {
+				t.Errorf("requests = %q, want %d", ranges, tt.ranges)
+			}
+			if data, err := os.ReadFile(filepath.Join(dir, "clip.mp4")); err != nil || string(data) != tt.want {
+				t.Errorf("file = %q, %v, want %q", data, err, tt.want)
+			}
+		})
+	}
+}
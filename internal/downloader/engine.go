@@ -1,183 +1,198 @@
 package downloader
 
 import (
-	"bufio"
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
 	"gotube/internal/models"
-	"os/exec"
+	"gotube/internal/utils"
+	"os"
 	"path/filepath"
-	"regexp"
-	"strconv"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 )
 
+// ErrInterrupted is used as a cancel cause (context.WithCancelCause) to stop a
+// download without deleting its .part files, so yt-dlp can resume it later.
+var ErrInterrupted = errors.New("download interrupted")
+
+// Engine dispatches jobs to a Backend and retries them per the RetryPolicy
 type Engine struct {
-	BinaryPath string
+	mu       sync.RWMutex
+	retry    RetryPolicy
+	backends map[string]Backend
 }
 
+// NewEngine returns an engine with the yt-dlp and direct HTTP backends registered
 func NewEngine(binaryPath string) *Engine {
-	return &Engine{BinaryPath: binaryPath}
+	e := &Engine{retry: DefaultRetryPolicy(), backends: make(map[string]Backend)}
+	e.Register(NewYtDlp(binaryPath))
+	e.Register(NewHTTPBackend())
+	return e
 }
 
-func (e *Engine) GetMetadata(url string) (*models.VideoMetadata, error) {
-	// --flat-playlist gives us the list of entries (ID + Title) very quickly
-	cmd := exec.Command(e.BinaryPath, "--dump-single-json", "--flat-playlist", url)
-	output, err := cmd.Output()
-	if err != nil {
-		return nil, err
-	}
-	var meta models.VideoMetadata
-	if err := json.Unmarshal(output, &meta); err != nil {
-		return nil, fmt.Errorf("failed to parse metadata: %v", err)
-	}
-	return &meta, nil
+// Register adds a backend, replacing any existing one with the same name
+func (e *Engine) Register(b Backend) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.backends[b.Name()] = b
 }
 
-func (e *Engine) Download(config models.DownloadConfig, callback func(models.ProgressUpdate)) error {
-	maxRetries := 3
-	retryDelay := 5 * time.Second
-	var lastErr error
-	for attempt := 1; attempt <= maxRetries; attempt++ {
-		args := e.buildArgs(config)
-		err := e.executeCommand(args, callback)
-		if err == nil {
-			return nil
-		}
-		lastErr = err
-		errMsg := err.Error()
-		if strings.Contains(errMsg, "HTTP Error 429") {
-			callback(models.ProgressUpdate{Text: "Rate limited. Waiting 30s...", Stage: "Retrying"})
-			time.Sleep(30 * time.Second)
-			continue
-		}
-		if strings.Contains(errMsg, "Sign in required") {
-			return fmt.Errorf("authentication required: please import cookies")
-		}
-		if strings.Contains(errMsg, "fragment not found") {
-			callback(models.ProgressUpdate{Text: "Fragment missing, retrying...", Stage: "Retrying"})
-			time.Sleep(retryDelay)
-			continue
+func (e *Engine) Backend(name string) (Backend, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	b, ok := e.backends[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown backend %q", name)
+	}
+	return b, nil
+}
+
+// Backends returns the registered backend names, sorted
+func (e *Engine) Backends() []string {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	names := make([]string, 0, len(e.backends))
+	for name := range e.backends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// BackendFor picks the backend for a job: the configured one, or for "" the
+// HTTP backend for direct media links and yt-dlp for everything else. Jobs
+// that need yt-dlp's processing (see ytDlpOption) never use the HTTP backend.
+func (e *Engine) BackendFor(config models.DownloadConfig) (Backend, error) {
+	option := ytDlpOption(config)
+	if config.Backend != "" {
+		if config.Backend == BackendHTTP && option != "" {
+			return nil, fmt.Errorf("the %s backend can't do %s; use %s", BackendHTTP, option, BackendYtDlp)
 		}
-		callback(models.ProgressUpdate{Text: fmt.Sprintf("Error: %v. Retrying...", err), Stage: "Retrying"})
-		time.Sleep(retryDelay)
+		return e.Backend(config.Backend)
 	}
-	return fmt.Errorf("failed after %d attempts: %v", maxRetries, lastErr)
+	if isDirectFileURL(config.URL) && option == "" {
+		return e.Backend(BackendHTTP)
+	}
+	return e.Backend(BackendYtDlp)
 }
 
-func (e *Engine) buildArgs(config models.DownloadConfig) []string {
-	if config.SafeMode {
-		return []string{config.URL, "-o", filepath.Join(config.OutputPath, "safe_%(title)s.%(ext)s"), "-f", "best"}
-	}
-	args := []string{
-		config.URL,
-		"-o", filepath.Join(config.OutputPath, "%(title)s.%(ext)s"),
-		"--no-mtime",
-		"--newline",
-		"--add-metadata", "--embed-thumbnail",
+// SetRetryPolicy replaces the retry policy; running downloads pick it up on
+// their next failure
+func (e *Engine) SetRetryPolicy(p RetryPolicy) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.retry = p
+}
+
+func (e *Engine) RetryPolicy() RetryPolicy {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.retry
+}
+
+// GetMetadata fetches metadata with the automatically chosen backend
+func (e *Engine) GetMetadata(ctx context.Context, url string) (*models.VideoMetadata, error) {
+	b, err := e.BackendFor(models.DownloadConfig{URL: url})
+	if err != nil {
+		return nil, err
 	}
+	return b.GetMetadata(ctx, url)
+}
 
-	// PLAYLIST LOGIC
-	if config.IsPlaylist {
-		args = append(args, "--yes-playlist")
-		// If user selected specific videos (e.g. "1,3,5")
-		if config.PlaylistItems != "" {
-			args = append(args, "--playlist-items", config.PlaylistItems)
-		}
-	} else {
-		args = append(args, "--no-playlist")
+// ListFormats lists the formats available with the automatically chosen backend
+func (e *Engine) ListFormats(ctx context.Context, url string) ([]models.Format, error) {
+	b, err := e.BackendFor(models.DownloadConfig{URL: url})
+	if err != nil {
+		return nil, err
 	}
+	return b.ListFormats(ctx, url)
+}
 
-	if config.EmbedSubs {
-		args = append(args, "--embed-subs")
-		args = append(args, "--convert-subs", "srt") // Ensure embedding works in mp4
-		if config.AutoSubs {
-			args = append(args, "--write-auto-subs")
-		}
-		lang := "en.*"
-		if config.SubLanguage == "de" {
-			lang = "de.*,en.*"
-		}
-		if config.SubLanguage == "all" {
-			lang = "all"
-		}
-		args = append(args, "--sub-langs", lang)
+// Download runs the job's backend, retrying per the policy. The job's
+// network settings override the global ones (utils.SetNetwork). Cancelling
+// ctx kills the download and removes the partial files it left behind,
+// unless the cancel cause is ErrInterrupted.
+func (e *Engine) Download(ctx context.Context, config models.DownloadConfig, callback func(models.ProgressUpdate)) error {
+	backend, err := e.BackendFor(config)
+	if err != nil {
+		return err
 	}
+	config.Network = utils.Network().Merge(config.Network)
 
-	if config.DownloadMode == "Audio" {
-		args = append(args, "-x")
-		switch config.Quality {
-		case "mp3":
-			args = append(args, "--audio-format", "mp3", "--audio-quality", "0")
-		case "m4a":
-			args = append(args, "--audio-format", "m4a")
-		default:
-			args = append(args, "--audio-format", "best")
+	var lastErr error
+	var partials []string
+	attempt := 1
+	for ; ; attempt++ {
+		files, err := backend.Download(ctx, config, callback)
+		partials = append(partials, files...)
+		if err == nil {
+			return nil
 		}
-	} else {
-		args = append(args, "--merge-output-format", "mp4")
-		switch config.Quality {
-		case "4k":
-			args = append(args, "-f", "bestvideo[height<=2160]+bestaudio/best")
-		case "1080p":
-			args = append(args, "-f", "bestvideo[height<=1080]+bestaudio/best")
-		case "720p":
-			args = append(args, "-f", "bestvideo[height<=720]+bestaudio/best")
-		default:
-			args = append(args, "-f", "bestvideo+bestaudio/best")
+		if ctx.Err() != nil {
+			return aborted(ctx, partials)
 		}
-	}
+		lastErr = err
 
-	if config.TrimStart != "" {
-		section := fmt.Sprintf("*%s-%s", config.TrimStart, config.TrimEnd)
-		if config.TrimEnd == "" {
-			section = fmt.Sprintf("*%s-inf", config.TrimStart)
+		// Permanent failures (private video, missing cookies, ...) won't improve on retry
+		policy := e.RetryPolicy()
+		if !policy.ShouldRetry(attempt, err) {
+			break
+		}
+		delay := policy.Delay(attempt, err).Round(time.Second)
+		callback(models.ProgressUpdate{Text: fmt.Sprintf("%v. Retrying in %s...", err, delay), Stage: "Retrying"})
+		if !sleepContext(ctx, delay) {
+			return aborted(ctx, partials)
 		}
-		args = append(args, "--download-sections", section, "--force-keyframes-at-cuts")
-	}
-	if config.UseSponsorBlock {
-		args = append(args, "--sponsorblock-remove", "all")
 	}
-	if config.Client != "" && config.Client != "Web" {
-		args = append(args, "--extractor-args", fmt.Sprintf("youtube:player_client=%s", strings.ToUpper(config.Client)))
+	if attempt == 1 {
+		return lastErr
 	}
-	if config.CookiesPath != "" {
-		args = append(args, "--cookies", config.CookiesPath)
+	return fmt.Errorf("failed after %d attempts: %w", attempt, lastErr)
+}
+
+// sleepContext waits for d and reports false if ctx was cancelled first.
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-ctx.Done():
+		return false
 	}
-	return args
 }
 
-func (e *Engine) executeCommand(args []string, callback func(models.ProgressUpdate)) error {
-	cmd := exec.Command(e.BinaryPath, args...)
-	stdout, _ := cmd.StdoutPipe()
-	stderr, _ := cmd.StderrPipe()
-	if err := cmd.Start(); err != nil {
-		return err
+// aborted handles a cancelled download and returns the context error
+func aborted(ctx context.Context, partials []string) error {
+	if !errors.Is(context.Cause(ctx), ErrInterrupted) {
+		cleanupPartials(partials)
 	}
+	return ctx.Err()
+}
 
-	progressRegex := regexp.MustCompile(`\[download\]\s+(\d+\.?\d*)%`)
-	scanner := bufio.NewScanner(stdout)
-	for scanner.Scan() {
-		line := scanner.Text()
-		matches := progressRegex.FindStringSubmatch(line)
-		var percent float64
-		if len(matches) > 1 {
-			p, _ := strconv.ParseFloat(matches[1], 64)
-			percent = p / 100.0
+// cleanupPartials removes the .part/.ytdl leftovers of aborted downloads.
+// files are the destinations the backend reported while downloading.
+func cleanupPartials(files []string) {
+	for _, f := range files {
+		os.Remove(f + ".part")
+		os.Remove(f + ".ytdl")
+
+		// Fragmented (DASH/HLS) downloads leave one file per fragment
+		dir, base := filepath.Split(f)
+		if dir == "" {
+			dir = "."
 		}
-		callback(models.ProgressUpdate{Percent: percent, Text: line, Stage: "Downloading"})
-	}
-	errScanner := bufio.NewScanner(stderr)
-	var errOutput string
-	for errScanner.Scan() {
-		errOutput += errScanner.Text() + "\n"
-	}
-	if err := cmd.Wait(); err != nil {
-		if errOutput != "" {
-			return fmt.Errorf("%v | %s", err, errOutput)
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			if strings.HasPrefix(entry.Name(), base+".part-Frag") {
+				os.Remove(filepath.Join(dir, entry.Name()))
+			}
 		}
-		return err
 	}
-	return nil
 }
@@ -0,0 +1,87 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"gotube/internal/models"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBackendFor(t *testing.T) {
+	e := NewEngine("yt-dlp")
+	tests := []struct {
+		config models.DownloadConfig
+		want   string
+	}{
+		{models.DownloadConfig{URL: "https://www.youtube.com/watch?v=x"}, BackendYtDlp},
+		{models.DownloadConfig{URL: "https://example.com/media/clip.MP4?sig=1"}, BackendHTTP},
+		{models.DownloadConfig{URL: "ftp://example.com/clip.mp4"}, BackendYtDlp},
+		{models.DownloadConfig{URL: "https://example.com/clip.mp4", Backend: BackendYtDlp}, BackendYtDlp},
+		{models.DownloadConfig{URL: "https://example.com/clip.mp4", Ranges: []models.TimeRange{{Start: 10}}}, BackendYtDlp},
+		{models.DownloadConfig{URL: "https://example.com/clip.mp4", WriteSubs: true}, BackendYtDlp},
+		{models.DownloadConfig{URL: "https://example.com/clip.mp4", DownloadMode: "Audio", Quality: "mp3"}, BackendYtDlp},
+		{models.DownloadConfig{URL: "https://example.com/clip.mp4", FormatID: "137+140"}, BackendYtDlp},
+	}
+	for _, tt := range tests {
+		b, err := e.BackendFor(tt.config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b.Name() != tt.want {
+			t.Errorf("BackendFor(%q) = %s, want %s", tt.config.URL, b.Name(), tt.want)
+		}
+	}
+	if _, err := e.BackendFor(models.DownloadConfig{Backend: "nope"}); err == nil {
+		t.Error("unknown backend accepted")
+	}
+	for name, config := range map[string]models.DownloadConfig{
+		"SponsorBlock": {UseSponsorBlock: true},
+		"Audio mode":   {DownloadMode: "Audio"},
+		"a format ID":  {FormatID: "18"},
+	} {
+		config.URL, config.Backend = "https://example.com/clip.mp4", BackendHTTP
+		if _, err := e.BackendFor(config); err == nil {
+			t.Errorf("HTTP backend accepted %s", name)
+		}
+	}
+}
+
+func TestDownloadCancelCleanup(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		cause error
+		kept  bool
+	}{
+		{"cancel removes partial files", context.Canceled, false},
+		{"interrupt keeps partial files", ErrInterrupted, true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			dest := filepath.Join(dir, "video.mp4")
+			frag := dest + ".part-Frag3"
+			e, _ := newFakeEngine(t, fakeRun{
+				Create: []string{dest + ".part", frag},
+				Stdout: []string{"[download] Destination: " + dest},
+				Hang:   true,
+			})
+
+			ctx, cancel := context.WithCancelCause(context.Background())
+			err := e.Download(ctx, models.DownloadConfig{URL: "https://youtu.be/x", OutputPath: dir}, func(u models.ProgressUpdate) {
+				if u.Text == "[download] Destination: "+dest {
+					cancel(tt.cause)
+				}
+			})
+			if !errors.Is(err, context.Canceled) {
+				t.Fatalf("got %v, want context.Canceled", err)
+			}
+			for _, f := range []string{dest + ".part", frag} {
+				_, statErr := os.Stat(f)
+				if kept := statErr == nil; kept != tt.kept {
+					t.Errorf("%s kept = %v, want %v", filepath.Base(f), kept, tt.kept)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package downloader
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrorKind classifies why a yt-dlp run failed
+type ErrorKind int
+
+const (
+	KindUnknown ErrorKind = iota
+	KindRateLimited
+	KindAuthRequired // Sign-in, age gate or bot check; needs cookies
+	KindGeoBlocked
+	KindUnavailable // Private, removed or terminated
+	KindMembersOnly
+	KindNotStarted // Premiere or live stream that hasn't begun
+	KindNetwork
+	KindFFmpegMissing
+	KindDiskFull
+	KindUnsupportedURL
+)
+
+var kindNames = map[ErrorKind]string{
+	KindUnknown:        "unknown",
+	KindRateLimited:    "rate_limited",
+	KindAuthRequired:   "auth_required",
+	KindGeoBlocked:     "geo_blocked",
+	KindUnavailable:    "unavailable",
+	KindMembersOnly:    "members_only",
+	KindNotStarted:     "not_started",
+	KindNetwork:        "network",
+	KindFFmpegMissing:  "ffmpeg_missing",
+	KindDiskFull:       "disk_full",
+	KindUnsupportedURL: "unsupported_url",
+}
+
+func (k ErrorKind) String() string {
+	return kindNames[k]
+}
+
+// Retryable reports whether another attempt can succeed without user action.
+// A RetryPolicy override can still enable retries for other kinds.
+func (k ErrorKind) Retryable() bool {
+	switch k {
+	case KindUnknown, KindRateLimited, KindNetwork:
+		return true
+	}
+	return false
+}
+
+// Error is a classified yt-dlp failure; inspect it with errors.As
+type Error struct {
+	Kind    ErrorKind
+	Message string // The most relevant line yt-dlp printed
+	Stderr  string
+	Err     error // The process error
+
+	// RetryAfter is the wait time the server asked for, if yt-dlp reported one
+	RetryAfter time.Duration
+}
+
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("%s: %v", e.Kind, e.Err)
+	}
+	return fmt.Sprintf("%s: %s", e.Kind, e.Message)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Checked in order, so the more specific phrases must come first
+// (e.g. "Video unavailable. This video is available to this channel's members",
+// or "Private video. ... Use --cookies for the authentication").
+var errorPatterns = []struct {
+	kind    ErrorKind
+	phrases []string
+}{
+	{KindDiskFull, []string{"no space left on device", "errno 28", "not enough space on the disk"}},
+	{KindFFmpegMissing, []string{"ffmpeg not found", "ffprobe and ffmpeg not found", "ffmpeg is not installed"}},
+	{KindUnsupportedURL, []string{"unsupported url", "is not a valid url"}},
+	{KindRateLimited, []string{"http error 429", "too many requests", "rate-limited", "rate limited"}},
+	{KindMembersOnly, []string{"members-only", "join this channel to get access", "available to this channel's members"}},
+	{KindNotStarted, []string{"premieres in", "premiere will begin", "live event will begin", "this live event will begin", "is upcoming"}},
+	{KindGeoBlocked, []string{"available in your country", "geo restriction", "geo-restricted", "geo restricted"}},
+	{KindUnavailable, []string{"private video", "video unavailable", "has been removed", "no longer available", "account associated with this video has been terminated", "http error 404", "http error 410"}},
+	{KindAuthRequired, []string{"sign in to confirm", "sign in required", "login required", "age-restricted", "inappropriate for some users", "use --cookies", "not a bot"}},
+	{KindNetwork, []string{"unable to download webpage", "connection reset", "timed out", "temporary failure in name resolution", "network is unreachable", "connection refused", "remote end closed connection", "fragment not found", "http error 5", "getaddrinfo failed"}},
+}
+
+// classifyError turns a failed run's stderr into an *Error. Only the error
+// message decides the kind; warnings before it are often unrelated.
+func classifyError(err error, stderr string) *Error {
+	e := &Error{Kind: KindUnknown, Message: errorMessage(stderr), Stderr: stderr, Err: err, RetryAfter: parseRetryAfter(stderr)}
+	lower := strings.ToLower(e.Message)
+	// yt-dlp uses a typographic apostrophe in some messages
+	lower = strings.ReplaceAll(lower, "’", "'")
+	for _, p := range errorPatterns {
+		for _, phrase := range p.phrases {
+			if strings.Contains(lower, phrase) {
+				e.Kind = p.kind
+				return e
+			}
+		}
+	}
+	return e
+}
+
+// errorMessage picks the last "ERROR:" line, falling back to the last line
+func errorMessage(stderr string) string {
+	var last, lastError string
+	for _, line := range strings.Split(stderr, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		last = line
+		if msg, ok := strings.CutPrefix(line, "ERROR: "); ok {
+			lastError = msg
+		}
+	}
+	if lastError != "" {
+		return lastError
+	}
+	return last
+}
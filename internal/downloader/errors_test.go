@@ -0,0 +1,50 @@
+package downloader
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		stderr     string
+		kind       ErrorKind
+		retryAfter time.Duration
+	}{
+		{"ERROR: unable to download video data: HTTP Error 429: Too Many Requests", KindRateLimited, 0},
+		{"ERROR: HTTP Error 429. Retry after 90 seconds", KindRateLimited, 90 * time.Second},
+		{"ERROR: [youtube] x: Sign in to confirm you’re not a bot. Use --cookies-from-browser", KindAuthRequired, 0},
+		{"ERROR: [youtube] x: Sign in to confirm your age. This video may be inappropriate for some users.", KindAuthRequired, 0},
+		{"ERROR: [youtube] x: The uploader has not made this video available in your country", KindGeoBlocked, 0},
+		{"ERROR: [youtube] x: Video unavailable. This video has been removed by the uploader", KindUnavailable, 0},
+		{"ERROR: [youtube] x: Private video. Sign in if you've been granted access to this video. Use --cookies-from-browser or --cookies for the authentication.", KindUnavailable, 0},
+		{"WARNING: [youtube] x: HTTP Error 429: Too Many Requests\nERROR: unable to download video data: HTTP Error 503: Service Unavailable", KindNetwork, 0},
+		{"ERROR: [youtube] x: Join this channel to get access to members-only content like this video", KindMembersOnly, 0},
+		{"ERROR: [youtube] x: Premieres in 3 hours", KindNotStarted, 0},
+		{"ERROR: [youtube] x: Unable to download webpage: <urlopen error [Errno -3] Temporary failure in name resolution>", KindNetwork, 0},
+		{"ERROR: Postprocessing: ffprobe and ffmpeg not found. Please install or provide the path using --ffmpeg-location", KindFFmpegMissing, 0},
+		{"ERROR: unable to write data: [Errno 28] No space left on device", KindDiskFull, 0},
+		{"ERROR: Unsupported URL: https://example.com/", KindUnsupportedURL, 0},
+		{"ERROR: something nobody has seen before", KindUnknown, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.kind.String(), func(t *testing.T) {
+			cause := errors.New("exit status 1")
+			got := classifyError(cause, "[youtube] Extracting URL\n"+tt.stderr+"\n")
+			if got.Kind != tt.kind {
+				t.Errorf("kind = %v, want %v", got.Kind, tt.kind)
+			}
+			if got.RetryAfter != tt.retryAfter {
+				t.Errorf("retry after = %v, want %v", got.RetryAfter, tt.retryAfter)
+			}
+			if got.Message != tt.stderr[strings.LastIndex(tt.stderr, "ERROR: ")+len("ERROR: "):] {
+				t.Errorf("message = %q", got.Message)
+			}
+			if !errors.Is(got, cause) {
+				t.Error("error does not wrap its cause")
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package downloader
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeYtDlp is the testdata/fakeytdlp binary, built once in TestMain
+var fakeYtDlp string
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "fakeytdlp")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	fakeYtDlp = filepath.Join(dir, "yt-dlp")
+	if runtime.GOOS == "windows" {
+		fakeYtDlp += ".exe"
+	}
+	build := exec.Command("go", "build", "-o", fakeYtDlp, "./testdata/fakeytdlp")
+	if out, err := build.CombinedOutput(); err != nil {
+		fmt.Fprintf(os.Stderr, "building fake yt-dlp: %v\n%s", err, out)
+		os.RemoveAll(dir)
+		os.Exit(1)
+	}
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// fakeRun scripts one invocation of the fake yt-dlp
+type fakeRun struct {
+	Stdout []string `json:"stdout"`
+	Stderr []string `json:"stderr"`
+	Exit   int      `json:"exit"`
+	Create []string `json:"create"`
+	Hang   bool     `json:"hang"`
+}
+
+// newFakeEngine returns an engine whose yt-dlp replays runs, one per
+// invocation, with retry delays short enough for tests. The returned
+// function lists the arguments of every invocation so far.
+func newFakeEngine(t *testing.T, runs ...fakeRun) (*Engine, func() [][]string) {
+	t.Helper()
+	dir := t.TempDir()
+	script := filepath.Join(dir, "script.json")
+	logPath := filepath.Join(dir, "calls.log")
+	data, err := json.Marshal(runs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(script, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("FAKE_YTDLP_SCRIPT", script)
+	t.Setenv("FAKE_YTDLP_LOG", logPath)
+
+	e := NewEngine(fakeYtDlp)
+	e.SetRetryPolicy(RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond, Factor: 1})
+
+	calls := func() [][]string {
+		data, err := os.ReadFile(logPath)
+		if err != nil {
+			return nil
+		}
+		var calls [][]string
+		for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+			var args []string
+			if err := json.Unmarshal([]byte(line), &args); err != nil {
+				t.Fatalf("bad call log line %q: %v", line, err)
+			}
+			calls = append(calls, args)
+		}
+		return calls
+	}
+	return e, calls
+}
+
+// progressLine formats a line as our --progress-template would print it
+func progressLine(fields ...string) string {
+	return progressPrefix + strings.Join(fields, "|")
+}
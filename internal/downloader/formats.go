@@ -0,0 +1,37 @@
+package downloader
+
+import (
+	"gotube/internal/models"
+	"strings"
+)
+
+// EstimateSize adds up the sizes of the formats in an exact selector such as
+// "137+140". It reports false for selectors it can't resolve, like "bv*+ba",
+// or when the size of any part is unknown.
+func EstimateSize(meta *models.VideoMetadata, selector string) (int64, bool) {
+	if meta == nil || selector == "" {
+		return 0, false
+	}
+	var total int64
+	for _, id := range strings.Split(selector, "+") {
+		f, ok := findFormat(meta.Formats, id)
+		if !ok {
+			return 0, false
+		}
+		size := f.Size(meta.Duration)
+		if size == 0 {
+			return 0, false
+		}
+		total += size
+	}
+	return total, true
+}
+
+func findFormat(formats []models.Format, id string) (models.Format, bool) {
+	for _, f := range formats {
+		if f.ID == id {
+			return f, true
+		}
+	}
+	return models.Format{}, false
+}
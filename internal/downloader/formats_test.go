@@ -0,0 +1,33 @@
+package downloader
+
+import (
+	"gotube/internal/models"
+	"testing"
+)
+
+func TestEstimateSize(t *testing.T) {
+	meta := &models.VideoMetadata{Duration: 100, Formats: []models.Format{
+		{ID: "137", VCodec: "avc1", FileSize: 1000},
+		{ID: "248", VCodec: "vp9", FileSizeApprox: 800},
+		{ID: "140", ACodec: "mp4a", TBR: 128},
+		{ID: "251", ACodec: "opus"},
+	}}
+	tests := []struct {
+		selector string
+		want     int64
+		ok       bool
+	}{
+		{"137", 1000, true},
+		{"137+140", 1000 + 128*125*100, true},
+		{"248+140", 800 + 128*125*100, true},
+		{"137+251", 0, false}, // No size or bitrate for 251
+		{"bv*+ba", 0, false},
+		{"", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := EstimateSize(meta, tt.selector)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("EstimateSize(%q) = %d, %v; want %d, %v", tt.selector, got, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package downloader
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// Limiter is a rate limit that may change while a download runs. The HTTP
+// backend follows it from chunk to chunk; yt-dlp only reads a rate at start.
+type Limiter struct {
+	rate atomic.Int64
+}
+
+func NewLimiter(rate int64) *Limiter {
+	l := &Limiter{}
+	l.rate.Store(rate)
+	return l
+}
+
+// SetRate changes the limit in bytes per second; 0 is unlimited
+func (l *Limiter) SetRate(rate int64) {
+	l.rate.Store(rate)
+}
+
+func (l *Limiter) Rate() int64 {
+	return l.rate.Load()
+}
+
+type limiterKey struct{}
+
+// WithLimiter makes backends that support it follow l instead of the
+// config's fixed RateLimit
+func WithLimiter(ctx context.Context, l *Limiter) context.Context {
+	return context.WithValue(ctx, limiterKey{}, l)
+}
+
+func limiterFrom(ctx context.Context) *Limiter {
+	l, _ := ctx.Value(limiterKey{}).(*Limiter)
+	return l
+}
+
+// ParseRate parses a rate the way yt-dlp's --limit-rate does: bytes per
+// second with an optional K, M or G suffix (powers of 1024). "" is 0.
+func ParseRate(s string) (int64, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	if s == "" {
+		return 0, nil
+	}
+	unit := 1.0
+	switch {
+	case strings.HasSuffix(s, "K"):
+		unit = 1 << 10
+	case strings.HasSuffix(s, "M"):
+		unit = 1 << 20
+	case strings.HasSuffix(s, "G"):
+		unit = 1 << 30
+	}
+	if unit > 1 {
+		s = s[:len(s)-1]
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	// float64(math.MaxInt64) rounds up to 2^63, which int64 can't hold
+	if err != nil || v < 0 || math.IsInf(v, 0) || math.IsNaN(v) || v*unit >= math.MaxInt64 {
+		return 0, fmt.Errorf("invalid rate %q", s)
+	}
+	return int64(v * unit), nil
+}
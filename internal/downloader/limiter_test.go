@@ -0,0 +1,53 @@
+package downloader
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestParseRate(t *testing.T) {
+	tests := map[string]int64{
+		"":       0,
+		"2048":   2048,
+		"500K":   500 << 10,
+		"1.5m":   3 << 19,
+		" 1G ":   1 << 30,
+		"0":      0,
+		"100000": 100000,
+	}
+	for in, want := range tests {
+		if got, err := ParseRate(in); err != nil || got != want {
+			t.Errorf("ParseRate(%q) = %d, %v; want %d", in, got, err, want)
+		}
+	}
+	for _, bad := range []string{"K", "fast", "-1M", "5KB", "inf", "Infinity", "-inf", "NaN", "1e30M", "9223372036854775807"} {
+		if _, err := ParseRate(bad); err == nil {
+			t.Errorf("ParseRate(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestThrottledReaderFollowsLimiter(t *testing.T) {
+	limiter := NewLimiter(0)
+	r := &throttledReader{ctx: context.Background(), r: bytes.NewReader(make([]byte, 1<<20)), limiter: limiter}
+
+	start := time.Now()
+	if _, err := io.CopyN(io.Discard, r, 512<<10); err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(start); d > 100*time.Millisecond {
+		t.Errorf("unlimited read took %v", d)
+	}
+
+	limiter.SetRate(20000)
+	start = time.Now()
+	if _, err := io.CopyN(io.Discard, r, 4000); err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(start); d < 150*time.Millisecond {
+		t.Errorf("read at 20000 B/s took only %v", d)
+	}
+}
@@ -0,0 +1,20 @@
+//go:build !windows
+
+package downloader
+
+import (
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+// configureProcess starts yt-dlp in its own process group so cancelling the
+// command also kills the ffmpeg children it spawns.
+func configureProcess(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+	// Don't hang on pipes still held open by orphaned grandchildren
+	cmd.WaitDelay = 5 * time.Second
+}
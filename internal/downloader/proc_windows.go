@@ -0,0 +1,19 @@
+//go:build windows
+
+package downloader
+
+import (
+	"os/exec"
+	"strconv"
+	"time"
+)
+
+// configureProcess makes cancelling the command kill the whole yt-dlp process
+// tree (including ffmpeg) instead of just the parent.
+func configureProcess(cmd *exec.Cmd) {
+	cmd.Cancel = func() error {
+		return exec.Command("taskkill", "/T", "/F", "/PID", strconv.Itoa(cmd.Process.Pid)).Run()
+	}
+	// Don't hang on pipes still held open by orphaned grandchildren
+	cmd.WaitDelay = 5 * time.Second
+}
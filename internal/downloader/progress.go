@@ -0,0 +1,142 @@
+package downloader
+
+import (
+	"fmt"
+	"gotube/internal/models"
+	"gotube/internal/utils"
+	"strconv"
+	"strings"
+)
+
+// Marker prefixes so our template lines can't be confused with yt-dlp's own output
+const (
+	progressPrefix    = "[gotube] "
+	postProcessPrefix = "[gotube-pp] "
+)
+
+// Fields are pipe separated; yt-dlp prints "NA" for anything it doesn't know.
+var (
+	progressTemplate = "download:" + progressPrefix + strings.Join([]string{
+		"%(progress.status)s",
+		"%(progress.downloaded_bytes)s",
+		"%(progress.total_bytes)s",
+		"%(progress.total_bytes_estimate)s",
+		"%(progress.speed)s",
+		"%(progress.eta)s",
+		"%(progress.fragment_index)s",
+		"%(progress.fragment_count)s",
+		"%(info.playlist_autonumber)s",
+		"%(info.n_entries)s",
+	}, "|")
+	postProcessTemplate = "postprocess:" + postProcessPrefix + strings.Join([]string{
+		"%(progress.status)s",
+		"%(progress.postprocessor)s",
+		"%(info.playlist_autonumber)s",
+		"%(info.n_entries)s",
+	}, "|")
+)
+
+// progressArgs makes yt-dlp report progress in a machine-readable form
+func progressArgs() []string {
+	return []string{
+		"--progress-template", progressTemplate,
+		"--progress-template", postProcessTemplate,
+	}
+}
+
+// parseProgressLine turns one of our template lines into a ProgressUpdate.
+// It reports false for any other output line.
+func parseProgressLine(line string) (models.ProgressUpdate, bool) {
+	if rest, ok := strings.CutPrefix(line, progressPrefix); ok {
+		f := strings.Split(rest, "|")
+		if len(f) != 10 {
+			return models.ProgressUpdate{}, false
+		}
+		u := models.ProgressUpdate{
+			Stage:           "Downloading",
+			DownloadedBytes: parseInt64(f[1]),
+			TotalBytes:      parseInt64(f[2]),
+			Speed:           parseFloat(f[4]),
+			ETA:             int(parseFloat(f[5])),
+			FragmentIndex:   int(parseInt64(f[6])),
+			FragmentCount:   int(parseInt64(f[7])),
+			PlaylistIndex:   int(parseInt64(f[8])),
+			PlaylistCount:   int(parseInt64(f[9])),
+		}
+		if u.TotalBytes == 0 {
+			u.TotalBytes = int64(parseFloat(f[3]))
+		}
+		if u.TotalBytes > 0 {
+			u.Percent = float64(u.DownloadedBytes) / float64(u.TotalBytes)
+		} else if u.FragmentCount > 0 {
+			u.Percent = float64(u.FragmentIndex) / float64(u.FragmentCount)
+		}
+		if f[0] == "finished" {
+			u.Percent = 1.0
+		}
+		u.Text = describeProgress(u)
+		return u, true
+	}
+
+	if rest, ok := strings.CutPrefix(line, postProcessPrefix); ok {
+		f := strings.Split(rest, "|")
+		if len(f) != 4 {
+			return models.ProgressUpdate{}, false
+		}
+		u := models.ProgressUpdate{
+			Stage:         "Processing",
+			PostProcessor: naToEmpty(f[1]),
+			PlaylistIndex: int(parseInt64(f[2])),
+			PlaylistCount: int(parseInt64(f[3])),
+		}
+		tag := u.PostProcessor
+		if tag == "" {
+			tag = "postprocess"
+		}
+		u.Text = fmt.Sprintf("[%s] %s", tag, f[0])
+		return u, true
+	}
+	return models.ProgressUpdate{}, false
+}
+
+// describeProgress builds the log line shown in place of yt-dlp's own progress output
+func describeProgress(u models.ProgressUpdate) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "[download] %5.1f%%", u.Percent*100)
+	if u.TotalBytes > 0 {
+		fmt.Fprintf(&b, " of %s", utils.FormatBytes(u.TotalBytes))
+	}
+	if u.Speed > 0 {
+		fmt.Fprintf(&b, " at %s/s", utils.FormatBytes(int64(u.Speed)))
+	}
+	if u.ETA > 0 {
+		fmt.Fprintf(&b, " ETA %s", utils.FormatETA(u.ETA))
+	}
+	if u.FragmentCount > 0 {
+		fmt.Fprintf(&b, " (frag %d/%d)", u.FragmentIndex, u.FragmentCount)
+	}
+	return b.String()
+}
+
+func naToEmpty(s string) string {
+	if s == "NA" {
+		return ""
+	}
+	return s
+}
+
+func parseInt64(s string) int64 {
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return int64(parseFloat(s))
+	}
+	return n
+}
+
+func parseFloat(s string) float64 {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0
+	}
+	return f
+}
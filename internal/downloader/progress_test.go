@@ -0,0 +1,72 @@
+package downloader
+
+import (
+	"gotube/internal/models"
+	"testing"
+)
+
+func TestParseProgressLine(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		ok   bool
+		want models.ProgressUpdate
+	}{
+		{
+			name: "known total",
+			line: progressLine("downloading", "1024", "4096", "NA", "512.5", "6", "NA", "NA", "NA", "NA"),
+			ok:   true,
+			want: models.ProgressUpdate{Stage: "Downloading", Percent: 0.25, DownloadedBytes: 1024, TotalBytes: 4096, Speed: 512.5, ETA: 6},
+		},
+		{
+			name: "estimated total",
+			line: progressLine("downloading", "300", "NA", "1200.0", "NA", "NA", "NA", "NA", "NA", "NA"),
+			ok:   true,
+			want: models.ProgressUpdate{Stage: "Downloading", Percent: 0.25, DownloadedBytes: 300, TotalBytes: 1200},
+		},
+		{
+			name: "fragments only",
+			line: progressLine("downloading", "NA", "NA", "NA", "NA", "NA", "3", "12", "NA", "NA"),
+			ok:   true,
+			want: models.ProgressUpdate{Stage: "Downloading", Percent: 0.25, FragmentIndex: 3, FragmentCount: 12},
+		},
+		{
+			name: "finished playlist entry",
+			line: progressLine("finished", "10", "NA", "NA", "NA", "NA", "NA", "NA", "4", "9"),
+			ok:   true,
+			want: models.ProgressUpdate{Stage: "Downloading", Percent: 1, DownloadedBytes: 10, PlaylistIndex: 4, PlaylistCount: 9},
+		},
+		{
+			name: "post-processing",
+			line: postProcessPrefix + "started|FFmpegExtractAudio|NA|NA",
+			ok:   true,
+			want: models.ProgressUpdate{Stage: "Processing", PostProcessor: "FFmpegExtractAudio", Text: "[FFmpegExtractAudio] started"},
+		},
+		{
+			name: "unnamed post-processor",
+			line: postProcessPrefix + "finished|NA|2|5",
+			ok:   true,
+			want: models.ProgressUpdate{Stage: "Processing", PlaylistIndex: 2, PlaylistCount: 5, Text: "[postprocess] finished"},
+		},
+		{name: "missing fields", line: progressLine("downloading", "1", "2"), ok: false},
+		{name: "bad post-processing line", line: postProcessPrefix + "started", ok: false},
+		{name: "plain yt-dlp output", line: "[download]  12.0% of 3.00MiB at 1.00MiB/s ETA 00:02", ok: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseProgressLine(tt.line)
+			if ok != tt.ok {
+				t.Fatalf("ok = %v, want %v", ok, tt.ok)
+			}
+			if !ok {
+				return
+			}
+			if tt.want.Text == "" {
+				tt.want.Text = describeProgress(tt.want)
+			}
+			if got != tt.want {
+				t.Errorf("got  %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,177 @@
+package downloader
+
+import (
+	"errors"
+	"fmt"
+	"gotube/internal/models"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrBadTimestamp  = errors.New(`time must look like "1:02:03", "2:03", "123" or "50%"`)
+	ErrBadRange      = errors.New(`range must look like "1:00-2:30"`)
+	ErrRangeOrder    = errors.New("range ends before it starts")
+	ErrRangeTooLong  = errors.New("range goes past the end of the video")
+	ErrNeedsDuration = errors.New("percentages need the video's duration")
+)
+
+// RangeError reports which range of a list is invalid
+type RangeError struct {
+	Range string
+	Err   error
+}
+
+func (e *RangeError) Error() string {
+	return e.Range + ": " + e.Err.Error()
+}
+
+func (e *RangeError) Unwrap() error {
+	return e.Err
+}
+
+// Digits with an optional fraction; ParseFloat alone would take "1e3" or "Inf"
+var timeFieldRegex = regexp.MustCompile(`^\d+(\.\d+)?$`)
+
+// ParseTimestamp parses "hh:mm:ss", "mm:ss" or seconds (each with optional
+// fractions) into seconds. "NN%" is a share of duration, which must then be
+// known (> 0).
+func ParseTimestamp(s string, duration int) (float64, error) {
+	s = strings.TrimSpace(s)
+	if p, ok := strings.CutSuffix(s, "%"); ok {
+		p = strings.TrimSpace(p)
+		if !timeFieldRegex.MatchString(p) {
+			return 0, ErrBadTimestamp
+		}
+		v, _ := strconv.ParseFloat(p, 64)
+		if v > 100 {
+			return 0, ErrBadTimestamp
+		}
+		if duration <= 0 {
+			return 0, ErrNeedsDuration
+		}
+		return v / 100 * float64(duration), nil
+	}
+
+	parts := strings.Split(s, ":")
+	if len(parts) > 3 {
+		return 0, ErrBadTimestamp
+	}
+	var total float64
+	for i, part := range parts {
+		// Only the last field may have a fraction, and only the first may
+		// exceed 59 ("90:00" is 90 minutes)
+		if !timeFieldRegex.MatchString(part) || (i < len(parts)-1 && strings.Contains(part, ".")) {
+			return 0, ErrBadTimestamp
+		}
+		v, _ := strconv.ParseFloat(part, 64)
+		if i > 0 && v >= 60 {
+			return 0, ErrBadTimestamp
+		}
+		total = total*60 + v
+	}
+	return total, nil
+}
+
+// ParseRanges parses a comma separated list like "0:30-1:00, 50%-" and
+// returns the ranges sorted with overlaps merged. An empty start is the
+// beginning and an empty end the end of the video. With a duration (> 0) the
+// ranges are checked against it; 0 skips that check.
+func ParseRanges(s string, duration int) ([]models.TimeRange, error) {
+	var ranges []models.TimeRange
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		r, err := parseRange(part, duration)
+		if err != nil {
+			return nil, &RangeError{Range: part, Err: err}
+		}
+		ranges = append(ranges, r)
+	}
+	return MergeRanges(ranges), nil
+}
+
+func parseRange(s string, duration int) (models.TimeRange, error) {
+	from, to, ok := strings.Cut(s, "-")
+	if !ok || (strings.TrimSpace(from) == "" && strings.TrimSpace(to) == "") {
+		return models.TimeRange{}, ErrBadRange
+	}
+	var r models.TimeRange
+	var err error
+	if strings.TrimSpace(from) != "" {
+		if r.Start, err = ParseTimestamp(from, duration); err != nil {
+			return r, err
+		}
+	}
+	if strings.TrimSpace(to) != "" {
+		if r.End, err = ParseTimestamp(to, duration); err != nil {
+			return r, err
+		}
+		if r.End == 0 {
+			return r, ErrRangeOrder // "-0" would otherwise mean "to the end"
+		}
+	}
+	return r, ValidateRange(r, duration)
+}
+
+// ValidateRange checks a range against the video's duration in seconds; 0
+// only checks the order
+func ValidateRange(r models.TimeRange, duration int) error {
+	if r.Start < 0 || (r.End != 0 && r.End <= r.Start) {
+		return ErrRangeOrder
+	}
+	if duration > 0 && (r.Start >= float64(duration) || r.End > float64(duration)) {
+		return ErrRangeTooLong
+	}
+	return nil
+}
+
+// MergeRanges sorts ranges by start and joins the ones that overlap or touch
+func MergeRanges(ranges []models.TimeRange) []models.TimeRange {
+	if len(ranges) == 0 {
+		return nil
+	}
+	sorted := append([]models.TimeRange(nil), ranges...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Start < sorted[j].Start })
+	merged := []models.TimeRange{sorted[0]}
+	for _, r := range sorted[1:] {
+		last := &merged[len(merged)-1]
+		switch {
+		case last.End == 0:
+			// Already runs to the end
+		case r.Start <= last.End:
+			if r.End == 0 || r.End > last.End {
+				last.End = r.End
+			}
+		default:
+			merged = append(merged, r)
+		}
+	}
+	return merged
+}
+
+// FormatRanges is the inverse of ParseRanges, for showing saved ranges
+func FormatRanges(ranges []models.TimeRange) string {
+	parts := make([]string, len(ranges))
+	for i, r := range ranges {
+		parts[i] = formatSeconds(r.Start) + "-"
+		if r.End != 0 {
+			parts[i] += formatSeconds(r.End)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// formatSeconds renders seconds as "h:mm:ss", keeping up to three decimals
+func formatSeconds(secs float64) string {
+	whole := int(secs)
+	s := fmt.Sprintf("%d:%02d:%02d", whole/3600, whole/60%60, whole%60)
+	if frac := secs - float64(whole); frac >= 0.0005 {
+		s += strings.TrimRight(strings.TrimPrefix(strconv.FormatFloat(frac, 'f', 3, 64), "0"), "0")
+	}
+	return s
+}
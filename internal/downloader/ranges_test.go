@@ -0,0 +1,108 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"gotube/internal/models"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseTimestamp(t *testing.T) {
+	tests := []struct {
+		in       string
+		duration int
+		want     float64
+		err      error
+	}{
+		{"1:02:03", 0, 3723, nil},
+		{"2:03", 0, 123, nil},
+		{"90:00", 0, 5400, nil},
+		{"123", 0, 123, nil},
+		{"1:30.25", 0, 90.25, nil},
+		{"50%", 600, 300, nil},
+		{"12.5 %", 200, 25, nil},
+		{"50%", 0, 0, ErrNeedsDuration},
+		{"1:2:3x", 0, 0, ErrBadTimestamp},
+		{"1:60", 0, 0, ErrBadTimestamp},
+		{"1.5:00", 0, 0, ErrBadTimestamp},
+		{"1:2:3:4", 0, 0, ErrBadTimestamp},
+		{"1e3", 0, 0, ErrBadTimestamp},
+		{"-5", 0, 0, ErrBadTimestamp},
+		{"101%", 600, 0, ErrBadTimestamp},
+		{"", 0, 0, ErrBadTimestamp},
+	}
+	for _, tt := range tests {
+		got, err := ParseTimestamp(tt.in, tt.duration)
+		if !errors.Is(err, tt.err) || got != tt.want {
+			t.Errorf("ParseTimestamp(%q, %d) = %v, %v; want %v, %v", tt.in, tt.duration, got, err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestParseRanges(t *testing.T) {
+	tests := []struct {
+		in       string
+		duration int
+		want     []models.TimeRange
+		err      error
+	}{
+		{"", 0, nil, nil},
+		{"1:00-2:00", 0, []models.TimeRange{{Start: 60, End: 120}}, nil},
+		{"-30, 50%-", 600, []models.TimeRange{{Start: 0, End: 30}, {Start: 300}}, nil},
+		// Sorted, with overlapping and touching ranges merged
+		{"5:00-6:00, 0:10-0:20, 0:15-1:00, 1:00-1:30", 0, []models.TimeRange{{Start: 10, End: 90}, {Start: 300, End: 360}}, nil},
+		{"1:00-, 2:00-3:00", 0, []models.TimeRange{{Start: 60}}, nil},
+		{"2:00-1:00", 0, nil, ErrRangeOrder},
+		{"1:00-1:00", 0, nil, ErrRangeOrder},
+		{"0:10-0", 0, nil, ErrRangeOrder},
+		{"9:00-11:00", 600, nil, ErrRangeTooLong},
+		{"10:00-", 600, nil, ErrRangeTooLong},
+		{"1:00", 0, nil, ErrBadRange},
+		{"1:00-2:00x", 0, nil, ErrBadTimestamp},
+	}
+	for _, tt := range tests {
+		got, err := ParseRanges(tt.in, tt.duration)
+		if !errors.Is(err, tt.err) || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseRanges(%q, %d) = %v, %v; want %v, %v", tt.in, tt.duration, got, err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestFormatRanges(t *testing.T) {
+	in := "0:00:10-0:01:30.5, 1:00:00-"
+	ranges, err := ParseRanges(in, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := FormatRanges(ranges); got != in {
+		t.Errorf("FormatRanges = %q, want %q", got, in)
+	}
+}
+
+func TestJoinSectionsWithoutFFmpeg(t *testing.T) {
+	dir := t.TempDir()
+	parts := []string{filepath.Join(dir, "Video [10s].mp4"), filepath.Join(dir, "Video [300s].mp4")}
+	for _, p := range parts {
+		if err := os.WriteFile(p, []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	moved := filepath.Join(dir, "moved.txt")
+	os.WriteFile(moved, []byte(strings.Join(parts, "\n")+"\n"), 0644)
+
+	y := &YtDlp{FFmpegPath: filepath.Join(dir, "missing-ffmpeg")}
+	err := y.joinSections(context.Background(), moved, func(models.ProgressUpdate) {})
+	var e *Error
+	if !errors.As(err, &e) || e.Kind != KindFFmpegMissing {
+		t.Fatalf("err = %v, want KindFFmpegMissing", err)
+	}
+	for _, p := range parts {
+		if _, err := os.Stat(p); err != nil {
+			t.Errorf("section %s was removed after a failed join", p)
+		}
+	}
+}
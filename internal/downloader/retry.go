@@ -0,0 +1,156 @@
+package downloader
+
+import (
+	"errors"
+	"gotube/internal/models"
+	"math"
+	"math/rand/v2"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RetryPolicy controls how Download retries failed yt-dlp runs.
+// The delay before attempt n+1 is BaseDelay * Factor^(n-1), capped at
+// MaxDelay and spread by ±Jitter, never beyond MaxDelay.
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+	Factor      float64
+	Jitter      float64 // Fraction of the delay, 0 to 1
+	Overrides   map[ErrorKind]RetryOverride
+}
+
+// RetryOverride adjusts the policy for one error kind; zero fields inherit.
+// Setting MaxAttempts also makes a normally permanent kind retryable.
+type RetryOverride struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+}
+
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   5 * time.Second,
+		MaxDelay:    2 * time.Minute,
+		Factor:      2,
+		Jitter:      0.2,
+		Overrides: map[ErrorKind]RetryOverride{
+			KindRateLimited: {MaxAttempts: 4, BaseDelay: 30 * time.Second},
+		},
+	}
+}
+
+// RetryableKinds are the kinds retried without an override, which the
+// System tab offers overrides for
+var RetryableKinds = []ErrorKind{KindUnknown, KindRateLimited, KindNetwork}
+
+// RetryPolicyFromSettings builds a policy from the System tab settings,
+// using the defaults for anything left unset. The user's attempt limit
+// also caps the built-in per-kind overrides, but not the user's own.
+func RetryPolicyFromSettings(s models.AppSettings) RetryPolicy {
+	p := DefaultRetryPolicy()
+	if s.RetryMaxAttempts > 0 {
+		p.MaxAttempts = s.RetryMaxAttempts
+		for kind, o := range p.Overrides {
+			if o.MaxAttempts > s.RetryMaxAttempts {
+				o.MaxAttempts = s.RetryMaxAttempts
+				p.Overrides[kind] = o
+			}
+		}
+	}
+	if s.RetryBaseDelay > 0 {
+		p.BaseDelay = time.Duration(s.RetryBaseDelay) * time.Second
+	}
+	if s.RetryMaxDelay > 0 {
+		p.MaxDelay = time.Duration(s.RetryMaxDelay) * time.Second
+	}
+	if s.RetryFactor >= 1 {
+		p.Factor = s.RetryFactor
+	}
+	if s.RetryJitter > 0 {
+		p.Jitter = math.Min(s.RetryJitter/100, 1)
+	}
+	for _, kind := range RetryableKinds {
+		saved, ok := s.RetryOverrides[kind.String()]
+		if !ok {
+			continue
+		}
+		o := p.Overrides[kind]
+		if saved.MaxAttempts > 0 {
+			o.MaxAttempts = saved.MaxAttempts
+		}
+		if saved.BaseDelay > 0 {
+			o.BaseDelay = time.Duration(saved.BaseDelay) * time.Second
+		}
+		if o != (RetryOverride{}) {
+			p.Overrides[kind] = o
+		}
+	}
+	return p
+}
+
+// ShouldRetry reports whether another attempt may follow the given failed one
+func (p RetryPolicy) ShouldRetry(attempt int, err error) bool {
+	var dlErr *Error
+	if !errors.As(err, &dlErr) {
+		return false // Couldn't even start yt-dlp
+	}
+	maxAttempts := p.MaxAttempts
+	o, hasOverride := p.Overrides[dlErr.Kind]
+	if hasOverride && o.MaxAttempts > 0 {
+		maxAttempts = o.MaxAttempts
+	} else if !dlErr.Kind.Retryable() {
+		return false
+	}
+	return attempt < maxAttempts
+}
+
+// Longest Retry-After hint we follow, so a bogus one can't stall a job for days
+const maxRetryAfter = 15 * time.Minute
+
+// Delay returns how long to wait after the given failed attempt.
+// A Retry-After hint from the server wins over the computed backoff, up to
+// maxRetryAfter.
+func (p RetryPolicy) Delay(attempt int, err error) time.Duration {
+	base := p.BaseDelay
+	var dlErr *Error
+	if errors.As(err, &dlErr) {
+		if dlErr.RetryAfter > 0 {
+			return min(dlErr.RetryAfter, maxRetryAfter)
+		}
+		if o, ok := p.Overrides[dlErr.Kind]; ok && o.BaseDelay > 0 {
+			base = o.BaseDelay
+		}
+	}
+
+	factor := math.Max(p.Factor, 1)
+	d := float64(base) * math.Pow(factor, float64(attempt-1))
+	if p.MaxDelay > 0 && d > float64(p.MaxDelay) {
+		d = float64(p.MaxDelay)
+	}
+	if p.Jitter > 0 {
+		d *= 1 + p.Jitter*(rand.Float64()*2-1)
+		if p.MaxDelay > 0 && d > float64(p.MaxDelay) {
+			d = float64(p.MaxDelay)
+		}
+	}
+	return time.Duration(d)
+}
+
+var retryAfterRegex = regexp.MustCompile(`(?i)(?:retry-after:\s*|retry after\s+|try again in\s+)(\d+)\s*(s|sec|seconds?|m|min|minutes?)?\b`)
+
+// parseRetryAfter extracts a server-provided wait time from yt-dlp output
+func parseRetryAfter(stderr string) time.Duration {
+	m := retryAfterRegex.FindStringSubmatch(stderr)
+	if m == nil {
+		return 0
+	}
+	n, _ := strconv.Atoi(m[1])
+	if strings.HasPrefix(strings.ToLower(m[2]), "m") {
+		return time.Duration(n) * time.Minute
+	}
+	return time.Duration(n) * time.Second
+}
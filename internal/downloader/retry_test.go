@@ -0,0 +1,156 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"gotube/internal/models"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestShouldRetry(t *testing.T) {
+	p := DefaultRetryPolicy()
+	tests := []struct {
+		name    string
+		attempt int
+		err     error
+		want    bool
+	}{
+		{"network, first attempt", 1, &Error{Kind: KindNetwork}, true},
+		{"network, out of attempts", 3, &Error{Kind: KindNetwork}, false},
+		{"unknown", 2, &Error{Kind: KindUnknown}, true},
+		{"wrapped", 1, errors.Join(errors.New("context"), &Error{Kind: KindNetwork}), true},
+		{"rate limited uses override", 3, &Error{Kind: KindRateLimited}, true},
+		{"rate limited, out of attempts", 4, &Error{Kind: KindRateLimited}, false},
+		{"permanent", 1, &Error{Kind: KindUnavailable}, false},
+		{"not a download error", 1, errors.New("exec: not found"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := p.ShouldRetry(tt.attempt, tt.err); got != tt.want {
+				t.Errorf("ShouldRetry(%d) = %v, want %v", tt.attempt, got, tt.want)
+			}
+		})
+	}
+
+	p.Overrides[KindAuthRequired] = RetryOverride{MaxAttempts: 2}
+	if !p.ShouldRetry(1, &Error{Kind: KindAuthRequired}) {
+		t.Error("override did not make a permanent kind retryable")
+	}
+}
+
+func TestDelay(t *testing.T) {
+	p := DefaultRetryPolicy()
+	p.Jitter = 0
+	tests := []struct {
+		name    string
+		attempt int
+		err     error
+		want    time.Duration
+	}{
+		{"first", 1, &Error{Kind: KindNetwork}, 5 * time.Second},
+		{"second", 2, &Error{Kind: KindNetwork}, 10 * time.Second},
+		{"third", 3, &Error{Kind: KindNetwork}, 20 * time.Second},
+		{"capped", 10, &Error{Kind: KindNetwork}, 2 * time.Minute},
+		{"override base", 1, &Error{Kind: KindRateLimited}, 30 * time.Second},
+		{"retry after wins", 3, &Error{Kind: KindRateLimited, RetryAfter: 7 * time.Second}, 7 * time.Second},
+		{"retry after clamped", 1, &Error{Kind: KindRateLimited, RetryAfter: 24 * time.Hour}, maxRetryAfter},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := p.Delay(tt.attempt, tt.err); got != tt.want {
+				t.Errorf("Delay(%d) = %v, want %v", tt.attempt, got, tt.want)
+			}
+		})
+	}
+
+	p.Jitter = 0.2
+	for i := 0; i < 100; i++ {
+		d := p.Delay(1, &Error{Kind: KindNetwork})
+		if d < 4*time.Second || d > 6*time.Second {
+			t.Fatalf("jittered delay %v outside ±20%% of 5s", d)
+		}
+		if d := p.Delay(10, &Error{Kind: KindNetwork}); d > p.MaxDelay {
+			t.Fatalf("jittered delay %v above MaxDelay", d)
+		}
+	}
+}
+
+func TestRetryPolicyFromSettings(t *testing.T) {
+	p := RetryPolicyFromSettings(models.AppSettings{RetryMaxAttempts: 5, RetryJitter: 150, RetryOverrides: map[string]models.RetryOverride{
+		"rate_limited": {BaseDelay: 60},
+		"network":      {MaxAttempts: 8, BaseDelay: 2},
+		"unknown":      {},
+	}})
+	if p.MaxAttempts != 5 || p.BaseDelay != 5*time.Second || p.Jitter != 1 {
+		t.Errorf("unexpected policy %+v", p)
+	}
+	if o := p.Overrides[KindRateLimited]; o.BaseDelay != time.Minute || o.MaxAttempts != 4 {
+		t.Errorf("unexpected rate limit override %+v", o)
+	}
+	if o := p.Overrides[KindNetwork]; o.BaseDelay != 2*time.Second || o.MaxAttempts != 8 {
+		t.Errorf("unexpected network override %+v", o)
+	}
+	if _, ok := p.Overrides[KindUnknown]; ok {
+		t.Error("empty override saved for unknown errors")
+	}
+	// The user's own override may allow more attempts than the general limit
+	if !p.ShouldRetry(7, &Error{Kind: KindNetwork}) {
+		t.Error("network override capped by RetryMaxAttempts")
+	}
+
+	p = RetryPolicyFromSettings(models.AppSettings{RetryMaxAttempts: 1})
+	if p.ShouldRetry(1, &Error{Kind: KindRateLimited}) {
+		t.Error("rate limit override retried past RetryMaxAttempts")
+	}
+}
+
+func TestDownloadRetries(t *testing.T) {
+	networkFailure := fakeRun{Stderr: []string{"ERROR: Unable to download webpage: Connection reset by peer"}, Exit: 1}
+	success := fakeRun{Stdout: []string{progressLine("finished", "1", "1", "NA", "NA", "NA", "NA", "NA", "NA", "NA")}}
+	unavailable := fakeRun{Stderr: []string{"ERROR: [youtube] x: Video unavailable"}, Exit: 1}
+
+	tests := []struct {
+		name     string
+		runs     []fakeRun
+		calls    int
+		kind     ErrorKind
+		wantErr  bool
+		attempts string
+	}{
+		{name: "succeeds after transient failures", runs: []fakeRun{networkFailure, networkFailure, success}, calls: 3},
+		{name: "gives up after max attempts", runs: []fakeRun{networkFailure}, calls: 3, wantErr: true, kind: KindNetwork, attempts: "failed after 3 attempts"},
+		{name: "permanent error is not retried", runs: []fakeRun{unavailable, success}, calls: 1, wantErr: true, kind: KindUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, calls := newFakeEngine(t, tt.runs...)
+			var retries int
+			err := e.Download(context.Background(), models.DownloadConfig{URL: "https://youtu.be/x", OutputPath: t.TempDir()}, func(u models.ProgressUpdate) {
+				if u.Stage == "Retrying" {
+					retries++
+				}
+			})
+			if n := len(calls()); n != tt.calls {
+				t.Errorf("yt-dlp ran %d times, want %d", n, tt.calls)
+			}
+			if retries != tt.calls-1 {
+				t.Errorf("%d retry updates, want %d", retries, tt.calls-1)
+			}
+			if !tt.wantErr {
+				if err != nil {
+					t.Fatalf("unexpected error %v", err)
+				}
+				return
+			}
+			var dlErr *Error
+			if !errors.As(err, &dlErr) || dlErr.Kind != tt.kind {
+				t.Fatalf("got %v, want a %v error", err, tt.kind)
+			}
+			if tt.attempts != "" && !strings.Contains(err.Error(), tt.attempts) {
+				t.Errorf("error %q does not mention %q", err, tt.attempts)
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package downloader
+
+import (
+	"encoding/json"
+	"fmt"
+	"gotube/internal/models"
+	"gotube/internal/utils"
+	"os"
+	"slices"
+	"strings"
+)
+
+// sponsorCategories returns the categories set to action, in display order.
+// UseSponsorBlock without any actions removes everything.
+func sponsorCategories(config models.DownloadConfig, action string) []string {
+	if len(config.SponsorBlock) == 0 {
+		if config.UseSponsorBlock && action == models.SponsorRemove {
+			return []string{"all"}
+		}
+		return nil
+	}
+	var categories []string
+	for _, c := range models.SponsorCategories {
+		if config.SponsorBlock[c] == action {
+			categories = append(categories, c)
+		}
+	}
+	return categories
+}
+
+func sponsorArgs(config models.DownloadConfig) []string {
+	var args []string
+	if remove := sponsorCategories(config, models.SponsorRemove); len(remove) > 0 {
+		args = append(args, "--sponsorblock-remove", strings.Join(remove, ","))
+	}
+	if mark := sponsorCategories(config, models.SponsorMark); len(mark) > 0 {
+		args = append(args, "--sponsorblock-mark", strings.Join(mark, ","))
+	}
+	return args
+}
+
+// ValidateSponsorBlock checks that actions only name known categories and
+// actions
+func ValidateSponsorBlock(actions map[string]string) error {
+	for c, a := range actions {
+		if !slices.Contains(models.SponsorCategories, c) {
+			return fmt.Errorf("unknown SponsorBlock category %q", c)
+		}
+		if a != models.SponsorRemove && a != models.SponsorMark && a != models.SponsorIgnore {
+			return fmt.Errorf("SponsorBlock action for %s must be %s, %s or %s", c, models.SponsorRemove, models.SponsorMark, models.SponsorIgnore)
+		}
+	}
+	return nil
+}
+
+// sponsorSegment is one entry of yt-dlp's sponsorblock_chapters field
+type sponsorSegment struct {
+	Start    float64 `json:"start_time"`
+	End      float64 `json:"end_time"`
+	Category string  `json:"category"`
+}
+
+// Printed per video after it is moved into place, to summarize the cuts
+const sponsorPrintTemplate = "%(sponsorblock_chapters)j\t%(title)s"
+
+// sponsorSummary describes how much was cut from each video in printed,
+// one line per video with cuts. Segments can overlap, so the total is
+// measured over the merged segments.
+func sponsorSummary(printed string, removed []string) []string {
+	var lines []string
+	for _, line := range strings.Split(printed, "\n") {
+		raw, title, ok := strings.Cut(line, "\t")
+		if !ok {
+			continue
+		}
+		var segments []sponsorSegment
+		if json.Unmarshal([]byte(raw), &segments) != nil {
+			continue // "NA" when SponsorBlock has no data for the video
+		}
+		perCategory := make(map[string]float64)
+		var cuts []models.TimeRange
+		for _, s := range segments {
+			if s.End <= s.Start || !slices.Contains(removed, s.Category) {
+				continue
+			}
+			perCategory[s.Category] += s.End - s.Start
+			cuts = append(cuts, models.TimeRange{Start: s.Start, End: s.End})
+		}
+		var total float64
+		for _, r := range MergeRanges(cuts) {
+			total += r.End - r.Start
+		}
+		if total < 0.5 {
+			continue
+		}
+		var parts []string
+		for _, c := range models.SponsorCategories {
+			if secs := perCategory[c]; secs > 0 {
+				parts = append(parts, fmt.Sprintf("%s %s", c, utils.FormatETA(int(secs+0.5))))
+			}
+		}
+		lines = append(lines, fmt.Sprintf("[SponsorBlock] Removed %s from %q (%s)", utils.FormatETA(int(total+0.5)), title, strings.Join(parts, ", ")))
+	}
+	return lines
+}
+
+// reportSponsorCuts logs sponsorSummary for the file yt-dlp printed to
+func reportSponsorCuts(path string, config models.DownloadConfig, report func(models.ProgressUpdate)) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+	removed := sponsorCategories(config, models.SponsorRemove)
+	if slices.Equal(removed, []string{"all"}) {
+		removed = models.SponsorCategories
+	}
+	for _, line := range sponsorSummary(string(data), removed) {
+		report(models.ProgressUpdate{Text: line})
+	}
+}
@@ -0,0 +1,30 @@
+package downloader
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSponsorSummary(t *testing.T) {
+	printed := `[{"start_time": 0, "end_time": 10, "category": "intro"}, {"start_time": 5, "end_time": 65, "category": "sponsor"}, {"start_time": 100, "end_time": 130, "category": "filler"}]	First
+NA	No data
+[{"start_time": 20, "end_time": 50, "category": "filler"}]	Only marked
+`
+	got := sponsorSummary(printed, []string{"sponsor", "intro"})
+	want := []string{`[SponsorBlock] Removed 1:05 from "First" (sponsor 1:00, intro 0:10)`}
+	if !slices.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestValidateSponsorBlock(t *testing.T) {
+	if err := ValidateSponsorBlock(map[string]string{"sponsor": "remove", "intro": "mark", "filler": "ignore"}); err != nil {
+		t.Error(err)
+	}
+	if ValidateSponsorBlock(map[string]string{"ads": "remove"}) == nil {
+		t.Error("unknown category accepted")
+	}
+	if ValidateSponsorBlock(map[string]string{"sponsor": "skip"}) == nil {
+		t.Error("unknown action accepted")
+	}
+}
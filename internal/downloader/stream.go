@@ -0,0 +1,79 @@
+package downloader
+
+import (
+	"bufio"
+	"errors"
+	"gotube/internal/models"
+	"io"
+	"strings"
+	"sync"
+)
+
+const (
+	// yt-dlp can print huge lines (e.g. JSON dumps); anything past this is dropped
+	maxLineLength = 1 << 20
+	// How much stderr is kept to classify a failure
+	stderrTailLines = 50
+)
+
+// readLines calls fn for every line read from r. Unlike bufio.Scanner it
+// never stops on long lines; they are truncated to maxLineLength instead.
+func readLines(r io.Reader, fn func(string)) {
+	br := bufio.NewReader(r)
+	var line []byte
+	for {
+		chunk, err := br.ReadSlice('\n')
+		if len(line)+len(chunk) <= maxLineLength {
+			line = append(line, chunk...)
+		} else if len(line) < maxLineLength {
+			line = append(line, chunk[:maxLineLength-len(line)]...)
+		}
+		if errors.Is(err, bufio.ErrBufferFull) {
+			continue
+		}
+		if len(line) > 0 {
+			fn(strings.TrimRight(string(line), "\r\n"))
+			line = line[:0]
+		}
+		if err != nil {
+			return // EOF, or the pipe was closed when the process exited
+		}
+	}
+}
+
+// lineTail keeps the last n lines written to it
+type lineTail struct {
+	mu    sync.Mutex
+	lines []string
+	max   int
+}
+
+func newLineTail(n int) *lineTail {
+	return &lineTail{max: n}
+}
+
+func (t *lineTail) add(line string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.lines) >= t.max {
+		t.lines = t.lines[1:]
+	}
+	t.lines = append(t.lines, line)
+}
+
+func (t *lineTail) String() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return strings.Join(t.lines, "\n")
+}
+
+// lineSeverity maps yt-dlp's stderr prefixes to a severity
+func lineSeverity(line string) models.Severity {
+	switch {
+	case strings.HasPrefix(line, "ERROR:"):
+		return models.SeverityError
+	case strings.HasPrefix(line, "WARNING:"):
+		return models.SeverityWarning
+	}
+	return models.SeverityInfo
+}
@@ -0,0 +1,222 @@
+package downloader
+
+import (
+	"errors"
+	"fmt"
+	"gotube/internal/models"
+	"gotube/internal/utils"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultOutputTemplate is yt-dlp's -o template when none is configured
+const DefaultOutputTemplate = "%(title)s.%(ext)s"
+
+// TemplatePresets are offered next to the user's own saved templates
+var TemplatePresets = []string{
+	DefaultOutputTemplate,
+	"%(title)s [%(id)s].%(ext)s",
+	"%(uploader)s/%(title)s.%(ext)s",
+	"%(uploader)s/%(upload_date>%Y-%m-%d)s - %(title)s.%(ext)s",
+	"%(playlist_title|Videos)s/%(playlist_index)03d - %(title)s.%(ext)s",
+}
+
+var (
+	ErrTemplateEmpty    = errors.New("template is empty")
+	ErrTemplateAbsolute = errors.New("template must be relative to the save folder")
+	ErrTemplateParent   = errors.New("template must not leave the save folder")
+	ErrTemplateNoExt    = errors.New("template must contain %(ext)s")
+)
+
+// Matches one yt-dlp template field, e.g. "%(title)s" or "%(playlist_index)03d"
+var templateFieldRegex = regexp.MustCompile(`%\(([^)]*)\)([-#0+ ]*\d*(?:\.\d+)?)([diouxXeEfFgGcrsaBjlqDSU])`)
+
+// ValidateTemplate checks that a template stays inside the save folder and
+// produces files with an extension
+func ValidateTemplate(tmpl string) error {
+	tmpl = strings.TrimSpace(tmpl)
+	if tmpl == "" {
+		return ErrTemplateEmpty
+	}
+	if filepath.IsAbs(tmpl) || strings.HasPrefix(tmpl, "/") || strings.HasPrefix(tmpl, `\`) {
+		return ErrTemplateAbsolute
+	}
+	for _, seg := range splitTemplate(tmpl) {
+		if strings.TrimSpace(seg) == ".." {
+			return ErrTemplateParent
+		}
+	}
+	if !strings.Contains(tmpl, "%(ext)s") {
+		return ErrTemplateNoExt
+	}
+	return nil
+}
+
+// outputTemplate returns the -o value for a job, with utils.SanitizeFilename
+// applied to the literal text of every path segment
+func outputTemplate(config models.DownloadConfig) string {
+	tmpl := config.OutputTemplate
+	if ValidateTemplate(tmpl) != nil {
+		tmpl = DefaultOutputTemplate
+	}
+	switch {
+	case len(config.Chapters) > 0:
+		// Every chapter is its own file
+		tmpl = beforeExt(tmpl, " - %(section_number)02d %(section_title)s")
+	case len(config.Ranges) > 1:
+		// So is every range, until joinSections combines them
+		tmpl = beforeExt(tmpl, sectionSuffix)
+	}
+	segments := splitTemplate(tmpl)
+	for i, seg := range segments {
+		// Hide the fields so their format specs survive sanitizing
+		fields := templateFieldRegex.FindAllString(seg, -1)
+		n := 0
+		masked := templateFieldRegex.ReplaceAllStringFunc(seg, func(string) string {
+			n++
+			return fmt.Sprintf("\x00%d\x00", n-1)
+		})
+		masked = utils.SanitizeFilename(masked)
+		for j, f := range fields {
+			masked = strings.Replace(masked, fmt.Sprintf("\x00%d\x00", j), f, 1)
+		}
+		segments[i] = masked
+	}
+	return filepath.Join(append([]string{config.OutputPath}, segments...)...)
+}
+
+// Names the file of each range by its start, e.g. "Title [90s].mp4"
+const sectionSuffix = " [%(section_start)ds]"
+
+var sectionSuffixRegex = regexp.MustCompile(` \[\d+s\](\.[^.]+)$`)
+
+// beforeExt inserts text in front of the template's extension
+func beforeExt(tmpl, text string) string {
+	i := strings.LastIndex(tmpl, ".%(ext)s")
+	if i < 0 {
+		i = strings.LastIndex(tmpl, "%(ext)s")
+	}
+	return tmpl[:i] + text + tmpl[i:]
+}
+
+// chapterTemplate names the files --split-chapters writes: a folder per
+// video, next to where the video itself goes
+func chapterTemplate(config models.DownloadConfig) string {
+	dir := filepath.Dir(outputTemplate(models.DownloadConfig{OutputPath: config.OutputPath, OutputTemplate: config.OutputTemplate}))
+	return filepath.Join(dir, "%(title)s", "%(section_number)02d - %(section_title)s.%(ext)s")
+}
+
+// chapterRegex matches exactly one chapter title for --download-sections
+func chapterRegex(title string) string {
+	return "^" + regexp.QuoteMeta(title) + "$"
+}
+
+// splitTemplate splits on path separators outside of fields, since a date
+// format like %(upload_date>%Y/%m)s may contain one. Like yt-dlp, such
+// separators never create folders.
+func splitTemplate(tmpl string) []string {
+	var segments []string
+	start, depth := 0, 0
+	for i := 0; i < len(tmpl); i++ {
+		switch c := tmpl[i]; {
+		case c == '(' && i > 0 && tmpl[i-1] == '%':
+			depth++
+		case c == ')' && depth > 0:
+			depth--
+		case (c == '/' || c == '\\') && depth == 0:
+			segments = append(segments, tmpl[start:i])
+			start = i + 1
+		}
+	}
+	return append(segments, tmpl[start:])
+}
+
+// TemplateFields returns the values yt-dlp would use for meta, for previews.
+// For playlists the first entry stands in for every video.
+func TemplateFields(meta *models.VideoMetadata, ext string) map[string]string {
+	fields := map[string]string{"ext": ext}
+	if meta == nil {
+		return fields
+	}
+	fields["title"] = meta.Title
+	fields["id"] = meta.ID
+	fields["uploader"] = meta.Uploader
+	fields["channel"] = meta.Channel
+	fields["upload_date"] = meta.UploadDate
+	if meta.Duration > 0 {
+		fields["duration"] = strconv.Itoa(meta.Duration)
+	}
+	if meta.Type == "playlist" {
+		fields["playlist"] = meta.Title
+		fields["playlist_title"] = meta.Title
+		fields["playlist_id"] = meta.ID
+		fields["playlist_index"] = "1"
+		fields["playlist_autonumber"] = "1"
+		fields["n_entries"] = strconv.Itoa(meta.EntryCount)
+		if len(meta.Entries) > 0 {
+			fields["title"] = meta.Entries[0].Title
+			fields["id"] = meta.Entries[0].ID
+		}
+	}
+	return fields
+}
+
+// RenderTemplate approximates yt-dlp's template expansion: alternatives
+// ("%(a,b)s"), defaults ("%(a|x)s"), date formats ("%(upload_date>%Y)s") and
+// numeric padding. Missing values become "NA", like in yt-dlp.
+func RenderTemplate(tmpl string, fields map[string]string) string {
+	rendered := templateFieldRegex.ReplaceAllStringFunc(tmpl, func(field string) string {
+		m := templateFieldRegex.FindStringSubmatch(field)
+		expr, spec, verb := m[1], m[2], m[3]
+
+		expr, def, hasDefault := strings.Cut(expr, "|")
+		expr, dateFormat, _ := strings.Cut(expr, ">")
+		var value string
+		for _, name := range strings.Split(expr, ",") {
+			if v := fields[strings.TrimSpace(name)]; v != "" {
+				value = v
+				break
+			}
+		}
+		switch {
+		case value == "" && hasDefault:
+			return def
+		case value == "":
+			return "NA"
+		case dateFormat != "":
+			value = formatDate(value, dateFormat)
+		}
+		if strings.ContainsAny(verb, "di") {
+			if n, err := strconv.Atoi(value); err == nil {
+				return fmt.Sprintf("%"+spec+"d", n)
+			}
+		}
+		// Field values can't create folders
+		return strings.NewReplacer("/", "_", `\`, "_").Replace(value)
+	})
+
+	segments := splitTemplate(rendered)
+	for i, seg := range segments {
+		segments[i] = utils.SanitizeFilename(seg)
+	}
+	return strings.Join(segments, string(filepath.Separator))
+}
+
+// formatDate applies a strftime-style format to a YYYYMMDD date
+func formatDate(value, format string) string {
+	t, err := time.Parse("20060102", value)
+	if err != nil {
+		return value
+	}
+	return strings.NewReplacer(
+		"%Y", t.Format("2006"),
+		"%y", t.Format("06"),
+		"%m", t.Format("01"),
+		"%d", t.Format("02"),
+		"%B", t.Format("January"),
+		"%b", t.Format("Jan"),
+	).Replace(format)
+}
@@ -0,0 +1,78 @@
+package downloader
+
+import (
+	"errors"
+	"gotube/internal/models"
+	"path/filepath"
+	"testing"
+)
+
+func TestValidateTemplate(t *testing.T) {
+	tests := []struct {
+		tmpl string
+		want error
+	}{
+		{DefaultOutputTemplate, nil},
+		{"%(uploader)s/%(upload_date>%Y/%m)s/%(title)s.%(ext)s", nil},
+		{"  ", ErrTemplateEmpty},
+		{"/tmp/%(title)s.%(ext)s", ErrTemplateAbsolute},
+		{"../%(title)s.%(ext)s", ErrTemplateParent},
+		{"%(uploader)s/../../%(title)s.%(ext)s", ErrTemplateParent},
+		{"%(title)s", ErrTemplateNoExt},
+	}
+	for _, tt := range tests {
+		if err := ValidateTemplate(tt.tmpl); !errors.Is(err, tt.want) {
+			t.Errorf("ValidateTemplate(%q) = %v, want %v", tt.tmpl, err, tt.want)
+		}
+	}
+}
+
+func TestRenderTemplate(t *testing.T) {
+	video := &models.VideoMetadata{ID: "abc", Title: "AC/DC: Live?", Uploader: "Some Channel", UploadDate: "20240302"}
+	playlist := &models.VideoMetadata{ID: "PL1", Title: "Mix", Type: "playlist", EntryCount: 12,
+		Entries: []models.PlaylistEntry{{ID: "e1", Title: "First"}}}
+	sep := string(filepath.Separator)
+
+	tests := []struct {
+		name string
+		tmpl string
+		meta *models.VideoMetadata
+		want string
+	}{
+		{"default", DefaultOutputTemplate, video, "AC_DC_ Live_.mp4"},
+		{"subfolder and date", "%(uploader)s/%(upload_date>%Y-%m-%d)s - %(title)s [%(id)s].%(ext)s", video,
+			"Some Channel" + sep + "2024-03-02 - AC_DC_ Live_ [abc].mp4"},
+		{"separator in field", "%(upload_date>%Y/%m)s/%(id)s.%(ext)s", video, "2024_03" + sep + "abc.mp4"},
+		{"missing field", "%(playlist_title)s/%(id)s.%(ext)s", video, "NA" + sep + "abc.mp4"},
+		{"default value", "%(playlist_title|Singles)s/%(id)s.%(ext)s", video, "Singles" + sep + "abc.mp4"},
+		{"alternatives", "%(channel,uploader)s.%(ext)s", video, "Some Channel.mp4"},
+		{"playlist index", "%(playlist_title)s/%(playlist_index)03d - %(title)s.%(ext)s", playlist,
+			"Mix" + sep + "001 - First.mp4"},
+		{"literal text", "My: Videos/%(id)s.%(ext)s", video, "My_ Videos" + sep + "abc.mp4"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RenderTemplate(tt.tmpl, TemplateFields(tt.meta, "mp4")); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOutputTemplate(t *testing.T) {
+	tests := []struct {
+		tmpl string
+		want string
+	}{
+		{"", filepath.Join("/out", DefaultOutputTemplate)},
+		{"../%(title)s.%(ext)s", filepath.Join("/out", DefaultOutputTemplate)},
+		{"Music: %(uploader)s/%(upload_date>%Y-%m)s %(title)s.%(ext)s", filepath.Join("/out", "Music_ %(uploader)s", "%(upload_date>%Y-%m)s %(title)s.%(ext)s")},
+		{"%(upload_date>%Y/%m)s/%(title)s.%(ext)s", filepath.Join("/out", "%(upload_date>%Y/%m)s", "%(title)s.%(ext)s")},
+	}
+	for _, tt := range tests {
+		got := outputTemplate(models.DownloadConfig{OutputPath: "/out", OutputTemplate: tt.tmpl})
+		if got != tt.want {
+			t.Errorf("outputTemplate(%q) = %q, want %q", tt.tmpl, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+// Command fakeytdlp stands in for yt-dlp in the downloader tests. Each
+// invocation appends its arguments to $FAKE_YTDLP_LOG and replays the next
+// run from the JSON script in $FAKE_YTDLP_SCRIPT; the last run repeats.
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+type run struct {
+	Stdout []string `json:"stdout"`
+	Stderr []string `json:"stderr"`
+	Exit   int      `json:"exit"`
+	Create []string `json:"create"` // Files to create before any output
+	Hang   bool     `json:"hang"`   // Block after the output until killed
+}
+
+func main() {
+	var runs []run
+	data, err := os.ReadFile(os.Getenv("FAKE_YTDLP_SCRIPT"))
+	if err == nil {
+		err = json.Unmarshal(data, &runs)
+	}
+	if err != nil || len(runs) == 0 {
+		fmt.Fprintln(os.Stderr, "fakeytdlp: bad script:", err)
+		os.Exit(2)
+	}
+
+	logPath := os.Getenv("FAKE_YTDLP_LOG")
+	prev, _ := os.ReadFile(logPath)
+	n := strings.Count(string(prev), "\n")
+	line, _ := json.Marshal(os.Args[1:])
+	f, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "fakeytdlp:", err)
+		os.Exit(2)
+	}
+	f.Write(append(line, '\n'))
+	f.Close()
+
+	r := runs[min(n, len(runs)-1)]
+	for _, name := range r.Create {
+		os.WriteFile(name, []byte("partial"), 0644)
+	}
+	for _, l := range r.Stdout {
+		fmt.Fprintln(os.Stdout, l)
+	}
+	for _, l := range r.Stderr {
+		fmt.Fprintln(os.Stderr, l)
+	}
+	if r.Hang {
+		time.Sleep(time.Minute)
+	}
+	os.Exit(r.Exit)
+}
@@ -0,0 +1,551 @@
+package downloader
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gotube/internal/models"
+	"gotube/internal/utils"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var (
+	destinationRegex = regexp.MustCompile(`^\[download\] Destination: (.+)$`)
+	progressRegex    = regexp.MustCompile(`\[download\]\s+(\d+\.?\d*)%`)
+)
+
+// YtDlp is the default backend, driving the yt-dlp executable
+type YtDlp struct {
+	BinaryPath string
+	// SelfUpdate replaces the binary (see updater.BinaryManager). If nil,
+	// Update falls back to yt-dlp's own "-U".
+	SelfUpdate func(progress func(string)) error
+	// FFmpegPath runs ffmpeg for joining time ranges; "" looks it up in PATH
+	FFmpegPath string
+}
+
+func NewYtDlp(binaryPath string) *YtDlp {
+	return &YtDlp{BinaryPath: binaryPath}
+}
+
+func (y *YtDlp) Name() string {
+	return BackendYtDlp
+}
+
+func (y *YtDlp) GetMetadata(ctx context.Context, url string) (*models.VideoMetadata, error) {
+	// --flat-playlist gives us the list of entries (ID + Title) very quickly
+	args := append([]string{"--dump-single-json", "--flat-playlist", url}, networkArgs(networkFrom(ctx))...)
+	output, err := y.run(ctx, args...)
+	if err != nil {
+		return nil, err
+	}
+	var meta models.VideoMetadata
+	if err := json.Unmarshal(output, &meta); err != nil {
+		return nil, fmt.Errorf("failed to parse metadata: %v", err)
+	}
+	return &meta, nil
+}
+
+func (y *YtDlp) ListFormats(ctx context.Context, url string) ([]models.Format, error) {
+	meta, err := y.GetMetadata(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	return meta.Formats, nil
+}
+
+func (y *YtDlp) Version(ctx context.Context) (string, error) {
+	output, err := y.run(ctx, "--version")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+func (y *YtDlp) Update(ctx context.Context, progress func(string)) error {
+	if y.SelfUpdate != nil {
+		return y.SelfUpdate(progress)
+	}
+	output, err := y.run(ctx, append([]string{"-U"}, networkArgs(utils.Network())...)...)
+	progress(strings.TrimSpace(string(output)))
+	return err
+}
+
+// run executes a short yt-dlp command with the network settings of ctx (see
+// WithNetwork) and returns its stdout
+func (y *YtDlp) run(ctx context.Context, args ...string) ([]byte, error) {
+	env, cleanup, err := networkEnv(networkFrom(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+	cmd := exec.CommandContext(ctx, y.BinaryPath, args...)
+	cmd.Env = env
+	configureProcess(cmd)
+	output, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && ctx.Err() == nil {
+			return output, classifyError(err, string(exitErr.Stderr))
+		}
+		return output, err
+	}
+	return output, nil
+}
+
+func (y *YtDlp) buildArgs(config models.DownloadConfig) []string {
+	if config.SafeMode {
+		args := []string{config.URL, "-o", filepath.Join(config.OutputPath, "safe_%(title)s.%(ext)s"), "-f", "best"}
+		return append(append(args, rateArgs(config)...), networkArgs(config.Network)...)
+	}
+	audio := config.DownloadMode == "Audio"
+	container := config.Prefs.Container
+	if container == "" {
+		container = "mp4"
+	}
+	args := []string{
+		config.URL,
+		"-o", outputTemplate(config),
+		"--no-mtime",
+		"--continue", // Resume .part files left by interrupted jobs
+		"--newline",
+		"--add-metadata",
+	}
+	// Thumbnails can't be embedded in WebM or WAV; yt-dlp fails the job if asked
+	if (audio && config.Quality != "wav") || (!audio && container != "webm") {
+		args = append(args, "--embed-thumbnail")
+	}
+	args = append(args, progressArgs()...)
+
+	// PLAYLIST LOGIC
+	if config.IsPlaylist {
+		args = append(args, "--yes-playlist")
+		// If user selected specific videos (e.g. "1,3,5")
+		if config.PlaylistItems != "" {
+			args = append(args, "--playlist-items", config.PlaylistItems)
+		}
+	} else {
+		args = append(args, "--no-playlist")
+	}
+
+	if config.EmbedSubs || config.WriteSubs {
+		args = append(args, subtitleArgs(config, audio, container)...)
+	}
+
+	if audio {
+		args = append(args, "-x")
+		if config.FormatID != "" {
+			args = append(args, "-f", config.FormatID)
+		}
+		switch config.Quality {
+		case "mp3":
+			args = append(args, "--audio-format", "mp3", "--audio-quality", "0")
+		case "m4a", "opus", "flac", "wav":
+			args = append(args, "--audio-format", config.Quality)
+		case "ogg":
+			args = append(args, "--audio-format", "vorbis")
+		default:
+			args = append(args, "--audio-format", "best")
+		}
+	} else {
+		args = append(args, "--merge-output-format", container)
+		switch {
+		case config.FormatID != "":
+			args = append(args, "-f", config.FormatID)
+		case config.Quality == "4k":
+			args = append(args, "-f", "bestvideo[height<=2160]+bestaudio/best")
+		case config.Quality == "1080p":
+			args = append(args, "-f", "bestvideo[height<=1080]+bestaudio/best")
+		case config.Quality == "720p":
+			args = append(args, "-f", "bestvideo[height<=720]+bestaudio/best")
+		default:
+			args = append(args, "-f", "bestvideo+bestaudio/best")
+		}
+	}
+
+	if sort := formatSort(config.Prefs, audio); sort != "" {
+		args = append(args, "-S", sort)
+	}
+
+	if len(config.Chapters) > 0 {
+		for _, title := range config.Chapters {
+			args = append(args, "--download-sections", chapterRegex(title))
+		}
+		args = append(args, "--force-keyframes-at-cuts")
+	} else if len(config.Ranges) > 0 {
+		for _, r := range config.Ranges {
+			args = append(args, "--download-sections", rangeSection(r))
+		}
+		args = append(args, "--force-keyframes-at-cuts")
+	} else if config.TrimStart != "" {
+		section := fmt.Sprintf("*%s-%s", config.TrimStart, config.TrimEnd)
+		if config.TrimEnd == "" {
+			section = fmt.Sprintf("*%s-inf", config.TrimStart)
+		}
+		args = append(args, "--download-sections", section, "--force-keyframes-at-cuts")
+	}
+	if config.SplitChapters {
+		args = append(args, "--split-chapters", "-o", "chapter:"+chapterTemplate(config))
+	}
+	if config.EmbedChapters {
+		args = append(args, "--embed-chapters")
+	}
+	args = append(args, sponsorArgs(config)...)
+	if config.Client != "" && config.Client != "Web" {
+		args = append(args, "--extractor-args", fmt.Sprintf("youtube:player_client=%s", strings.ToUpper(config.Client)))
+	}
+	if config.CookiesPath != "" {
+		args = append(args, "--cookies", config.CookiesPath)
+	}
+	if config.ArchivePath != "" {
+		args = append(args, "--download-archive", config.ArchivePath)
+	}
+	if config.DateAfter != "" {
+		args = append(args, "--dateafter", config.DateAfter)
+	}
+	args = append(args, rateArgs(config)...)
+	return append(args, networkArgs(config.Network)...)
+}
+
+// SubFormats are the formats subtitle files can be saved in
+var SubFormats = []string{"srt", "vtt", "ass"}
+
+// subtitleArgs embeds the subtitles and/or saves them next to the file
+func subtitleArgs(config models.DownloadConfig, audio bool, container string) []string {
+	var args []string
+	format := config.SubFormat
+	if !slices.Contains(SubFormats, format) {
+		format = "srt"
+	}
+	if config.EmbedSubs {
+		args = append(args, "--embed-subs")
+		if !audio && container == "webm" {
+			// WebM only takes WebVTT, so the saved files follow suit
+			format = "vtt"
+		} else if !config.WriteSubs {
+			format = "srt" // MP4 and MKV take SRT
+		}
+	}
+	if config.WriteSubs {
+		args = append(args, "--write-subs")
+	}
+	args = append(args, "--convert-subs", format)
+	if config.AutoSubs {
+		args = append(args, "--write-auto-subs")
+	}
+	return append(args, "--sub-langs", subLangs(config))
+}
+
+// subLangs returns the --sub-langs value. yt-dlp takes regexes, so picked
+// languages are quoted to match exactly.
+func subLangs(config models.DownloadConfig) string {
+	if len(config.SubLanguages) > 0 {
+		langs := make([]string, len(config.SubLanguages))
+		for i, lang := range config.SubLanguages {
+			langs[i] = regexp.QuoteMeta(lang)
+		}
+		return strings.Join(langs, ",")
+	}
+	switch config.SubLanguage {
+	case "de":
+		return "de.*,en.*"
+	case "all":
+		return "all"
+	}
+	return "en.*"
+}
+
+// rangeSection turns a range into a --download-sections value like "*90-120.5"
+func rangeSection(r models.TimeRange) string {
+	end := "inf"
+	if r.End != 0 {
+		end = strconv.FormatFloat(r.End, 'f', -1, 64)
+	}
+	return "*" + strconv.FormatFloat(r.Start, 'f', -1, 64) + "-" + end
+}
+
+// rateArgs applies the rate limit, which even safe mode must respect
+func rateArgs(config models.DownloadConfig) []string {
+	if config.RateLimit <= 0 {
+		return nil
+	}
+	return []string{"--limit-rate", strconv.FormatInt(config.RateLimit, 10)}
+}
+
+// networkArgs applies the network settings, which even safe mode must
+// respect. The CA bundle goes through the environment, see networkEnv.
+func networkArgs(n models.NetworkConfig) []string {
+	var args []string
+	if n.Proxy != "" {
+		args = append(args, "--proxy", n.Proxy)
+	}
+	if n.SourceAddress != "" {
+		args = append(args, "--source-address", n.SourceAddress)
+	}
+	switch n.IPVersion {
+	case 4:
+		args = append(args, "--force-ipv4")
+	case 6:
+		args = append(args, "--force-ipv6")
+	}
+	if n.CABundle != "" {
+		// yt-dlp trusts certifi's bundle over the system store, which is
+		// where SSL_CERT_FILE applies
+		args = append(args, "--compat-options", "no-certifi")
+	}
+	return args
+}
+
+// networkEnv returns yt-dlp's environment and a function that cleans up
+// after the run. A CA bundle replaces Python's trust store, so yt-dlp gets a
+// copy that also holds the system roots, which the Go side trusts as well.
+// A nil environment keeps the parent's.
+func networkEnv(n models.NetworkConfig) ([]string, func(), error) {
+	if n.CABundle == "" {
+		return nil, func() {}, nil
+	}
+	bundle, err := utils.WriteCABundle(n.CABundle)
+	if err != nil {
+		return nil, nil, err
+	}
+	env := append(os.Environ(), "SSL_CERT_FILE="+bundle, "REQUESTS_CA_BUNDLE="+bundle)
+	return env, func() { os.Remove(bundle) }, nil
+}
+
+// yt-dlp's names for the codecs offered in the settings
+var sortCodecs = map[string]string{
+	"avc1": "h264",
+	"vp9":  "vp9",
+	"av01": "av01",
+	"aac":  "aac",
+	"opus": "opus",
+}
+
+// formatSort builds a -S value from the preferences, or "" to keep yt-dlp's
+// default order. Resolution stays the first criterion so a codec preference
+// never trades 1080p for 480p.
+func formatSort(p models.FormatPrefs, audio bool) string {
+	var fields []string
+	if !audio {
+		if p.MaxFPS > 0 {
+			fields = append(fields, fmt.Sprintf("fps:%d", p.MaxFPS))
+		}
+		switch p.DynamicRange {
+		case "hdr":
+			fields = append(fields, "hdr")
+		case "sdr":
+			fields = append(fields, "hdr:sdr")
+		}
+		if c, ok := sortCodecs[p.VideoCodec]; ok {
+			fields = append(fields, "vcodec:"+c)
+		}
+	}
+	if c, ok := sortCodecs[p.AudioCodec]; ok {
+		fields = append(fields, "acodec:"+c)
+	}
+	if !audio {
+		// Prefer streams that fit the container without re-encoding
+		switch p.Container {
+		case "mp4":
+			fields = append(fields, "ext:mp4:m4a")
+		case "webm":
+			fields = append(fields, "ext:webm:webm")
+		}
+	}
+	if len(fields) == 0 {
+		return ""
+	}
+	if !audio {
+		fields = append([]string{"res"}, fields...)
+	}
+	return strings.Join(fields, ",")
+}
+
+// Download runs a single yt-dlp attempt and returns the destination files it
+// started writing, so they can be cleaned up on cancel.
+func (y *YtDlp) Download(ctx context.Context, config models.DownloadConfig, callback func(models.ProgressUpdate)) ([]string, error) {
+	args := y.buildArgs(config)
+	var moved, sponsored string
+	var err error
+	if joinRanges(config) {
+		// yt-dlp writes the final path of every section here, after merging
+		if moved, err = printToFile(&args, "filepath"); err != nil {
+			return nil, err
+		}
+		defer os.Remove(moved)
+	}
+	if len(sponsorCategories(config, models.SponsorRemove)) > 0 && !config.SafeMode {
+		if sponsored, err = printToFile(&args, sponsorPrintTemplate); err != nil {
+			return nil, err
+		}
+		defer os.Remove(sponsored)
+	}
+	env, cleanup, err := networkEnv(config.Network)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+	cmd := exec.CommandContext(ctx, y.BinaryPath, args...)
+	cmd.Env = env
+	configureProcess(cmd)
+	stdout, _ := cmd.StdoutPipe()
+	stderr, _ := cmd.StderrPipe()
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	var files []string
+	tail := newLineTail(stderrTailLines)
+	var wg sync.WaitGroup
+	wg.Add(2)
+
+	// Callers get updates one at a time, even though both pipes report them
+	var callbackMu sync.Mutex
+	report := func(u models.ProgressUpdate) {
+		callbackMu.Lock()
+		defer callbackMu.Unlock()
+		callback(u)
+	}
+
+	// Both pipes must be drained concurrently: if stderr fills up while we
+	// are still reading stdout, yt-dlp blocks and never exits.
+	go func() {
+		defer wg.Done()
+		readLines(stdout, func(line string) {
+			if m := destinationRegex.FindStringSubmatch(line); m != nil {
+				files = append(files, m[1])
+			}
+			if update, ok := parseProgressLine(line); ok {
+				report(update)
+				return
+			}
+			// Fallback for plain yt-dlp output (e.g. Safe Mode runs without our template)
+			matches := progressRegex.FindStringSubmatch(line)
+			var percent float64
+			if len(matches) > 1 {
+				p, _ := strconv.ParseFloat(matches[1], 64)
+				percent = p / 100.0
+			}
+			report(models.ProgressUpdate{Percent: percent, Text: line, Stage: "Downloading"})
+		})
+	}()
+	go func() {
+		defer wg.Done()
+		readLines(stderr, func(line string) {
+			tail.add(line)
+			report(models.ProgressUpdate{Text: line, Severity: lineSeverity(line)})
+		})
+	}()
+	wg.Wait()
+
+	if err := cmd.Wait(); err != nil {
+		if ctx.Err() != nil {
+			return files, err
+		}
+		return files, classifyError(err, tail.String())
+	}
+	if sponsored != "" {
+		reportSponsorCuts(sponsored, config, report)
+	}
+	if moved != "" {
+		return files, y.joinSections(ctx, moved, report)
+	}
+	return files, nil
+}
+
+// printToFile makes yt-dlp write tmpl for every video, once it is in place,
+// to a new temporary file, whose path it returns
+func printToFile(args *[]string, tmpl string) (string, error) {
+	f, err := os.CreateTemp("", "gotube-print-*.txt")
+	if err != nil {
+		return "", err
+	}
+	f.Close()
+	*args = append(*args, "--print-to-file", "after_move:"+tmpl, f.Name())
+	return f.Name(), nil
+}
+
+func joinRanges(config models.DownloadConfig) bool {
+	return config.ConcatRanges && len(config.Ranges) > 1 && len(config.Chapters) == 0 && !config.SafeMode
+}
+
+// joinSections concatenates the section files listed in moved into one file
+// per video, named like the video would be without ranges. The sections
+// were cut at keyframes, so ffmpeg can copy the streams.
+func (y *YtDlp) joinSections(ctx context.Context, moved string, report func(models.ProgressUpdate)) error {
+	data, err := os.ReadFile(moved)
+	if err != nil {
+		return err
+	}
+	var outputs []string
+	parts := make(map[string][]string)
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		out := sectionSuffixRegex.ReplaceAllString(line, "$1")
+		if _, ok := parts[out]; !ok {
+			outputs = append(outputs, out)
+		}
+		parts[out] = append(parts[out], line)
+	}
+
+	for _, out := range outputs {
+		if len(parts[out]) < 2 || out == parts[out][0] {
+			continue
+		}
+		report(models.ProgressUpdate{Stage: "Processing", Text: fmt.Sprintf("[gotube] Joining %d sections into %s", len(parts[out]), out)})
+		if err := y.concat(ctx, parts[out], out); err != nil {
+			return err
+		}
+		for _, part := range parts[out] {
+			os.Remove(part)
+		}
+	}
+	return nil
+}
+
+// concat runs ffmpeg's concat demuxer over files
+func (y *YtDlp) concat(ctx context.Context, files []string, out string) error {
+	list, err := os.CreateTemp("", "gotube-concat-*.txt")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(list.Name())
+	for _, f := range files {
+		abs, _ := filepath.Abs(f)
+		fmt.Fprintf(list, "file '%s'\n", strings.ReplaceAll(abs, "'", `'\''`))
+	}
+	if err := list.Close(); err != nil {
+		return err
+	}
+
+	ffmpeg := y.FFmpegPath
+	if ffmpeg == "" {
+		ffmpeg = "ffmpeg"
+	}
+	cmd := exec.CommandContext(ctx, ffmpeg, "-y", "-loglevel", "error", "-f", "concat", "-safe", "0",
+		"-i", list.Name(), "-map", "0", "-c", "copy", out)
+	configureProcess(cmd)
+	output, err := cmd.CombinedOutput()
+	if errors.Is(err, exec.ErrNotFound) || errors.Is(err, os.ErrNotExist) {
+		return &Error{Kind: KindFFmpegMissing, Message: err.Error(), Err: err}
+	}
+	if err != nil {
+		os.Remove(out)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("joining sections: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
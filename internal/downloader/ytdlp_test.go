@@ -0,0 +1,400 @@
+package downloader
+
+import (
+	"bytes"
+	"context"
+	"encoding/pem"
+	"errors"
+	"gotube/internal/models"
+	"gotube/internal/utils"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// containsSeq reports whether want appears in args as a contiguous run
+func containsSeq(args []string, want ...string) bool {
+	for i := 0; i+len(want) <= len(args); i++ {
+		if slices.Equal(args[i:i+len(want)], want) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestBuildArgs(t *testing.T) {
+	base := models.DownloadConfig{URL: "https://youtu.be/x", OutputPath: "/out"}
+	tests := []struct {
+		name    string
+		modify  func(c *models.DownloadConfig)
+		want    [][]string
+		notWant []string
+	}{
+		{
+			name: "defaults",
+			want: [][]string{
+				{"https://youtu.be/x"},
+				{"-o", filepath.Join("/out", "%(title)s.%(ext)s")},
+				{"--continue"},
+				{"--no-playlist"},
+				{"--merge-output-format", "mp4"},
+				{"-f", "bestvideo+bestaudio/best"},
+			},
+			notWant: []string{"--yes-playlist", "--embed-subs", "--cookies", "--download-sections", "--extractor-args", "-S", "--download-archive"},
+		},
+		{
+			name:    "safe mode",
+			modify:  func(c *models.DownloadConfig) { c.SafeMode = true; c.EmbedSubs = true },
+			want:    [][]string{{"-f", "best"}, {"-o", filepath.Join("/out", "safe_%(title)s.%(ext)s")}},
+			notWant: []string{"--embed-subs", "--progress-template", "--add-metadata"},
+		},
+		{
+			name: "webm with preferences",
+			modify: func(c *models.DownloadConfig) {
+				c.EmbedSubs = true
+				c.Prefs = models.FormatPrefs{Container: "webm", VideoCodec: "vp9", AudioCodec: "opus", DynamicRange: "sdr", MaxFPS: 30}
+			},
+			want: [][]string{
+				{"--merge-output-format", "webm"},
+				{"--convert-subs", "vtt"},
+				{"-S", "res,fps:30,hdr:sdr,vcodec:vp9,acodec:opus,ext:webm:webm"},
+			},
+			notWant: []string{"--embed-thumbnail"},
+		},
+		{
+			name: "audio ogg",
+			modify: func(c *models.DownloadConfig) {
+				c.DownloadMode = "Audio"
+				c.Quality = "ogg"
+				c.Prefs.VideoCodec = "av01"
+			},
+			want:    [][]string{{"--audio-format", "vorbis"}, {"--embed-thumbnail"}},
+			notWant: []string{"-S"},
+		},
+		{
+			name: "audio wav",
+			modify: func(c *models.DownloadConfig) {
+				c.DownloadMode = "Audio"
+				c.Quality = "wav"
+				c.Prefs.AudioCodec = "aac"
+			},
+			want:    [][]string{{"--audio-format", "wav"}, {"-S", "acodec:aac"}},
+			notWant: []string{"--embed-thumbnail"},
+		},
+		{
+			name:   "archive",
+			modify: func(c *models.DownloadConfig) { c.ArchivePath = "/tmp/archive.txt" },
+			want:   [][]string{{"--download-archive", "/tmp/archive.txt"}},
+		},
+		{
+			name:   "date filter",
+			modify: func(c *models.DownloadConfig) { c.DateAfter = "20240101" },
+			want:   [][]string{{"--dateafter", "20240101"}},
+		},
+		{
+			name:   "rate limit",
+			modify: func(c *models.DownloadConfig) { c.RateLimit = 512000 },
+			want:   [][]string{{"--limit-rate", "512000"}},
+		},
+		{
+			name: "network",
+			modify: func(c *models.DownloadConfig) {
+				c.Network = models.NetworkConfig{Proxy: "socks5://127.0.0.1:1080", SourceAddress: "10.0.0.2", IPVersion: 6}
+			},
+			want: [][]string{{"--proxy", "socks5://127.0.0.1:1080"}, {"--source-address", "10.0.0.2"}, {"--force-ipv6"}},
+		},
+		{
+			name: "chapters",
+			modify: func(c *models.DownloadConfig) {
+				c.Chapters = []string{"Intro", "Q&A (live)"}
+				c.TrimStart = "00:01:00" // Chapters win
+			},
+			want: [][]string{
+				{"-o", filepath.Join("/out", "%(title)s - %(section_number)02d %(section_title)s.%(ext)s")},
+				{"--download-sections", "^Intro$", "--download-sections", `^Q&A \(live\)$`, "--force-keyframes-at-cuts"},
+			},
+			notWant: []string{"*00:01:00-inf", "--embed-chapters"},
+		},
+		{
+			name: "split chapters",
+			modify: func(c *models.DownloadConfig) {
+				c.SplitChapters = true
+				c.EmbedChapters = true
+				c.OutputTemplate = "%(uploader)s/%(title)s.%(ext)s"
+			},
+			want: [][]string{
+				{"--split-chapters", "-o", "chapter:" + filepath.Join("/out", "%(uploader)s", "%(title)s", "%(section_number)02d - %(section_title)s.%(ext)s")},
+				{"--embed-chapters"},
+			},
+		},
+		{
+			name: "ranges",
+			modify: func(c *models.DownloadConfig) {
+				c.Ranges = []models.TimeRange{{Start: 30, End: 90.5}, {Start: 600}}
+				c.TrimStart = "00:01:00" // Ranges win
+			},
+			want: [][]string{
+				{"-o", filepath.Join("/out", "%(title)s [%(section_start)ds].%(ext)s")},
+				{"--download-sections", "*30-90.5", "--download-sections", "*600-inf", "--force-keyframes-at-cuts"},
+			},
+			notWant: []string{"*00:01:00-inf"},
+		},
+		{
+			name: "sponsorblock categories",
+			modify: func(c *models.DownloadConfig) {
+				c.UseSponsorBlock = true
+				c.SponsorBlock = map[string]string{"intro": "mark", "sponsor": "remove", "filler": "ignore", "selfpromo": "remove"}
+			},
+			want:    [][]string{{"--sponsorblock-remove", "sponsor,selfpromo"}, {"--sponsorblock-mark", "intro"}},
+			notWant: []string{"all", "filler"},
+		},
+		{
+			name:   "1080p",
+			modify: func(c *models.DownloadConfig) { c.Quality = "1080p" },
+			want:   [][]string{{"-f", "bestvideo[height<=1080]+bestaudio/best"}},
+		},
+		{
+			name:    "audio mp3",
+			modify:  func(c *models.DownloadConfig) { c.DownloadMode = "Audio"; c.Quality = "mp3" },
+			want:    [][]string{{"-x"}, {"--audio-format", "mp3", "--audio-quality", "0"}},
+			notWant: []string{"--merge-output-format"},
+		},
+		{
+			name:    "playlist selection",
+			modify:  func(c *models.DownloadConfig) { c.IsPlaylist = true; c.PlaylistItems = "1,3,5" },
+			want:    [][]string{{"--yes-playlist"}, {"--playlist-items", "1,3,5"}},
+			notWant: []string{"--no-playlist"},
+		},
+		{
+			name:   "german subtitles",
+			modify: func(c *models.DownloadConfig) { c.EmbedSubs = true; c.AutoSubs = true; c.SubLanguage = "de" },
+			want:   [][]string{{"--embed-subs"}, {"--write-auto-subs"}, {"--sub-langs", "de.*,en.*"}},
+		},
+		{
+			name: "picked subtitles saved as files",
+			modify: func(c *models.DownloadConfig) {
+				c.WriteSubs = true
+				c.SubFormat = "ass"
+				c.SubLanguages = []string{"en", "de-DE", "zh-Hans"}
+				c.SubLanguage = "de" // Picked languages win
+			},
+			want:    [][]string{{"--write-subs", "--convert-subs", "ass"}, {"--sub-langs", "en,de-DE,zh-Hans"}},
+			notWant: []string{"--embed-subs", "--write-auto-subs"},
+		},
+		{
+			name: "subtitles embedded in webm and saved",
+			modify: func(c *models.DownloadConfig) {
+				c.Prefs.Container = "webm"
+				c.EmbedSubs = true
+				c.WriteSubs = true
+				c.SubFormat = "srt"
+			},
+			want: [][]string{{"--embed-subs", "--write-subs", "--convert-subs", "vtt"}, {"--sub-langs", "en.*"}},
+		},
+		{
+			name:   "open ended trim",
+			modify: func(c *models.DownloadConfig) { c.TrimStart = "00:01:00" },
+			want:   [][]string{{"--download-sections", "*00:01:00-inf", "--force-keyframes-at-cuts"}},
+		},
+		{
+			name: "client, cookies and sponsorblock",
+			modify: func(c *models.DownloadConfig) {
+				c.Client = "Android"
+				c.CookiesPath = "/c.txt"
+				c.UseSponsorBlock = true
+			},
+			want: [][]string{
+				{"--extractor-args", "youtube:player_client=ANDROID"},
+				{"--cookies", "/c.txt"},
+				{"--sponsorblock-remove", "all"},
+			},
+		},
+	}
+
+	y := NewYtDlp("yt-dlp")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := base
+			if tt.modify != nil {
+				tt.modify(&config)
+			}
+			args := y.buildArgs(config)
+			for _, want := range tt.want {
+				if !containsSeq(args, want...) {
+					t.Errorf("args missing %q\nargs: %q", want, args)
+				}
+			}
+			for _, flag := range tt.notWant {
+				if slices.Contains(args, flag) {
+					t.Errorf("args unexpectedly contain %q\nargs: %q", flag, args)
+				}
+			}
+		})
+	}
+}
+
+func TestYtDlpGetMetadataSubtitles(t *testing.T) {
+	e, _ := newFakeEngine(t, fakeRun{Stdout: []string{
+		`{"id":"v","title":"Talk","subtitles":{"live_chat":[{"ext":"json"}],"en":[{"ext":"vtt","name":"English"}],"de-DE":[{"ext":"vtt","name":"German"}]},` +
+			`"automatic_captions":{"en":[{"ext":"vtt","name":"English"}],"fr":[{"ext":"srv3"},{"ext":"vtt","name":"French"}]}}`,
+	}})
+	meta, err := e.GetMetadata(context.Background(), "https://youtube.com/watch?v=v")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []models.SubtitleTrack{
+		{Language: "de-DE", Name: "German"},
+		{Language: "en", Name: "English"},
+		{Language: "fr", Name: "French", Auto: true},
+	}
+	if got := meta.SubtitleTracks(); !slices.Equal(got, want) {
+		t.Errorf("SubtitleTracks() = %+v, want %+v", got, want)
+	}
+}
+
+func TestYtDlpGetMetadataPlaylist(t *testing.T) {
+	e, calls := newFakeEngine(t, fakeRun{Stdout: []string{
+		`{"id":"PL1","title":"Mix","_type":"playlist","playlist_count":2,"entries":[{"id":"a","title":"First"},{"id":"b","title":"Second"}]}`,
+	}})
+	meta, err := e.GetMetadata(context.Background(), "https://youtube.com/playlist?list=PL1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta.Type != "playlist" || meta.EntryCount != 2 || len(meta.Entries) != 2 || meta.Entries[1].Title != "Second" {
+		t.Errorf("unexpected metadata: %+v", meta)
+	}
+	if args := calls()[0]; !containsSeq(args, "--dump-single-json", "--flat-playlist") {
+		t.Errorf("unexpected args %q", args)
+	}
+
+	// A job's own network replaces the global one
+	ctx := WithNetwork(context.Background(), models.NetworkConfig{Proxy: "socks5://127.0.0.1:1080"})
+	if _, err := e.GetMetadata(ctx, "https://youtube.com/playlist?list=PL1"); err != nil {
+		t.Fatal(err)
+	}
+	if args := calls()[1]; !containsSeq(args, "--proxy", "socks5://127.0.0.1:1080") {
+		t.Errorf("job proxy missing from %q", args)
+	}
+}
+
+func TestYtDlpGetMetadataError(t *testing.T) {
+	e, _ := newFakeEngine(t, fakeRun{
+		Stderr: []string{"ERROR: [youtube] x: Private video. Sign in if you've been granted access to this video"},
+		Exit:   1,
+	})
+	_, err := e.GetMetadata(context.Background(), "https://youtu.be/x")
+	var dlErr *Error
+	if !errors.As(err, &dlErr) || dlErr.Kind != KindUnavailable {
+		t.Fatalf("got %v, want an unavailable error", err)
+	}
+	if dlErr.Message != "[youtube] x: Private video. Sign in if you've been granted access to this video" {
+		t.Errorf("unexpected message %q", dlErr.Message)
+	}
+}
+
+func TestYtDlpDownloadPlaylistProgress(t *testing.T) {
+	e, calls := newFakeEngine(t, fakeRun{Stdout: []string{
+		"[download] Destination: /out/First.mp4",
+		progressLine("downloading", "50", "100", "NA", "10", "5", "NA", "NA", "1", "2"),
+		progressLine("finished", "100", "100", "NA", "NA", "NA", "NA", "NA", "1", "2"),
+		postProcessPrefix + "started|Merger|1|2",
+		"[download] Destination: /out/Second.mp4",
+		"[download]  40.0% of 10MiB",
+		progressLine("finished", "100", "100", "NA", "NA", "NA", "NA", "NA", "2", "2"),
+	}, Stderr: []string{"WARNING: something odd"}})
+
+	var updates []models.ProgressUpdate
+	config := models.DownloadConfig{URL: "https://youtube.com/playlist?list=PL1", OutputPath: "/out", IsPlaylist: true}
+	if err := e.Download(context.Background(), config, func(u models.ProgressUpdate) { updates = append(updates, u) }); err != nil {
+		t.Fatal(err)
+	}
+	if args := calls()[0]; !slices.Contains(args, "--yes-playlist") {
+		t.Errorf("playlist flag missing from %q", args)
+	}
+
+	var stages []string
+	var warned bool
+	for _, u := range updates {
+		if u.Stage == "" {
+			warned = warned || u.Severity == models.SeverityWarning
+			continue
+		}
+		stages = append(stages, u.Stage)
+	}
+	if !warned {
+		t.Error("stderr warning not reported")
+	}
+	want := []string{"Downloading", "Downloading", "Downloading", "Processing", "Downloading", "Downloading", "Downloading"}
+	if !slices.Equal(stages, want) {
+		t.Errorf("stages = %q, want %q", stages, want)
+	}
+
+	var first, fallback, last models.ProgressUpdate
+	for _, u := range updates {
+		switch {
+		case u.DownloadedBytes == 50:
+			first = u
+		case u.Text == "[download]  40.0% of 10MiB":
+			fallback = u
+		case u.PlaylistIndex == 2:
+			last = u
+		}
+	}
+	if first.Percent != 0.5 || first.Speed != 10 || first.ETA != 5 || first.PlaylistCount != 2 {
+		t.Errorf("unexpected progress %+v", first)
+	}
+	if fallback.Percent != 0.4 {
+		t.Errorf("fallback percent = %v, want 0.4", fallback.Percent)
+	}
+	if last.Percent != 1 {
+		t.Errorf("last percent = %v, want 1", last.Percent)
+	}
+}
+
+func TestNetworkEnvCABundle(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	srv.Close()
+	custom := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	dir := t.TempDir()
+	path := filepath.Join(dir, "ca.pem")
+	if err := os.WriteFile(path, custom, 0644); err != nil {
+		t.Fatal(err)
+	}
+	system := filepath.Join(dir, "system.pem")
+	if err := os.WriteFile(system, []byte("system roots\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("SSL_CERT_FILE", system)
+
+	env, cleanup, err := networkEnv(models.NetworkConfig{CABundle: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var bundle string
+	for _, kv := range env {
+		if v, ok := strings.CutPrefix(kv, "SSL_CERT_FILE="); ok {
+			bundle = v // The last one wins, as in the child
+		}
+	}
+	data, err := os.ReadFile(bundle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// yt-dlp must keep trusting the system roots, like the Go side does
+	if !bytes.HasPrefix(data, []byte("system roots\n")) || !bytes.Contains(data, custom) {
+		t.Errorf("bundle = %q, want the system roots and the custom CA", data)
+	}
+	cleanup()
+	if _, err := os.Stat(bundle); !os.IsNotExist(err) {
+		t.Error("bundle copy left behind")
+	}
+
+	os.WriteFile(path, []byte("not a certificate"), 0644)
+	if _, _, err := networkEnv(models.NetworkConfig{CABundle: path}); !errors.Is(err, utils.ErrBadCABundle) {
+		t.Errorf("bad bundle error = %v, want ErrBadCABundle", err)
+	}
+}
@@ -0,0 +1,81 @@
+package gui
+
+import (
+	"fmt"
+	"gotube/internal/locales"
+	"gotube/internal/models"
+	"gotube/internal/utils"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// showChapterPicker lets the user tick the chapters to download, each as its
+// own file. onSelect receives the picked indices, or nil for the whole video
+// when none or all are ticked.
+func showChapterPicker(ctx *AppContext, chapters []models.Chapter, current []int, onSelect func([]int)) {
+	selected := make([]bool, len(chapters))
+	for _, i := range current {
+		selected[i] = true
+	}
+	labelCount := widget.NewLabel("")
+	updateCount := func() {
+		count := 0
+		for _, s := range selected {
+			if s {
+				count++
+			}
+		}
+		labelCount.SetText(fmt.Sprintf(locales.Get("pl_selected"), count))
+	}
+	updateCount()
+
+	list := widget.NewList(
+		func() int { return len(chapters) },
+		func() fyne.CanvasObject { return widget.NewCheck("Chapter", nil) },
+		func(i int, o fyne.CanvasObject) {
+			c := chapters[i]
+			check := o.(*widget.Check)
+			check.Text = fmt.Sprintf("%d. %s (%s – %s)", i+1, c.Title, utils.FormatETA(int(c.Start)), utils.FormatETA(int(c.End)))
+			check.Checked = selected[i]
+			check.OnChanged = func(b bool) {
+				selected[i] = b
+				updateCount()
+			}
+			check.Refresh()
+		},
+	)
+	setAll := func(b bool) {
+		for i := range selected {
+			selected[i] = b
+		}
+		list.Refresh()
+		updateCount()
+	}
+	btnAll := widget.NewButton(locales.Get("pl_select_all"), func() { setAll(true) })
+	btnNone := widget.NewButton(locales.Get("pl_select_none"), func() { setAll(false) })
+
+	hint := widget.NewLabel(locales.Get("chapters_hint"))
+	hint.Wrapping = fyne.TextWrapWord
+	content := container.NewBorder(container.NewVBox(hint, labelCount, container.NewHBox(btnAll, btnNone)), nil, nil, nil, list)
+
+	d := dialog.NewCustomConfirm(locales.Get("chapters_title"), locales.Get("pl_confirm"), locales.Get("btn_cancel"), content, func(ok bool) {
+		if !ok {
+			return
+		}
+		var picked []int
+		for i, s := range selected {
+			if s {
+				picked = append(picked, i)
+			}
+		}
+		if len(picked) == len(chapters) {
+			picked = nil
+		}
+		onSelect(picked)
+	}, ctx.Win)
+	d.Resize(fyne.NewSize(450, 600))
+	d.Show()
+}
@@ -1,11 +1,22 @@
 package gui
 
 import (
+	"errors"
 	"fmt"
+	"gotube/internal/downloader"
 	"gotube/internal/locales"
+	"gotube/internal/models"
+	"gotube/internal/queue"
+	"gotube/internal/utils"
+	"maps"
+	"net"
+	"strings"
+	"time"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
 )
@@ -20,7 +31,7 @@ func createFormatSelectors() (*widget.Select, *widget.Select) {
 
 	formatSelect.OnChanged = func(s string) {
 		if s == locales.Get("format_audio") {
-			detailSelect.Options = []string{"Best", "mp3", "m4a"}
+			detailSelect.Options = []string{"Best", "mp3", "m4a", "opus", "flac", "wav", "ogg"}
 			detailSelect.Selected = "mp3"
 		} else {
 			detailSelect.Options = []string{"Best", "4k", "1080p", "720p"}
@@ -31,6 +42,248 @@ func createFormatSelectors() (*widget.Select, *widget.Select) {
 	return formatSelect, detailSelect
 }
 
+// outputExt guesses the extension a download will end up with, for previews
+func outputExt(audio bool, quality string, prefs models.FormatPrefs) string {
+	if audio {
+		switch quality {
+		case "mp3", "m4a", "opus", "flac", "wav", "ogg":
+			return quality
+		}
+		return "m4a"
+	}
+	if prefs.Container != "" {
+		return prefs.Container
+	}
+	return "mp4"
+}
+
+// newStartAtEntry returns the entry for scheduling jobs; empty starts now
+func newStartAtEntry() *widget.Entry {
+	e := widget.NewEntry()
+	e.SetPlaceHolder("23:30")
+	return e
+}
+
+// parseStartAt reads a newStartAtEntry, with a localized error
+func parseStartAt(e *widget.Entry) (time.Time, error) {
+	at, err := queue.ParseStartTime(e.Text, time.Now())
+	if err != nil {
+		return time.Time{}, errors.New(locales.Get("schedule_err"))
+	}
+	return at, nil
+}
+
+// newRateEntry returns the entry for a job's own speed limit; empty is
+// unlimited
+func newRateEntry() *widget.Entry {
+	e := widget.NewEntry()
+	e.SetPlaceHolder("500K")
+	return e
+}
+
+// parseRate reads a newRateEntry, with a localized error
+func parseRate(e *widget.Entry) (int64, error) {
+	rate, err := downloader.ParseRate(e.Text)
+	if err != nil {
+		return 0, errors.New(locales.Get("rate_err"))
+	}
+	return rate, nil
+}
+
+// networkOptions are a job's own network settings in the Download and
+// Batch tabs; empty fields use the Network settings
+type networkOptions struct {
+	proxy, source, caBundle                         *widget.Entry
+	ipVersion                                       *widget.Select
+	caBtn                                           *widget.Button
+	labelProxy, labelSource, labelIP, labelCABundle *widget.Label
+}
+
+func newNetworkOptions(win fyne.Window) *networkOptions {
+	o := &networkOptions{
+		proxy:         widget.NewEntry(),
+		source:        widget.NewEntry(),
+		caBundle:      widget.NewEntry(),
+		labelProxy:    widget.NewLabel(""),
+		labelSource:   widget.NewLabel(""),
+		labelIP:       widget.NewLabel(""),
+		labelCABundle: widget.NewLabel(""),
+	}
+	o.proxy.SetPlaceHolder("socks5://127.0.0.1:1080")
+	o.source.SetPlaceHolder("192.168.1.10")
+	o.caBundle.SetPlaceHolder("/etc/ssl/corp-ca.pem")
+	// Index 0 keeps the global IP version
+	o.ipVersion = widget.NewSelect([]string{"", "IPv4", "IPv6"}, nil)
+	o.caBtn = widget.NewButtonWithIcon("", theme.FolderOpenIcon(), func() {
+		dialog.ShowFileOpen(func(r fyne.URIReadCloser, err error) {
+			if r != nil {
+				r.Close()
+				o.caBundle.SetText(r.URI().Path())
+			}
+		}, win)
+	})
+	return o
+}
+
+// config reads the job's network settings, with a localized error
+func (o *networkOptions) config() (models.NetworkConfig, error) {
+	n := models.NetworkConfig{
+		Proxy:         strings.TrimSpace(o.proxy.Text),
+		SourceAddress: strings.TrimSpace(o.source.Text),
+		CABundle:      strings.TrimSpace(o.caBundle.Text),
+	}
+	switch o.ipVersion.SelectedIndex() {
+	case 1:
+		n.IPVersion = 4
+	case 2:
+		n.IPVersion = 6
+	}
+	if n.Proxy != "" {
+		if _, err := utils.ParseProxy(n.Proxy); err != nil {
+			return models.NetworkConfig{}, errors.New(locales.Get("net_err_proxy"))
+		}
+	}
+	if n.SourceAddress != "" && net.ParseIP(n.SourceAddress) == nil {
+		return models.NetworkConfig{}, errors.New(locales.Get("net_err_source"))
+	}
+	if n.CABundle != "" && utils.ValidateNetwork(models.NetworkConfig{CABundle: n.CABundle}) != nil {
+		return models.NetworkConfig{}, errors.New(locales.Get("net_err_ca"))
+	}
+	return n, nil
+}
+
+func (o *networkOptions) rows() []fyne.CanvasObject {
+	return []fyne.CanvasObject{
+		container.NewGridWithColumns(2, o.labelProxy, o.proxy),
+		container.NewGridWithColumns(2, o.labelSource, o.source),
+		container.NewGridWithColumns(2, o.labelIP, o.ipVersion),
+		container.NewGridWithColumns(2, o.labelCABundle, container.NewBorder(nil, nil, nil, o.caBtn, o.caBundle)),
+	}
+}
+
+func (o *networkOptions) updateText() {
+	o.labelProxy.SetText(locales.Get("net_job_proxy"))
+	o.labelSource.SetText(locales.Get("net_job_source"))
+	o.labelIP.SetText(locales.Get("net_job_ip_version"))
+	o.labelCABundle.SetText(locales.Get("net_job_ca_bundle"))
+	o.ipVersion.Options[0] = locales.Get("net_ip_default")
+	if o.ipVersion.SelectedIndex() <= 0 {
+		o.ipVersion.Selected = o.ipVersion.Options[0]
+	}
+	o.ipVersion.Refresh()
+}
+
+// newRangesEntry returns the entry for a job's time ranges
+func newRangesEntry() *widget.Entry {
+	e := widget.NewEntry()
+	e.SetPlaceHolder("0:30-1:00, 50%-")
+	return e
+}
+
+// parseRanges reads a newRangesEntry, checking the ranges against the
+// video's duration (0 if unknown), with a localized error
+func parseRanges(e *widget.Entry, duration int) ([]models.TimeRange, error) {
+	ranges, err := downloader.ParseRanges(e.Text, duration)
+	var rangeErr *downloader.RangeError
+	if !errors.As(err, &rangeErr) {
+		return ranges, err
+	}
+	switch {
+	case errors.Is(err, downloader.ErrRangeOrder):
+		return nil, fmt.Errorf(locales.Get("trim_err_order"), rangeErr.Range)
+	case errors.Is(err, downloader.ErrRangeTooLong):
+		return nil, fmt.Errorf(locales.Get("trim_err_long"), rangeErr.Range, utils.FormatETA(duration))
+	case errors.Is(err, downloader.ErrNeedsDuration):
+		return nil, fmt.Errorf(locales.Get("trim_err_percent"), rangeErr.Range)
+	}
+	return nil, fmt.Errorf(locales.Get("trim_err_format"), rangeErr.Range)
+}
+
+// subtitleOptions are the subtitle settings of the Download and Batch tabs:
+// embed and/or save as files, which format the files get, and a language
+// for when no tracks were picked
+type subtitleOptions struct {
+	embed, auto, write     *widget.Check
+	lang, format           *widget.Select
+	labelLang, labelFormat *widget.Label
+}
+
+func newSubtitleOptions() *subtitleOptions {
+	o := &subtitleOptions{
+		auto:        widget.NewCheck("", nil),
+		lang:        widget.NewSelect([]string{"en", "de", "all"}, nil),
+		format:      widget.NewSelect(downloader.SubFormats, nil),
+		labelLang:   widget.NewLabel(""),
+		labelFormat: widget.NewLabel(""),
+	}
+	o.lang.Selected = "en"
+	o.format.Selected = downloader.SubFormats[0]
+	o.format.Disable()
+	o.auto.Disable()
+	toggle := func(bool) {
+		if o.embed.Checked || o.write.Checked {
+			o.auto.Enable()
+		} else {
+			o.auto.Disable()
+		}
+		if o.write.Checked {
+			o.format.Enable()
+		} else {
+			o.format.Disable()
+		}
+	}
+	o.embed = widget.NewCheck("", toggle)
+	o.write = widget.NewCheck("", toggle)
+	return o
+}
+
+// wanted reports whether the job should get subtitles at all
+func (o *subtitleOptions) wanted() bool {
+	return o.embed.Checked || o.write.Checked
+}
+
+func (o *subtitleOptions) apply(c *models.DownloadConfig) {
+	c.EmbedSubs = o.embed.Checked
+	c.WriteSubs = o.write.Checked
+	c.AutoSubs = o.auto.Checked && o.wanted()
+	c.SubLanguage = o.lang.Selected
+	c.SubFormat = o.format.Selected
+}
+
+func (o *subtitleOptions) rows() []fyne.CanvasObject {
+	return []fyne.CanvasObject{
+		container.NewGridWithColumns(2, o.labelLang, o.lang),
+		container.NewGridWithColumns(2, o.embed, o.auto),
+		container.NewGridWithColumns(2, o.write, container.NewBorder(nil, nil, o.labelFormat, nil, o.format)),
+	}
+}
+
+func (o *subtitleOptions) updateText() {
+	o.embed.SetText(locales.Get("subs_embed"))
+	o.auto.SetText(locales.Get("subs_auto"))
+	o.write.SetText(locales.Get("subs_write"))
+	o.labelLang.SetText(locales.Get("subs_lang"))
+	o.labelFormat.SetText(locales.Get("subs_format"))
+}
+
+// sponsorBlock returns the saved SponsorBlock actions for a job with the
+// SponsorBlock box ticked
+func sponsorBlock(ctx *AppContext, ticked bool) map[string]string {
+	if !ticked {
+		return nil
+	}
+	return maps.Clone(ctx.Settings.SponsorBlock)
+}
+
+// formatStartAt shows the time alone for today, and the date otherwise
+func formatStartAt(at time.Time) string {
+	now := time.Now()
+	if at.Year() == now.Year() && at.YearDay() == now.YearDay() {
+		return at.Format("15:04")
+	}
+	return at.Format("2006-01-02 15:04")
+}
+
 // createPreviewImage returns a standard configured image canvas
 func createPreviewImage() *canvas.Image {
 	img := canvas.NewImageFromResource(theme.FileImageIcon())
@@ -53,3 +306,35 @@ func formatDuration(seconds int) string {
 	minutes = minutes % 60
 	return fmt.Sprintf("%dh %02dm %02ds", hours, minutes, seconds)
 }
+
+// formatProgressDetail renders the footer line, e.g. "12.3 MiB/s • ETA 0:42 • item 3/17"
+func formatProgressDetail(u models.ProgressUpdate) string {
+	var parts []string
+	if u.PostProcessor != "" {
+		parts = append(parts, u.PostProcessor)
+	}
+	if u.Speed > 0 {
+		parts = append(parts, utils.FormatBytes(int64(u.Speed))+"/s")
+	}
+	if u.ETA > 0 {
+		parts = append(parts, "ETA "+utils.FormatETA(u.ETA))
+	}
+	if u.TotalBytes > 0 {
+		parts = append(parts, fmt.Sprintf("%s / %s", utils.FormatBytes(u.DownloadedBytes), utils.FormatBytes(u.TotalBytes)))
+	} else if u.FragmentCount > 0 {
+		parts = append(parts, fmt.Sprintf("frag %d/%d", u.FragmentIndex, u.FragmentCount))
+	}
+	if u.PlaylistCount > 1 {
+		parts = append(parts, fmt.Sprintf("item %d/%d", u.PlaylistIndex, u.PlaylistCount))
+	}
+	return strings.Join(parts, " • ")
+}
+
+// errorHint returns a localized, actionable explanation for a classified
+// failure, or "" when yt-dlp's own message is all we have.
+func errorHint(kind downloader.ErrorKind) string {
+	if kind == downloader.KindUnknown {
+		return ""
+	}
+	return locales.Get("err_" + kind.String())
+}
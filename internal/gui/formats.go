@@ -0,0 +1,170 @@
+package gui
+
+import (
+	"fmt"
+	"gotube/internal/downloader"
+	"gotube/internal/locales"
+	"gotube/internal/models"
+	"gotube/internal/utils"
+	"sort"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// showFormatPicker lets the user pick an exact video+audio pair from
+// meta.Formats or type their own yt-dlp selector. onSelect receives "" to
+// go back to the Quality preset.
+func showFormatPicker(ctx *AppContext, meta *models.VideoMetadata, current string, onSelect func(string)) {
+	var videos, audios []models.Format
+	for _, f := range meta.Formats {
+		switch {
+		case f.HasVideo():
+			videos = append(videos, f)
+		case f.HasAudio():
+			audios = append(audios, f)
+		}
+	}
+	// Best first
+	sort.SliceStable(videos, func(i, j int) bool {
+		if videos[i].Height != videos[j].Height {
+			return videos[i].Height > videos[j].Height
+		}
+		return videos[i].TBR > videos[j].TBR
+	})
+	sort.SliceStable(audios, func(i, j int) bool { return audios[i].ABR > audios[j].ABR })
+
+	none := locales.Get("formats_none")
+	options := func(formats []models.Format) []string {
+		opts := []string{none}
+		for _, f := range formats {
+			opts = append(opts, formatLabel(f, meta.Duration))
+		}
+		return opts
+	}
+	videoSelect := widget.NewSelect(options(videos), nil)
+	audioSelect := widget.NewSelect(options(audios), nil)
+	customEntry := widget.NewEntry()
+	customEntry.SetPlaceHolder("bv*[height<=1440]+ba/b")
+	sizeLabel := widget.NewLabel("")
+
+	// selector builds the -f value from the current choices
+	selector := func() string {
+		if s := strings.TrimSpace(customEntry.Text); s != "" {
+			return s
+		}
+		var ids []string
+		if i := videoSelect.SelectedIndex(); i > 0 {
+			ids = append(ids, videos[i-1].ID)
+		}
+		if i := audioSelect.SelectedIndex(); i > 0 {
+			ids = append(ids, audios[i-1].ID)
+		}
+		return strings.Join(ids, "+")
+	}
+	refreshSize := func() {
+		if size, ok := downloader.EstimateSize(meta, selector()); ok {
+			sizeLabel.SetText(fmt.Sprintf(locales.Get("formats_size"), utils.FormatBytes(size)))
+		} else {
+			sizeLabel.SetText(locales.Get("formats_size_unknown"))
+		}
+	}
+	videoSelect.OnChanged = func(string) { refreshSize() }
+	audioSelect.OnChanged = func(string) { refreshSize() }
+	customEntry.OnChanged = func(string) { refreshSize() }
+
+	// Restore the previous choice
+	videoSelect.SetSelectedIndex(0)
+	audioSelect.SetSelectedIndex(0)
+	if current != "" {
+		matched := 0
+		for _, id := range strings.Split(current, "+") {
+			if i := formatIndex(videos, id); i >= 0 {
+				videoSelect.SetSelectedIndex(i + 1)
+				matched++
+			} else if i := formatIndex(audios, id); i >= 0 {
+				audioSelect.SetSelectedIndex(i + 1)
+				matched++
+			}
+		}
+		if matched != len(strings.Split(current, "+")) {
+			customEntry.SetText(current)
+		}
+	}
+	refreshSize()
+
+	form := container.NewVBox(
+		widget.NewLabel(locales.Get("formats_video")),
+		videoSelect,
+		widget.NewLabel(locales.Get("formats_audio")),
+		audioSelect,
+		widget.NewLabel(locales.Get("formats_custom")),
+		customEntry,
+		widget.NewSeparator(),
+		sizeLabel,
+	)
+
+	d := dialog.NewCustomConfirm(locales.Get("formats_title"), locales.Get("pl_confirm"), locales.Get("btn_cancel"), form, func(ok bool) {
+		if ok {
+			onSelect(selector())
+		}
+	}, ctx.Win)
+	d.Resize(fyne.NewSize(480, 420))
+	d.Show()
+}
+
+// formatLabel describes a format, e.g. "137 • 1080p60 HDR • mp4 • avc1 • 4400k • 120.3 MiB"
+func formatLabel(f models.Format, duration int) string {
+	parts := []string{f.ID}
+	if f.HasVideo() {
+		res := f.Resolution
+		if f.Height > 0 {
+			res = fmt.Sprintf("%dp", f.Height)
+			if f.FPS > 30 {
+				res += fmt.Sprintf("%.0f", f.FPS)
+			}
+		}
+		if f.IsHDR() {
+			res += " " + f.DynamicRange
+		}
+		parts = append(parts, res)
+	} else if f.Note != "" {
+		parts = append(parts, f.Note)
+	}
+	parts = append(parts, f.Ext)
+	if f.HasVideo() {
+		parts = append(parts, codecName(f.VCodec))
+	}
+	if f.HasAudio() {
+		parts = append(parts, codecName(f.ACodec))
+	}
+	if f.TBR > 0 {
+		parts = append(parts, fmt.Sprintf("%.0fk", f.TBR))
+	}
+	if size := f.Size(duration); size > 0 {
+		prefix := ""
+		if f.FileSize == 0 {
+			prefix = "≈"
+		}
+		parts = append(parts, prefix+utils.FormatBytes(size))
+	}
+	return strings.Join(parts, " • ")
+}
+
+// codecName shortens codec strings like "avc1.640028" to "avc1"
+func codecName(codec string) string {
+	name, _, _ := strings.Cut(codec, ".")
+	return name
+}
+
+func formatIndex(formats []models.Format, id string) int {
+	for i, f := range formats {
+		if f.ID == id {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,474 @@
+package gui
+
+import (
+	"errors"
+	"gotube/internal/downloader"
+	"gotube/internal/locales"
+	"gotube/internal/models"
+	"gotube/internal/queue"
+	"gotube/internal/utils"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// Sections of the System tab. Each returns its content and a func that
+// re-applies the localized texts.
+
+// numberField is a labelled entry that saves itself once the input is valid
+type numberField struct {
+	labelKey string
+	label    *widget.Label
+	entry    *widget.Entry
+}
+
+func newNumberField(labelKey, value string, min, max float64, apply func(float64, string)) *numberField {
+	f := &numberField{labelKey: labelKey, label: widget.NewLabel(""), entry: widget.NewEntry()}
+	f.entry.SetText(value)
+	f.entry.Validator = func(s string) error {
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil || v < min || v > max {
+			return errors.New(locales.Get("invalid_number"))
+		}
+		return nil
+	}
+	f.entry.OnChanged = func(s string) {
+		if f.entry.Validator(s) == nil {
+			v, _ := strconv.ParseFloat(s, 64)
+			apply(v, s)
+		}
+	}
+	return f
+}
+
+// newOptionalNumberField is a numberField that may be left empty, which
+// applies 0
+func newOptionalNumberField(labelKey, value string, min, max float64, apply func(float64, string)) *numberField {
+	f := newNumberField(labelKey, value, min, max, apply)
+	valid := f.entry.Validator
+	f.entry.Validator = func(s string) error {
+		if s == "" {
+			return nil
+		}
+		return valid(s)
+	}
+	return f
+}
+
+func (f *numberField) row() fyne.CanvasObject {
+	return container.NewGridWithColumns(2, f.label, f.entry)
+}
+
+func (f *numberField) updateText() {
+	f.label.SetText(locales.Get(f.labelKey))
+}
+
+// textField is a labelled entry that saves itself once the input is empty
+// or valid
+type textField struct {
+	labelKey string
+	label    *widget.Label
+	entry    *widget.Entry
+}
+
+func newTextField(labelKey, value, placeholder string, valid func(string) bool, errKey string, apply func(string)) *textField {
+	f := &textField{labelKey: labelKey, label: widget.NewLabel(""), entry: widget.NewEntry()}
+	f.entry.SetText(value)
+	f.entry.SetPlaceHolder(placeholder)
+	f.entry.Validator = func(s string) error {
+		if s = strings.TrimSpace(s); s != "" && !valid(s) {
+			return errors.New(locales.Get(errKey))
+		}
+		return nil
+	}
+	f.entry.OnChanged = func(s string) {
+		if f.entry.Validator(s) == nil {
+			apply(strings.TrimSpace(s))
+		}
+	}
+	return f
+}
+
+func (f *textField) row() fyne.CanvasObject {
+	return container.NewGridWithColumns(2, f.label, f.entry)
+}
+
+func (f *textField) updateText() {
+	f.label.SetText(locales.Get(f.labelKey))
+}
+
+// choiceField is a labelled select over fixed values. The first option is
+// "Auto", stored as "".
+type choiceField struct {
+	labelKey string
+	label    *widget.Label
+	sel      *widget.Select
+}
+
+func newChoiceField(labelKey string, values, names []string, current string, apply func(string)) *choiceField {
+	f := &choiceField{labelKey: labelKey, label: widget.NewLabel("")}
+	f.sel = widget.NewSelect(append([]string{""}, names...), nil)
+	f.sel.SetSelectedIndex(0)
+	for i, v := range values {
+		if v == current {
+			f.sel.SetSelectedIndex(i + 1)
+		}
+	}
+	f.sel.OnChanged = func(string) {
+		if i := f.sel.SelectedIndex(); i > 0 {
+			apply(values[i-1])
+		} else {
+			apply("")
+		}
+	}
+	return f
+}
+
+func (f *choiceField) row() fyne.CanvasObject {
+	return container.NewGridWithColumns(2, f.label, f.sel)
+}
+
+func (f *choiceField) updateText() {
+	f.label.SetText(locales.Get(f.labelKey))
+	f.sel.Options[0] = locales.Get("prefs_auto")
+	if f.sel.SelectedIndex() <= 0 {
+		f.sel.Selected = f.sel.Options[0]
+	}
+	f.sel.Refresh()
+}
+
+// buildFormatSettings edits the container and codec preferences used for new downloads
+func buildFormatSettings(ctx *AppContext) (fyne.CanvasObject, func()) {
+	p := &ctx.Settings.FormatPrefs
+	save := func(key string, target *string) func(string) {
+		return func(v string) {
+			*target = v
+			ctx.DB.SaveSetting(key, v)
+		}
+	}
+	fields := []*choiceField{
+		newChoiceField("prefs_container", []string{"mp4", "mkv", "webm"}, []string{"MP4", "MKV", "WebM"}, p.Container, save("Container", &p.Container)),
+		newChoiceField("prefs_vcodec", []string{"avc1", "vp9", "av01"}, []string{"H.264", "VP9", "AV1"}, p.VideoCodec, save("VideoCodec", &p.VideoCodec)),
+		newChoiceField("prefs_acodec", []string{"aac", "opus"}, []string{"AAC", "Opus"}, p.AudioCodec, save("AudioCodec", &p.AudioCodec)),
+		newChoiceField("prefs_dynamic_range", []string{"hdr", "sdr"}, []string{"HDR", "SDR"}, p.DynamicRange, save("DynamicRange", &p.DynamicRange)),
+		newChoiceField("prefs_max_fps", []string{"30", "60"}, []string{"30", "60"}, strconv.Itoa(p.MaxFPS), func(v string) {
+			p.MaxFPS, _ = strconv.Atoi(v)
+			ctx.DB.SaveSetting("MaxFPS", v)
+		}),
+	}
+
+	title := widget.NewLabelWithStyle("", fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
+	content := container.NewVBox(title)
+	for _, f := range fields {
+		content.Add(f.row())
+	}
+
+	updateText := func() {
+		title.SetText(locales.Get("prefs_title"))
+		for _, f := range fields {
+			f.updateText()
+		}
+	}
+	updateText()
+	return content, updateText
+}
+
+// buildRetrySettings edits the engine's retry policy
+func buildRetrySettings(ctx *AppContext) (fyne.CanvasObject, func()) {
+	p := downloader.RetryPolicyFromSettings(ctx.Settings)
+	seconds := func(d time.Duration) string { return strconv.Itoa(int(d.Seconds())) }
+
+	apply := func(key string) func(float64, string) {
+		return func(v float64, raw string) {
+			switch key {
+			case "RetryMaxAttempts":
+				ctx.Settings.RetryMaxAttempts = int(v)
+			case "RetryBaseDelay":
+				ctx.Settings.RetryBaseDelay = int(v)
+			case "RetryMaxDelay":
+				ctx.Settings.RetryMaxDelay = int(v)
+			case "RetryFactor":
+				ctx.Settings.RetryFactor = v
+			case "RetryJitter":
+				ctx.Settings.RetryJitter = v
+			}
+			ctx.DB.SaveSetting(key, raw)
+			ctx.Engine.SetRetryPolicy(downloader.RetryPolicyFromSettings(ctx.Settings))
+		}
+	}
+
+	fields := []*numberField{
+		newNumberField("retry_attempts", strconv.Itoa(p.MaxAttempts), 1, 20, apply("RetryMaxAttempts")),
+		newNumberField("retry_base_delay", seconds(p.BaseDelay), 1, 3600, apply("RetryBaseDelay")),
+		newNumberField("retry_max_delay", seconds(p.MaxDelay), 1, 86400, apply("RetryMaxDelay")),
+		newNumberField("retry_factor", strconv.FormatFloat(p.Factor, 'f', -1, 64), 1, 10, apply("RetryFactor")),
+		newNumberField("retry_jitter", strconv.FormatFloat(p.Jitter*100, 'f', -1, 64), 0, 100, apply("RetryJitter")),
+	}
+
+	// Per-kind overrides; empty fields keep the built-in override, shown as
+	// the placeholder, or the general settings above
+	applyOverride := func(kind, field string) func(float64, string) {
+		return func(v float64, raw string) {
+			if ctx.Settings.RetryOverrides == nil {
+				ctx.Settings.RetryOverrides = make(map[string]models.RetryOverride)
+			}
+			o := ctx.Settings.RetryOverrides[kind]
+			if field == "MaxAttempts" {
+				o.MaxAttempts = int(v)
+			} else {
+				o.BaseDelay = int(v)
+			}
+			ctx.Settings.RetryOverrides[kind] = o
+			ctx.DB.SaveSetting("Retry."+kind+"."+field, raw)
+			if kind == downloader.KindRateLimited.String() && field == "BaseDelay" {
+				ctx.DB.SaveSetting("RetryRateLimitDelay", "") // Replaced by this field
+			}
+			ctx.Engine.SetRetryPolicy(downloader.RetryPolicyFromSettings(ctx.Settings))
+		}
+	}
+	saved := func(n int) string {
+		if n <= 0 {
+			return ""
+		}
+		return strconv.Itoa(n)
+	}
+	builtIn := downloader.DefaultRetryPolicy().Overrides
+	var overrides []*numberField
+	var placeholders []string // Built-in values, "" to inherit
+	for _, kind := range downloader.RetryableKinds {
+		o := ctx.Settings.RetryOverrides[kind.String()]
+		overrides = append(overrides,
+			newOptionalNumberField("retry_"+kind.String()+"_attempts", saved(o.MaxAttempts), 1, 20, applyOverride(kind.String(), "MaxAttempts")),
+			newOptionalNumberField("retry_"+kind.String()+"_delay", saved(o.BaseDelay), 1, 3600, applyOverride(kind.String(), "BaseDelay")))
+		placeholders = append(placeholders, saved(builtIn[kind].MaxAttempts), saved(int(builtIn[kind].BaseDelay.Seconds())))
+	}
+
+	title := widget.NewLabelWithStyle("", fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
+	content := container.NewVBox(title)
+	for _, f := range append(fields, overrides...) {
+		content.Add(f.row())
+	}
+
+	updateText := func() {
+		title.SetText(locales.Get("retry_title"))
+		for _, f := range fields {
+			f.updateText()
+		}
+		for i, f := range overrides {
+			f.updateText()
+			if placeholders[i] != "" {
+				f.entry.SetPlaceHolder(placeholders[i])
+			} else {
+				f.entry.SetPlaceHolder(locales.Get("retry_inherit"))
+			}
+		}
+	}
+	updateText()
+	return content, updateText
+}
+
+// buildWindowSettings edits the global speed limit, the download windows and what the queue does
+// outside them
+func buildWindowSettings(ctx *AppContext) (fyne.CanvasObject, func()) {
+	apply := func() {
+		ctx.Queue.SetPolicy(queue.PolicyFromSettings(ctx.Settings))
+	}
+
+	limit := newNumberField("rate_global", strconv.Itoa(ctx.Settings.RateLimit), 0, 1<<20, func(v float64, raw string) {
+		ctx.Settings.RateLimit = int(v)
+		ctx.DB.SaveSetting("RateLimit", raw)
+		apply()
+	})
+
+	windowsLabel := widget.NewLabel("")
+	windowsEntry := widget.NewEntry()
+	windowsEntry.SetPlaceHolder("01:00-07:00, 13:00-14:00")
+	windowsEntry.SetText(strings.Join(ctx.Settings.DownloadWindows, ", "))
+	windowsEntry.Validator = func(s string) error {
+		if _, err := queue.ParseWindows(s); err != nil {
+			return errors.New(locales.Get("window_err"))
+		}
+		return nil
+	}
+	windowsEntry.OnChanged = func(s string) {
+		windows, err := queue.ParseWindows(s)
+		if err != nil {
+			return
+		}
+		ctx.Settings.DownloadWindows = nil
+		for _, w := range windows {
+			ctx.Settings.DownloadWindows = append(ctx.Settings.DownloadWindows, w.String())
+		}
+		ctx.DB.SaveListSetting("DownloadWindows", ctx.Settings.DownloadWindows)
+		apply()
+	}
+
+	modeLabel := widget.NewLabel("")
+	modeSelect := widget.NewSelect(modeOptions(), nil)
+	modes := []string{queue.OffPeakPause, queue.OffPeakThrottle}
+	modeSelect.SetSelectedIndex(0)
+	if ctx.Settings.OffPeakMode == queue.OffPeakThrottle {
+		modeSelect.SetSelectedIndex(1)
+	}
+	modeSelect.OnChanged = func(string) {
+		ctx.Settings.OffPeakMode = modes[max(modeSelect.SelectedIndex(), 0)]
+		ctx.DB.SaveSetting("OffPeakMode", ctx.Settings.OffPeakMode)
+		apply()
+	}
+
+	shownRate := ctx.Settings.OffPeakRate
+	if shownRate <= 0 {
+		shownRate = queue.DefaultOffPeakRate
+	}
+	rate := newNumberField("window_rate", strconv.Itoa(shownRate), 1, 1<<20, func(v float64, raw string) {
+		ctx.Settings.OffPeakRate = int(v)
+		ctx.DB.SaveSetting("OffPeakRate", raw)
+		apply()
+	})
+
+	title := widget.NewLabelWithStyle("", fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
+	content := container.NewVBox(
+		title,
+		limit.row(),
+		container.NewGridWithColumns(2, windowsLabel, windowsEntry),
+		container.NewGridWithColumns(2, modeLabel, modeSelect),
+		rate.row(),
+	)
+
+	updateText := func() {
+		title.SetText(locales.Get("window_title"))
+		windowsLabel.SetText(locales.Get("window_ranges"))
+		modeLabel.SetText(locales.Get("window_mode"))
+		i := modeSelect.SelectedIndex()
+		modeSelect.Options = modeOptions()
+		modeSelect.Selected = modeSelect.Options[max(i, 0)]
+		modeSelect.Refresh()
+		rate.updateText()
+		limit.updateText()
+	}
+	updateText()
+	return content, updateText
+}
+
+// modeOptions returns the localized off-peak modes in the order of
+// queue.OffPeakPause and queue.OffPeakThrottle. SelectedIndex needs them to
+// differ, so they are set before anything is selected.
+func modeOptions() []string {
+	return []string{locales.Get("window_mode_pause"), locales.Get("window_mode_throttle")}
+}
+
+// buildNetworkSettings edits the proxy and connection settings used by
+// downloads, thumbnails and updates
+func buildNetworkSettings(ctx *AppContext) (fyne.CanvasObject, func()) {
+	n := &ctx.Settings.Network
+	save := func(key string, apply func(string)) func(string) {
+		return func(v string) {
+			apply(v)
+			ctx.DB.SaveSetting(key, v)
+			utils.SetNetwork(*n)
+		}
+	}
+
+	proxy := newTextField("net_proxy", n.Proxy, "socks5://127.0.0.1:1080", func(s string) bool {
+		_, err := utils.ParseProxy(s)
+		return err == nil
+	}, "net_err_proxy", save("Proxy", func(v string) { n.Proxy = v }))
+	source := newTextField("net_source", n.SourceAddress, "192.168.1.10", func(s string) bool {
+		return net.ParseIP(s) != nil
+	}, "net_err_source", save("SourceAddress", func(v string) { n.SourceAddress = v }))
+	caBundle := newTextField("net_ca_bundle", n.CABundle, "/etc/ssl/corp-ca.pem", func(s string) bool {
+		return utils.ValidateNetwork(models.NetworkConfig{CABundle: s}) == nil
+	}, "net_err_ca", save("CABundle", func(v string) { n.CABundle = v }))
+	caBtn := widget.NewButtonWithIcon("", theme.FolderOpenIcon(), func() {
+		dialog.ShowFileOpen(func(r fyne.URIReadCloser, err error) {
+			if r != nil {
+				r.Close()
+				caBundle.entry.SetText(r.URI().Path())
+			}
+		}, ctx.Win)
+	})
+	ipVersion := newChoiceField("net_ip_version", []string{"4", "6"}, []string{"IPv4", "IPv6"}, strconv.Itoa(n.IPVersion),
+		save("IPVersion", func(v string) { n.IPVersion, _ = strconv.Atoi(v) }))
+
+	title := widget.NewLabelWithStyle("", fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
+	content := container.NewVBox(
+		title,
+		proxy.row(),
+		source.row(),
+		ipVersion.row(),
+		container.NewGridWithColumns(2, caBundle.label, container.NewBorder(nil, nil, nil, caBtn, caBundle.entry)),
+	)
+
+	updateText := func() {
+		title.SetText(locales.Get("net_title"))
+		proxy.updateText()
+		source.updateText()
+		ipVersion.updateText()
+		caBundle.updateText()
+	}
+	updateText()
+	return content, updateText
+}
+
+// buildSponsorSettings edits what SponsorBlock does with each segment
+// category. Until the user changes anything, every category is removed.
+func buildSponsorSettings(ctx *AppContext) (fyne.CanvasObject, func()) {
+	actions := []string{models.SponsorRemove, models.SponsorMark, models.SponsorIgnore}
+	actionKeys := []string{"sponsor_remove", "sponsor_mark", "sponsor_ignore"}
+
+	labels := make([]*widget.Label, len(models.SponsorCategories))
+	selects := make([]*widget.Select, len(models.SponsorCategories))
+	save := func() {
+		prefs := make(map[string]string)
+		for i, c := range models.SponsorCategories {
+			prefs[c] = actions[max(selects[i].SelectedIndex(), 0)]
+			ctx.DB.SaveSetting("SponsorBlock."+c, prefs[c])
+		}
+		ctx.Settings.SponsorBlock = prefs
+	}
+
+	title := widget.NewLabelWithStyle("", fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
+	hint := widget.NewLabel("")
+	hint.Wrapping = fyne.TextWrapWord
+	content := container.NewVBox(title, hint)
+	for i, c := range models.SponsorCategories {
+		labels[i] = widget.NewLabel("")
+		selects[i] = widget.NewSelect(make([]string, len(actions)), nil)
+		for j, key := range actionKeys {
+			selects[i].Options[j] = locales.Get(key)
+		}
+		current := 0
+		if a, ok := ctx.Settings.SponsorBlock[c]; ok {
+			current = max(slices.Index(actions, a), 0)
+		} else if len(ctx.Settings.SponsorBlock) > 0 {
+			current = slices.Index(actions, models.SponsorIgnore)
+		}
+		selects[i].SetSelectedIndex(current)
+		selects[i].OnChanged = func(string) { save() }
+		content.Add(container.NewGridWithColumns(2, labels[i], selects[i]))
+	}
+
+	updateText := func() {
+		title.SetText(locales.Get("sponsor_title"))
+		hint.SetText(locales.Get("sponsor_hint"))
+		for i, c := range models.SponsorCategories {
+			labels[i].SetText(locales.Get("sponsor_cat_" + c))
+			current := max(selects[i].SelectedIndex(), 0)
+			for j, key := range actionKeys {
+				selects[i].Options[j] = locales.Get(key)
+			}
+			selects[i].Selected = selects[i].Options[current]
+			selects[i].Refresh()
+		}
+	}
+	updateText()
+	return content, updateText
+}
@@ -0,0 +1,107 @@
+package gui
+
+import (
+	"fmt"
+	"gotube/internal/locales"
+	"gotube/internal/models"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// showSubtitlePicker lets the user tick subtitle languages from the video's
+// tracks. A filter helps with YouTube's long list of auto-translations.
+// onSelect receives the picked languages in list order.
+func showSubtitlePicker(ctx *AppContext, tracks []models.SubtitleTrack, current []string, onSelect func([]string)) {
+	selected := make(map[string]bool)
+	for _, lang := range current {
+		selected[lang] = true
+	}
+	labelCount := widget.NewLabel("")
+	updateCount := func() {
+		labelCount.SetText(fmt.Sprintf(locales.Get("pl_selected"), len(selected)))
+	}
+	updateCount()
+
+	visible := tracks
+	list := widget.NewList(
+		func() int { return len(visible) },
+		func() fyne.CanvasObject { return widget.NewCheck("Language", nil) },
+		func(i int, o fyne.CanvasObject) {
+			t := visible[i]
+			check := o.(*widget.Check)
+			check.Text = subtitleLabel(t)
+			check.Checked = selected[t.Language]
+			check.OnChanged = func(b bool) {
+				if b {
+					selected[t.Language] = true
+				} else {
+					delete(selected, t.Language)
+				}
+				updateCount()
+			}
+			check.Refresh()
+		},
+	)
+
+	filter := widget.NewEntry()
+	filter.SetPlaceHolder(locales.Get("subs_filter"))
+	filter.OnChanged = func(s string) {
+		s = strings.ToLower(strings.TrimSpace(s))
+		visible = nil
+		for _, t := range tracks {
+			if strings.Contains(strings.ToLower(t.Name), s) || strings.Contains(strings.ToLower(t.Language), s) {
+				visible = append(visible, t)
+			}
+		}
+		list.Refresh()
+	}
+	btnNone := widget.NewButton(locales.Get("pl_select_none"), func() {
+		clear(selected)
+		list.Refresh()
+		updateCount()
+	})
+
+	content := container.NewBorder(container.NewVBox(filter, container.NewHBox(labelCount, btnNone)), nil, nil, nil, list)
+	d := dialog.NewCustomConfirm(locales.Get("subs_title"), locales.Get("pl_confirm"), locales.Get("btn_cancel"), content, func(ok bool) {
+		if !ok {
+			return
+		}
+		var picked []string
+		for _, t := range tracks {
+			if selected[t.Language] {
+				picked = append(picked, t.Language)
+			}
+		}
+		onSelect(picked)
+	}, ctx.Win)
+	d.Resize(fyne.NewSize(450, 600))
+	d.Show()
+}
+
+func subtitleLabel(t models.SubtitleTrack) string {
+	label := t.Language
+	if t.Name != t.Language {
+		label = fmt.Sprintf("%s (%s)", t.Name, t.Language)
+	}
+	if t.Auto {
+		label += " – " + locales.Get("subs_auto_track")
+	}
+	return label
+}
+
+// pickedAutoSubs reports whether any of the picked languages only has
+// auto-generated subtitles
+func pickedAutoSubs(tracks []models.SubtitleTrack, picked []string) bool {
+	for _, t := range tracks {
+		for _, lang := range picked {
+			if t.Auto && t.Language == lang {
+				return true
+			}
+		}
+	}
+	return false
+}
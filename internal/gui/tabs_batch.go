@@ -4,7 +4,9 @@ import (
 	"fmt"
 	"gotube/internal/locales"
 	"gotube/internal/models"
+	"gotube/internal/queue"
 	"strings"
+	"sync"
 	"time"
 
 	"fyne.io/fyne/v2"
@@ -14,7 +16,7 @@ import (
 	"fyne.io/fyne/v2/widget"
 )
 
-func buildBatchTab(ctx *AppContext) (fyne.CanvasObject, *widget.Button, func()) {
+func buildBatchTab(ctx *AppContext) (fyne.CanvasObject, *widget.Button, *widget.Button, func()) {
 	// Components
 	batchEntry := widget.NewMultiLineEntry()
 	batchEntry.SetPlaceHolder("https://youtube.com/video1\nhttps://youtube.com/video2\n...")
@@ -41,6 +43,15 @@ func buildBatchTab(ctx *AppContext) (fyne.CanvasObject, *widget.Button, func())
 	clientSelect.Selected = "Web"
 	checkSponsor := widget.NewCheck("", nil)
 	checkSafe := widget.NewCheck("", nil)
+	checkForce := widget.NewCheck("", nil)
+	checkSplit := widget.NewCheck("", nil)
+	checkMarkers := widget.NewCheck("", nil)
+	subs := newSubtitleOptions()
+	startAt := newStartAtEntry()
+	labelStartAt := widget.NewLabel("")
+	rateEntry := newRateEntry()
+	network := newNetworkOptions(ctx.Win)
+	labelRate := widget.NewLabel("")
 
 	cookieBtn := widget.NewButton("", func() {
 		dialog.ShowFileOpen(func(r fyne.URIReadCloser, err error) {
@@ -55,6 +66,63 @@ func buildBatchTab(ctx *AppContext) (fyne.CanvasObject, *widget.Button, func())
 	batchBtn := widget.NewButtonWithIcon("Start Batch", theme.MediaPlayIcon(), nil)
 	batchBtn.Importance = widget.HighImportance
 
+	// State of every job submitted from this tab, for the footer summary
+	var batchMu sync.Mutex
+	batchJobs := make(map[int]queue.State)
+
+	cancelBtn := widget.NewButtonWithIcon("", theme.CancelIcon(), func() {
+		batchMu.Lock()
+		ids := make([]int, 0, len(batchJobs))
+		for id := range batchJobs {
+			ids = append(ids, id)
+		}
+		batchMu.Unlock()
+		for _, id := range ids {
+			ctx.Queue.Cancel(id)
+		}
+	})
+	cancelBtn.Disable()
+
+	refreshSummary := func() {
+		batchMu.Lock()
+		total, done, failed, finished := len(batchJobs), 0, 0, 0
+		for _, st := range batchJobs {
+			if st.Finished() {
+				finished++
+			}
+			if st == queue.StateDone {
+				done++
+			} else if st == queue.StateFailed {
+				failed++
+			}
+		}
+		batchMu.Unlock()
+		if total == 0 {
+			return
+		}
+
+		ctx.BatchProgress.Set(float64(finished) / float64(total))
+		if finished == total {
+			ctx.BatchStatus.Set(fmt.Sprintf(locales.Get("batch_done"), done, failed))
+			cancelBtn.Disable()
+		} else {
+			ctx.BatchStatus.Set(fmt.Sprintf(locales.Get("batch_status"), finished, total, failed))
+			cancelBtn.Enable()
+		}
+	}
+
+	ctx.Queue.Subscribe(func(job queue.Job) {
+		batchMu.Lock()
+		_, ok := batchJobs[job.ID]
+		if ok {
+			batchJobs[job.ID] = job.State
+		}
+		batchMu.Unlock()
+		if ok {
+			refreshSummary()
+		}
+	})
+
 	// Logic
 	batchBtn.OnTapped = func() {
 		raw := batchEntry.Text
@@ -71,9 +139,21 @@ func buildBatchTab(ctx *AppContext) (fyne.CanvasObject, *widget.Button, func())
 		if len(urls) == 0 {
 			return
 		}
-
-		batchBtn.Disable()
-		ctx.Progress.Set(0.0)
+		at, err := parseStartAt(startAt)
+		if err != nil {
+			dialog.ShowError(err, ctx.Win)
+			return
+		}
+		rate, err := parseRate(rateEntry)
+		if err != nil {
+			dialog.ShowError(err, ctx.Win)
+			return
+		}
+		jobNetwork, err := network.config()
+		if err != nil {
+			dialog.ShowError(err, ctx.Win)
+			return
+		}
 
 		mode := "Video"
 		if formatSelect.Selected == locales.Get("format_audio") {
@@ -88,34 +168,48 @@ func buildBatchTab(ctx *AppContext) (fyne.CanvasObject, *widget.Button, func())
 			CookiesPath:     ctx.Settings.CookiesPath,
 			SafeMode:        checkSafe.Checked,
 			IsPlaylist:      false,
-			EmbedSubs:       false, // Simplified for batch
-			AutoSubs:        false,
 			UseSponsorBlock: checkSponsor.Checked,
-		}
-
-		go func() {
-			total := float64(len(urls))
-			for i, u := range urls {
-				ctx.Status.Set(fmt.Sprintf("Batch: %d/%d", i+1, int(total)))
+			SponsorBlock:    sponsorBlock(ctx, checkSponsor.Checked),
+			Prefs:           ctx.Settings.FormatPrefs,
+			OutputTemplate:  ctx.Settings.OutputTemplate,
+			ForceRedownload: checkForce.Checked,
+			RateLimit:       rate,
+			Network:         jobNetwork,
 
-				req := baseReq
-				req.URL = u
+			SplitChapters: checkSplit.Checked,
+			EmbedChapters: checkMarkers.Checked,
+		}
+		subs.apply(&baseReq)
 
-				title := u
-				if meta, err := ctx.Engine.GetMetadata(u); err == nil {
-					title = meta.Title
-				}
+		// Start a fresh summary unless the previous batch is still running
+		batchMu.Lock()
+		running := false
+		for _, st := range batchJobs {
+			if !st.Finished() {
+				running = true
+			}
+		}
+		if !running {
+			batchJobs = make(map[int]queue.State)
+		}
+		batchMu.Unlock()
 
-				ctx.Engine.Download(req, func(update models.ProgressUpdate) {
-					ctx.Logger.Write(fmt.Sprintf("[%d/%d] %s", i+1, int(total), update.Text))
-				})
+		var ids []int
+		for _, u := range urls {
+			req := baseReq
+			req.URL = u
+			ids = append(ids, ctx.Queue.SubmitAt(req, nil, queue.PriorityNormal, at).ID)
+		}
 
-				ctx.DB.SaveHistory(title, u, req.OutputPath)
-				ctx.Progress.Set(float64(i+1) / total)
+		// Register with the current state in case a job already moved on
+		batchMu.Lock()
+		for _, id := range ids {
+			if job, ok := ctx.Queue.Get(id); ok {
+				batchJobs[id] = job.State
 			}
-			ctx.Status.Set("Batch Complete")
-			batchBtn.Enable()
-		}()
+		}
+		batchMu.Unlock()
+		refreshSummary()
 	}
 
 	// Layout
@@ -129,6 +223,12 @@ func buildBatchTab(ctx *AppContext) (fyne.CanvasObject, *widget.Button, func())
 	advContent := container.NewVBox(
 		container.NewGridWithColumns(2, widget.NewLabel("Client:"), clientSelect),
 		container.NewGridWithColumns(2, cookieBtn, container.NewHBox(checkSponsor, checkSafe)),
+		container.NewGridWithColumns(2, labelStartAt, startAt),
+		container.NewGridWithColumns(2, labelRate, rateEntry),
+		container.NewVBox(network.rows()...),
+		container.NewGridWithColumns(2, checkSplit, checkMarkers),
+		container.NewVBox(subs.rows()...),
+		checkForce,
 	)
 	advExpander := widget.NewAccordion(widget.NewAccordionItem(locales.Get("adv_options"), advContent))
 
@@ -160,8 +260,16 @@ func buildBatchTab(ctx *AppContext) (fyne.CanvasObject, *widget.Button, func())
 	// Updater
 	updateText := func() {
 		cookieBtn.SetText(locales.Get("cookies"))
+		cancelBtn.SetText(locales.Get("btn_cancel"))
 		checkSponsor.SetText(locales.Get("sponsor"))
 		checkSafe.SetText(locales.Get("safe_mode"))
+		checkForce.SetText(locales.Get("archive_force"))
+		checkSplit.SetText(locales.Get("chapters_split"))
+		checkMarkers.SetText(locales.Get("chapters_markers"))
+		subs.updateText()
+		labelStartAt.SetText(locales.Get("schedule_label"))
+		labelRate.SetText(locales.Get("rate_label"))
+		network.updateText()
 		advExpander.Items[0].Title = locales.Get("adv_options")
 		advExpander.Refresh()
 
@@ -170,5 +278,5 @@ func buildBatchTab(ctx *AppContext) (fyne.CanvasObject, *widget.Button, func())
 		formatSelect.Refresh()
 	}
 
-	return content, batchBtn, updateText
+	return content, batchBtn, cancelBtn, updateText
 }
@@ -1,9 +1,13 @@
 package gui
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"gotube/internal/downloader"
 	"gotube/internal/locales"
 	"gotube/internal/models"
+	"gotube/internal/queue"
 	"gotube/internal/utils"
 	"strings"
 	"time"
@@ -16,13 +20,14 @@ import (
 	"fyne.io/fyne/v2/widget"
 )
 
-// Returns: Content, ActionButton, UpdateFunc
-func buildMainTab(ctx *AppContext) (fyne.CanvasObject, *widget.Button, func()) {
+// Returns: Content, ActionButton, CancelButton, UpdateFunc
+func buildMainTab(ctx *AppContext) (fyne.CanvasObject, *widget.Button, *widget.Button, func()) {
 	// Components
 	urlEntry := widget.NewEntry()
 	previewImage := createPreviewImage()
 
-	var currentTitle string = "Unknown Video"
+	var currentMeta *models.VideoMetadata
+	var currentMetaURL string
 	var currentPlEntries []models.PlaylistEntry
 	var selectedPlIndices []string
 	isPlMode := false
@@ -37,6 +42,83 @@ func buildMainTab(ctx *AppContext) (fyne.CanvasObject, *widget.Button, func()) {
 	playlistBtn := widget.NewButton(locales.Get("pl_select_btn"), nil)
 	playlistBtn.Disable()
 
+	// An exact format from the picker overrides the video Quality preset
+	selectedFormat := ""
+	formatBtn := widget.NewButton(locales.Get("formats_btn"), nil)
+	formatBtn.Disable()
+	updateFormatBtn := func() {
+		if selectedFormat == "" {
+			formatBtn.SetText(locales.Get("formats_btn"))
+			return
+		}
+		text := fmt.Sprintf(locales.Get("formats_selected"), selectedFormat)
+		if size, ok := downloader.EstimateSize(currentMeta, selectedFormat); ok {
+			text += " • " + utils.FormatBytes(size)
+		}
+		formatBtn.SetText(text)
+	}
+	formatBtn.OnTapped = func() {
+		if currentMeta == nil || len(currentMeta.Formats) == 0 {
+			return
+		}
+		showFormatPicker(ctx, currentMeta, selectedFormat, func(s string) {
+			selectedFormat = s
+			updateFormatBtn()
+		})
+	}
+
+	// Picked chapters are downloaded as separate files; none means the whole video
+	var selectedChapters []int
+	chapterBtn := widget.NewButton(locales.Get("chapters_btn"), nil)
+	chapterBtn.Disable()
+	updateChapterBtn := func() {
+		switch {
+		case currentMeta == nil || len(currentMeta.Chapters) == 0:
+			chapterBtn.SetText(locales.Get("chapters_btn"))
+		case len(selectedChapters) == 0:
+			chapterBtn.SetText(fmt.Sprintf(locales.Get("chapters_all"), len(currentMeta.Chapters)))
+		default:
+			chapterBtn.SetText(fmt.Sprintf(locales.Get("chapters_some"), len(selectedChapters), len(currentMeta.Chapters)))
+		}
+	}
+	chapterBtn.OnTapped = func() {
+		if currentMeta == nil || len(currentMeta.Chapters) == 0 {
+			return
+		}
+		showChapterPicker(ctx, currentMeta.Chapters, selectedChapters, func(picked []int) {
+			selectedChapters = picked
+			updateChapterBtn()
+		})
+	}
+
+	// Picked subtitle languages; none falls back to the language select
+	subs := newSubtitleOptions()
+	var selectedSubs []string
+	subsBtn := widget.NewButton(locales.Get("subs_btn"), nil)
+	subsBtn.Disable()
+	updateSubsBtn := func() {
+		switch {
+		case currentMeta == nil || len(currentMeta.SubtitleTracks()) == 0:
+			subsBtn.SetText(locales.Get("subs_btn"))
+		case len(selectedSubs) == 0:
+			subsBtn.SetText(fmt.Sprintf(locales.Get("subs_available"), len(currentMeta.SubtitleTracks())))
+		default:
+			subsBtn.SetText(fmt.Sprintf(locales.Get("subs_some"), strings.Join(selectedSubs, ", ")))
+		}
+	}
+	subsBtn.OnTapped = func() {
+		if currentMeta == nil {
+			return
+		}
+		showSubtitlePicker(ctx, currentMeta.SubtitleTracks(), selectedSubs, func(picked []string) {
+			selectedSubs = picked
+			if len(picked) > 0 && !subs.wanted() {
+				subs.embed.SetChecked(true)
+			}
+			updateSubsBtn()
+		})
+	}
+
 	pathEntry := widget.NewEntry()
 	pathEntry.SetText(ctx.Settings.LastSavePath)
 	pathEntry.Disable()
@@ -51,11 +133,22 @@ func buildMainTab(ctx *AppContext) (fyne.CanvasObject, *widget.Button, func()) {
 	})
 	pathContainer := container.NewBorder(nil, nil, nil, pathBtn, pathEntry)
 
+	labelTemplate := widget.NewLabelWithStyle("", fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
+	templateEditor, refreshTemplate, updateTemplateText := buildTemplateEditor(ctx, func() (*models.VideoMetadata, string) {
+		return currentMeta, outputExt(formatSelect.Selected == locales.Get("format_audio"), detailSelect.Selected, ctx.Settings.FormatPrefs)
+	})
+	onFormatChanged := formatSelect.OnChanged
+	formatSelect.OnChanged = func(s string) {
+		onFormatChanged(s)
+		refreshTemplate()
+	}
+	detailSelect.OnChanged = func(string) { refreshTemplate() }
+
 	// Advanced
-	trimStart := widget.NewEntry()
-	trimStart.SetPlaceHolder("00:00:00")
-	trimEnd := widget.NewEntry()
-	trimEnd.SetPlaceHolder("00:00:00")
+	rangesEntry := newRangesEntry()
+	startAt := newStartAtEntry()
+	rateEntry := newRateEntry()
+	network := newNetworkOptions(ctx.Win)
 
 	clientSelect := widget.NewSelect([]string{"Web", "Android", "iOS"}, nil)
 	clientSelect.Selected = "Web"
@@ -63,22 +156,22 @@ func buildMainTab(ctx *AppContext) (fyne.CanvasObject, *widget.Button, func()) {
 		clientSelect.Selected = ctx.Settings.ClientSpoof
 	}
 
-	checkSponsor := widget.NewCheck("", nil)
-	checkSafe := widget.NewCheck("", nil)
-
-	checkEmbed := widget.NewCheck("", nil)
-	checkAuto := widget.NewCheck("", nil)
-	checkAuto.Disable()
-	checkEmbed.OnChanged = func(b bool) {
-		if b {
-			checkAuto.Enable()
-		} else {
-			checkAuto.Disable()
+	// Index 0 is "Auto", which lets the engine choose by URL
+	backendSelect := widget.NewSelect(append([]string{""}, ctx.Engine.Backends()...), nil)
+	backendSelect.SetSelectedIndex(0)
+	backendName := func() string {
+		if backendSelect.SelectedIndex() <= 0 {
+			return ""
 		}
+		return backendSelect.Selected
 	}
 
-	subLang := widget.NewSelect([]string{"en", "de", "all"}, nil)
-	subLang.Selected = "en"
+	checkSponsor := widget.NewCheck("", nil)
+	checkSafe := widget.NewCheck("", nil)
+	checkForce := widget.NewCheck("", nil)
+	checkSplit := widget.NewCheck("", nil)
+	checkMarkers := widget.NewCheck("", nil)
+	checkConcat := widget.NewCheck("", nil)
 
 	cookieBtn := widget.NewButton("", func() {
 		dialog.ShowFileOpen(func(r fyne.URIReadCloser, err error) {
@@ -92,10 +185,11 @@ func buildMainTab(ctx *AppContext) (fyne.CanvasObject, *widget.Button, func()) {
 	// Dynamic Labels
 	labelQuality := widget.NewLabelWithStyle("", fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
 	labelSaveTo := widget.NewLabelWithStyle("", fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
-	labelTrimStart := widget.NewLabel("")
-	labelTrimEnd := widget.NewLabel("")
+	labelRanges := widget.NewLabel("")
 	labelClient := widget.NewLabel("")
-	labelSubLang := widget.NewLabel("")
+	labelBackend := widget.NewLabel("")
+	labelStartAt := widget.NewLabel("")
+	labelRate := widget.NewLabel("")
 
 	// Logic
 	playlistBtn.OnTapped = func() {
@@ -171,12 +265,47 @@ func buildMainTab(ctx *AppContext) (fyne.CanvasObject, *widget.Button, func()) {
 		}
 		ctx.Status.Set(locales.Get("fetching"))
 		go func() {
-			meta, err := ctx.Engine.GetMetadata(url)
+			var meta *models.VideoMetadata
+			backend, err := ctx.Engine.BackendFor(models.DownloadConfig{URL: url, Backend: backendName()})
+			if err == nil {
+				// Invalid job settings are reported on download; until then use the global ones
+				jobNetwork, _ := network.config()
+				merged := utils.Network().Merge(jobNetwork)
+				meta, err = backend.GetMetadata(downloader.WithNetwork(context.Background(), merged), url)
+			}
 			if err != nil {
-				ctx.Status.Set("Error: " + err.Error())
+				var dlErr *downloader.Error
+				if errors.As(err, &dlErr) && dlErr.Kind != downloader.KindUnknown {
+					ctx.Status.Set(errorHint(dlErr.Kind))
+				} else {
+					ctx.Status.Set("Error: " + err.Error())
+				}
 				return
 			}
-			currentTitle = meta.Title
+			currentMeta = meta
+			currentMetaURL = url
+			selectedFormat = ""
+			updateFormatBtn()
+			refreshTemplate()
+			if len(meta.Formats) > 0 {
+				formatBtn.Enable()
+			} else {
+				formatBtn.Disable()
+			}
+			selectedChapters = nil
+			updateChapterBtn()
+			if len(meta.Chapters) > 0 {
+				chapterBtn.Enable()
+			} else {
+				chapterBtn.Disable()
+			}
+			selectedSubs = nil
+			updateSubsBtn()
+			if len(meta.SubtitleTracks()) > 0 {
+				subsBtn.Enable()
+			} else {
+				subsBtn.Disable()
+			}
 			ctx.Status.Set(locales.Get("meta_loaded"))
 			previewTitle.SetText(meta.Title)
 
@@ -215,11 +344,75 @@ func buildMainTab(ctx *AppContext) (fyne.CanvasObject, *widget.Button, func()) {
 	}
 	checkBtn := widget.NewButtonWithIcon("", theme.SearchIcon(), func() { performFetch(urlEntry.Text) })
 
-	var downloadBtn *widget.Button
-	downloadBtn = widget.NewButtonWithIcon("", theme.DownloadIcon(), func() {
+	// The footer follows the job most recently submitted from this tab
+	trackedJob := 0
+	cancelBtn := widget.NewButtonWithIcon("", theme.CancelIcon(), func() {
+		if trackedJob != 0 {
+			ctx.Queue.Cancel(trackedJob)
+		}
+	})
+	cancelBtn.Disable()
+
+	ctx.Queue.Subscribe(func(job queue.Job) {
+		if job.ID != trackedJob {
+			return
+		}
+		switch job.State {
+		case queue.StateDone:
+			ctx.Status.Set(locales.Get("success"))
+			ctx.Progress.Set(1.0)
+			ctx.Detail.Set("")
+			cancelBtn.Disable()
+		case queue.StateFailed:
+			ctx.Status.Set(locales.Get("failed"))
+			ctx.Detail.Set("")
+			cancelBtn.Disable()
+			msg := job.Err
+			if hint := errorHint(job.ErrKind); hint != "" {
+				msg = hint + "\n\n" + job.Err
+			}
+			dialog.ShowError(errors.New(msg), ctx.Win)
+		case queue.StateCancelled:
+			ctx.Status.Set(locales.Get("cancelled"))
+			ctx.Progress.Set(0.0)
+			ctx.Detail.Set("")
+			cancelBtn.Disable()
+		case queue.StateQueued:
+			ctx.Status.Set(locales.Get("queued"))
+		default:
+			if job.Progress.Percent > 0 {
+				ctx.Progress.Set(job.Progress.Percent)
+			}
+			if detail := formatProgressDetail(job.Progress); detail != "" {
+				ctx.Detail.Set(detail)
+			}
+			if job.Progress.Stage != "" {
+				ctx.Status.Set(job.Progress.Stage + "...")
+			} else {
+				ctx.Status.Set(locales.Get("fetching"))
+			}
+		}
+	})
+
+	downloadBtn := widget.NewButtonWithIcon("", theme.DownloadIcon(), func() {
 		if urlEntry.Text == "" {
 			return
 		}
+		at, err := parseStartAt(startAt)
+		if err != nil {
+			dialog.ShowError(err, ctx.Win)
+			return
+		}
+		rate, err := parseRate(rateEntry)
+		if err != nil {
+			dialog.ShowError(err, ctx.Win)
+			return
+		}
+		jobNetwork, err := network.config()
+		if err != nil {
+			dialog.ShowError(err, ctx.Win)
+			return
+		}
 
 		mode := "Video"
 		if formatSelect.Selected == locales.Get("format_audio") {
@@ -235,53 +428,65 @@ func buildMainTab(ctx *AppContext) (fyne.CanvasObject, *widget.Button, func()) {
 			OutputPath:      ctx.Settings.LastSavePath,
 			DownloadMode:    mode,
 			Quality:         detailSelect.Selected,
-			TrimStart:       trimStart.Text,
-			TrimEnd:         trimEnd.Text,
 			UseSponsorBlock: checkSponsor.Checked,
+			SponsorBlock:    sponsorBlock(ctx, checkSponsor.Checked),
 			Client:          clientSelect.Selected,
 			CookiesPath:     ctx.Settings.CookiesPath,
 			SafeMode:        checkSafe.Checked,
 			IsPlaylist:      isPlMode,
 			PlaylistItems:   idxStr,
-			EmbedSubs:       checkEmbed.Checked,
-			AutoSubs:        checkAuto.Checked,
-			SubLanguage:     subLang.Selected,
+			Backend:         backendName(),
+			Prefs:           ctx.Settings.FormatPrefs,
+			OutputTemplate:  ctx.Settings.OutputTemplate,
+			ForceRedownload: checkForce.Checked,
+			RateLimit:       rate,
+			Network:         jobNetwork,
+
+			SplitChapters: checkSplit.Checked,
+			EmbedChapters: checkMarkers.Checked,
+			ConcatRanges:  checkConcat.Checked,
 		}
+		subs.apply(&req)
 		ctx.DB.SaveSetting("ClientSpoof", clientSelect.Selected)
 
-		downloadBtn.Disable()
-		ctx.Progress.Set(0.0)
-		ctx.Logger.Clear()
-		ctx.Logger.Write("Starting download...")
-
-		go func() {
-			if currentTitle == "Unknown Video" {
-				if meta, err := ctx.Engine.GetMetadata(req.URL); err == nil {
-					currentTitle = meta.Title
-				}
+		// Reuse the preview metadata so the worker can skip its own fetch.
+		// The picked format and chapters only apply to the video they were
+		// picked for, and only its duration can check the ranges.
+		var meta *models.VideoMetadata
+		duration := 0
+		if currentMeta != nil && currentMetaURL == req.URL {
+			meta = currentMeta
+			req.FormatID = selectedFormat
+			for _, i := range selectedChapters {
+				req.Chapters = append(req.Chapters, currentMeta.Chapters[i].Title)
 			}
-
-			err := ctx.Engine.Download(req, func(update models.ProgressUpdate) {
-				if update.Percent > 0 {
-					ctx.Progress.Set(update.Percent)
-				}
-				ctx.Status.Set(update.Stage + "...")
-				ctx.Logger.Write(update.Text)
-			})
-
-			if err != nil {
-				ctx.Status.Set(locales.Get("failed"))
-				ctx.Logger.Write("ERROR: " + err.Error())
-				dialog.ShowError(err, ctx.Win)
-			} else {
-				ctx.Status.Set(locales.Get("success"))
-				ctx.Progress.Set(1.0)
-				ctx.Logger.Write("SUCCESS: Download finished.")
-				ctx.DB.SaveHistory(currentTitle, req.URL, req.OutputPath)
-				currentTitle = "Unknown Video"
+			if req.EmbedSubs || req.WriteSubs {
+				req.SubLanguages = selectedSubs
+				req.AutoSubs = req.AutoSubs || pickedAutoSubs(currentMeta.SubtitleTracks(), selectedSubs)
 			}
-			downloadBtn.Enable()
-		}()
+			if !isPlMode {
+				duration = currentMeta.Duration
+			}
+		}
+		if req.Ranges, err = parseRanges(rangesEntry, duration); err != nil {
+			dialog.ShowError(err, ctx.Win)
+			return
+		}
+		if len(req.Chapters) > 0 && len(req.Ranges) > 0 {
+			dialog.ShowError(errors.New(locales.Get("chapters_err_trim")), ctx.Win)
+			return
+		}
+
+		ctx.Progress.Set(0.0)
+		ctx.Detail.Set("")
+		job := ctx.Queue.SubmitAt(req, meta, queue.PriorityNormal, at)
+		trackedJob = job.ID
+		if at.IsZero() {
+			ctx.Status.Set(locales.Get("queued"))
+		} else {
+			ctx.Status.Set(fmt.Sprintf(locales.Get("queue_scheduled"), formatStartAt(at)))
+		}
+		cancelBtn.Enable()
 	})
 	downloadBtn.Importance = widget.HighImportance
 
@@ -295,19 +500,29 @@ func buildMainTab(ctx *AppContext) (fyne.CanvasObject, *widget.Button, func()) {
 		widget.NewSeparator(),
 		labelQuality,
 		container.NewGridWithColumns(2, formatSelect, detailSelect),
+		formatBtn,
+		chapterBtn,
+		subsBtn,
 		labelSaveTo,
 		pathContainer,
+		labelTemplate,
+		templateEditor,
 		playlistBtn,
 	)
 	unifiedCard := widget.NewCard("", "", unifiedContent)
 
 	advContent := container.NewVBox(
-		container.NewGridWithColumns(2, labelTrimStart, trimStart),
-		container.NewGridWithColumns(2, labelTrimEnd, trimEnd),
+		container.NewGridWithColumns(2, labelRanges, rangesEntry),
+		checkConcat,
 		container.NewGridWithColumns(2, labelClient, clientSelect),
-		container.NewGridWithColumns(2, labelSubLang, subLang),
-		container.NewGridWithColumns(2, checkEmbed, checkAuto),
+		container.NewGridWithColumns(2, labelBackend, backendSelect),
+		container.NewGridWithColumns(2, labelStartAt, startAt),
+		container.NewGridWithColumns(2, labelRate, rateEntry),
+		container.NewVBox(network.rows()...),
+		container.NewVBox(subs.rows()...),
 		container.NewGridWithColumns(2, cookieBtn, container.NewHBox(checkSponsor, checkSafe)),
+		container.NewGridWithColumns(2, checkSplit, checkMarkers),
+		checkForce,
 	)
 	advExpander := widget.NewAccordion(widget.NewAccordionItem("", advContent))
 
@@ -343,23 +558,39 @@ func buildMainTab(ctx *AppContext) (fyne.CanvasObject, *widget.Button, func()) {
 		urlEntry.SetPlaceHolder(locales.Get("placeholder"))
 		labelQuality.SetText(locales.Get("quality"))
 		labelSaveTo.SetText(locales.Get("save_to"))
+		labelTemplate.SetText(locales.Get("template_label"))
+		updateTemplateText()
 		if isPlMode {
 			playlistBtn.SetText(fmt.Sprintf("%s (%d)", locales.Get("pl_select_btn"), len(selectedPlIndices)))
 		} else {
 			playlistBtn.SetText(locales.Get("pl_select_btn"))
 		}
+		updateFormatBtn()
+		updateChapterBtn()
+		checkSplit.SetText(locales.Get("chapters_split"))
+		checkMarkers.SetText(locales.Get("chapters_markers"))
 		advExpander.Items[0].Title = locales.Get("adv_options")
 		advExpander.Refresh()
-		labelTrimStart.SetText(locales.Get("trim_start"))
-		labelTrimEnd.SetText(locales.Get("trim_end"))
+		labelRanges.SetText(locales.Get("trim_label"))
+		checkConcat.SetText(locales.Get("trim_concat"))
+		labelStartAt.SetText(locales.Get("schedule_label"))
+		labelRate.SetText(locales.Get("rate_label"))
+		network.updateText()
 		labelClient.SetText(locales.Get("client"))
+		labelBackend.SetText(locales.Get("backend"))
+		backendSelect.Options[0] = locales.Get("backend_auto")
+		if backendSelect.SelectedIndex() <= 0 {
+			backendSelect.Selected = backendSelect.Options[0]
+		}
+		backendSelect.Refresh()
 		cookieBtn.SetText(locales.Get("cookies"))
 		checkSponsor.SetText(locales.Get("sponsor"))
 		checkSafe.SetText(locales.Get("safe_mode"))
+		checkForce.SetText(locales.Get("archive_force"))
 		downloadBtn.SetText(locales.Get("btn_download"))
-		checkEmbed.SetText(locales.Get("subs_embed"))
-		checkAuto.SetText(locales.Get("subs_auto"))
-		labelSubLang.SetText(locales.Get("subs_lang"))
+		cancelBtn.SetText(locales.Get("btn_cancel"))
+		updateSubsBtn()
+		subs.updateText()
 
 		// Select Options
 		formatSelect.Options = []string{locales.Get("format_video"), locales.Get("format_audio")}
@@ -367,5 +598,5 @@ func buildMainTab(ctx *AppContext) (fyne.CanvasObject, *widget.Button, func()) {
 		formatSelect.Refresh()
 	}
 
-	return content, downloadBtn, updateText
+	return content, downloadBtn, cancelBtn, updateText
 }
@@ -1,16 +1,22 @@
 package gui
 
 import (
+	"fmt"
+	"gotube/internal/locales"
+	"gotube/internal/models"
 	"gotube/internal/utils"
+	"time"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
 )
 
-func buildHistoryTab(ctx *AppContext) fyne.CanvasObject {
-	return widget.NewList(
+// Returns: Content, UpdateFunc
+func buildHistoryTab(ctx *AppContext) (fyne.CanvasObject, func()) {
+	historyList := widget.NewList(
 		func() int { return len(ctx.DB.GetHistory()) },
 		func() fyne.CanvasObject {
 			icon := widget.NewIcon(theme.MediaPlayIcon())
@@ -36,4 +42,84 @@ func buildHistoryTab(ctx *AppContext) fyne.CanvasObject {
 			}
 		},
 	)
+
+	archiveView, reloadArchive, archiveUpdate := buildArchiveView(ctx)
+	historyItem := container.NewTabItem("", historyList)
+	archiveItem := container.NewTabItem("", archiveView)
+	tabs := container.NewAppTabs(historyItem, archiveItem)
+	tabs.OnSelected = func(item *container.TabItem) {
+		if item == archiveItem {
+			reloadArchive()
+		} else {
+			historyList.Refresh()
+		}
+	}
+
+	updateText := func() {
+		historyItem.Text = locales.Get("history_downloads")
+		archiveItem.Text = locales.Get("archive_title")
+		tabs.Refresh()
+		archiveUpdate()
+	}
+	return tabs, updateText
+}
+
+// buildArchiveView lists the videos the archive will skip, with a way to
+// forget single entries or everything
+func buildArchiveView(ctx *AppContext) (fyne.CanvasObject, func(), func()) {
+	var entries []models.ArchiveEntry
+	summary := widget.NewLabel("")
+
+	list := widget.NewList(
+		func() int { return len(entries) },
+		func() fyne.CanvasObject {
+			id := widget.NewLabel("ID")
+			id.TextStyle = fyne.TextStyle{Monospace: true}
+			id.Truncation = fyne.TextTruncateEllipsis
+			library := widget.NewLabel("Library")
+			library.Truncation = fyne.TextTruncateEllipsis
+			btn := widget.NewButtonWithIcon("", theme.DeleteIcon(), nil)
+			return container.NewBorder(nil, nil, nil, btn, container.NewVBox(id, library))
+		},
+		nil,
+	)
+
+	reload := func() {
+		var err error
+		entries, err = ctx.DB.ListArchive()
+		if err != nil {
+			ctx.Logger.Write("Could not load archive: " + err.Error())
+		}
+		summary.SetText(fmt.Sprintf(locales.Get("archive_summary"), len(entries)))
+		list.Refresh()
+	}
+
+	list.UpdateItem = func(i int, o fyne.CanvasObject) {
+		e := entries[i]
+		row := o.(*fyne.Container)
+		// [0]=Labels, [1]=Button(Right)
+		labels := row.Objects[0].(*fyne.Container)
+		labels.Objects[0].(*widget.Label).SetText(e.Extractor + " " + e.VideoID)
+		labels.Objects[1].(*widget.Label).SetText(fmt.Sprintf("%s • %s", e.Library, time.Unix(e.Added, 0).Format("2006-01-02")))
+		row.Objects[1].(*widget.Button).OnTapped = func() {
+			ctx.DB.DeleteArchiveEntry(e)
+			reload()
+		}
+	}
+
+	clearBtn := widget.NewButtonWithIcon("", theme.DeleteIcon(), func() {
+		dialog.ShowConfirm(locales.Get("archive_clear"), locales.Get("archive_clear_msg"), func(ok bool) {
+			if ok {
+				ctx.DB.ClearArchive()
+				reload()
+			}
+		}, ctx.Win)
+	})
+
+	updateText := func() {
+		clearBtn.SetText(locales.Get("archive_clear"))
+		reload()
+	}
+	content := container.NewBorder(container.NewBorder(nil, nil, nil, clearBtn, summary), nil, nil, nil, list)
+	return content, reload, updateText
 }
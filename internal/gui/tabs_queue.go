@@ -0,0 +1,298 @@
+package gui
+
+import (
+	"fmt"
+	"gotube/internal/locales"
+	"gotube/internal/queue"
+	"gotube/internal/utils"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// Returns: Content, UpdateFunc
+func buildQueueTab(ctx *AppContext) (fyne.CanvasObject, func()) {
+	var mu sync.Mutex
+	jobs := ctx.Queue.Jobs()
+	dirty := true
+	thumbs := make(map[string]fyne.Resource)
+	loading := make(map[string]bool)
+
+	emptyLabel := widget.NewLabel("")
+	emptyLabel.Alignment = fyne.TextAlignCenter
+
+	// Thumbnails are fetched once per URL in the background
+	thumbnailFor := func(url string) fyne.Resource {
+		mu.Lock()
+		defer mu.Unlock()
+		if res, ok := thumbs[url]; ok {
+			return res
+		}
+		if url != "" && !loading[url] {
+			loading[url] = true
+			go func() {
+				res, err := utils.FetchResource(url)
+				mu.Lock()
+				if err == nil {
+					thumbs[url] = res
+				}
+				dirty = true
+				mu.Unlock()
+			}()
+		}
+		return theme.FileVideoIcon()
+	}
+
+	list := widget.NewList(
+		func() int {
+			mu.Lock()
+			defer mu.Unlock()
+			return len(jobs)
+		},
+		func() fyne.CanvasObject {
+			thumb := canvas.NewImageFromResource(theme.FileVideoIcon())
+			thumb.FillMode = canvas.ImageFillContain
+			thumb.SetMinSize(fyne.NewSize(64, 36))
+
+			title := widget.NewLabel("Title")
+			title.TextStyle = fyne.TextStyle{Bold: true}
+			title.Truncation = fyne.TextTruncateEllipsis
+			badge := widget.NewLabel("")
+			detail := widget.NewLabel("")
+			detail.Truncation = fyne.TextTruncateEllipsis
+
+			pauseBtn := widget.NewButtonWithIcon("", theme.MediaPauseIcon(), nil)
+			cancelBtn := widget.NewButtonWithIcon("", theme.CancelIcon(), nil)
+			retryBtn := widget.NewButtonWithIcon("", theme.ViewRefreshIcon(), nil)
+			folderBtn := widget.NewButtonWithIcon("", theme.FolderOpenIcon(), nil)
+			startBtn := widget.NewButtonWithIcon("", theme.MediaFastForwardIcon(), nil)
+			prioritySelect := widget.NewSelect(nil, nil)
+			upBtn := widget.NewButtonWithIcon("", theme.MoveUpIcon(), nil)
+			downBtn := widget.NewButtonWithIcon("", theme.MoveDownIcon(), nil)
+			buttons := container.NewHBox(pauseBtn, cancelBtn, retryBtn, folderBtn, startBtn, prioritySelect, upBtn, downBtn)
+
+			info := container.NewVBox(
+				container.NewBorder(nil, nil, nil, badge, title),
+				widget.NewProgressBar(),
+				detail,
+			)
+			return widget.NewCard("", "", container.NewBorder(nil, nil, container.NewCenter(thumb), buttons, info))
+		},
+		func(i int, o fyne.CanvasObject) {
+			mu.Lock()
+			if i >= len(jobs) {
+				mu.Unlock()
+				return
+			}
+			job := jobs[i]
+			last := len(jobs) - 1
+			mu.Unlock()
+
+			border := o.(*widget.Card).Content.(*fyne.Container)
+
+			// [0]=Info(Center), [1]=Thumb(Left), [2]=Buttons(Right)
+			info := border.Objects[0].(*fyne.Container)
+			thumb := border.Objects[1].(*fyne.Container).Objects[0].(*canvas.Image)
+			buttons := border.Objects[2].(*fyne.Container)
+
+			header := info.Objects[0].(*fyne.Container)
+			header.Objects[0].(*widget.Label).SetText(job.Title)
+			badge := header.Objects[1].(*widget.Label)
+			badge.Importance = stateImportance(job.State)
+			badge.SetText(stateText(job.State))
+
+			bar := info.Objects[1].(*widget.ProgressBar)
+			bar.SetValue(job.Progress.Percent)
+
+			detail := info.Objects[2].(*widget.Label)
+			switch {
+			case job.State == queue.StateFailed:
+				if hint := errorHint(job.ErrKind); hint != "" {
+					detail.SetText(hint)
+				} else {
+					detail.SetText(job.Err)
+				}
+			case job.State.Active():
+				detail.SetText(formatProgressDetail(job.Progress))
+			case job.Scheduled(time.Now()):
+				detail.SetText(fmt.Sprintf(locales.Get("queue_scheduled"), formatStartAt(job.StartAt)))
+			default:
+				detail.SetText("")
+			}
+
+			if res := thumbnailFor(job.ThumbnailURL); thumb.Resource != res {
+				thumb.Resource = res
+				thumb.Refresh()
+			}
+
+			pauseBtn := buttons.Objects[0].(*widget.Button)
+			cancelBtn := buttons.Objects[1].(*widget.Button)
+			retryBtn := buttons.Objects[2].(*widget.Button)
+			folderBtn := buttons.Objects[3].(*widget.Button)
+
+			if job.State == queue.StatePaused {
+				pauseBtn.SetIcon(theme.MediaPlayIcon())
+				pauseBtn.OnTapped = func() { ctx.Queue.Resume(job.ID) }
+			} else {
+				pauseBtn.SetIcon(theme.MediaPauseIcon())
+				pauseBtn.OnTapped = func() { ctx.Queue.Pause(job.ID) }
+			}
+			setEnabled(pauseBtn, !job.State.Finished())
+			cancelBtn.OnTapped = func() { ctx.Queue.Cancel(job.ID) }
+			setEnabled(cancelBtn, !job.State.Finished())
+			retryBtn.OnTapped = func() { ctx.Queue.Retry(job.ID) }
+			setEnabled(retryBtn, job.State == queue.StateFailed || job.State == queue.StateCancelled)
+			folderBtn.OnTapped = func() { utils.OpenFolder(job.Config.OutputPath) }
+			// Only scheduled jobs can be started early
+			startBtn := buttons.Objects[4].(*widget.Button)
+			startBtn.OnTapped = func() { ctx.Queue.SetStartAt(job.ID, time.Time{}) }
+			if job.Scheduled(time.Now()) {
+				startBtn.Show()
+			} else {
+				startBtn.Hide()
+			}
+
+			// Priority and position only matter until a job starts
+			prioritySelect := buttons.Objects[5].(*widget.Select)
+			prioritySelect.OnChanged = nil
+			prioritySelect.Options = priorityOptions()
+			prioritySelect.SetSelectedIndex(job.Priority - queue.PriorityLow)
+			prioritySelect.OnChanged = func(string) {
+				ctx.Queue.SetPriority(job.ID, prioritySelect.SelectedIndex()+queue.PriorityLow)
+			}
+			setEnabled(prioritySelect, job.State == queue.StateQueued || job.State == queue.StatePaused)
+			upBtn := buttons.Objects[6].(*widget.Button)
+			downBtn := buttons.Objects[7].(*widget.Button)
+			upBtn.OnTapped = func() { ctx.Queue.Move(job.ID, i-1) }
+			setEnabled(upBtn, i > 0)
+			downBtn.OnTapped = func() { ctx.Queue.Move(job.ID, i+1) }
+			setEnabled(downBtn, i < last)
+		},
+	)
+
+	clearBtn := widget.NewButtonWithIcon("", theme.DeleteIcon(), func() {
+		ctx.Queue.ClearFinished()
+		mu.Lock()
+		dirty = true
+		mu.Unlock()
+	})
+	summary := widget.NewLabel("")
+
+	ctx.Queue.Subscribe(func(queue.Job) {
+		mu.Lock()
+		dirty = true
+		mu.Unlock()
+	})
+
+	// Progress updates arrive far faster than the list needs redrawing.
+	// Redraw at least once a minute for the window status and start times.
+	go func() {
+		ticker := time.NewTicker(250 * time.Millisecond)
+		lastDraw := time.Now()
+		for range ticker.C {
+			mu.Lock()
+			if time.Since(lastDraw) >= time.Minute {
+				dirty = true
+			}
+			if !dirty {
+				mu.Unlock()
+				continue
+			}
+			dirty = false
+			mu.Unlock()
+			lastDraw = time.Now()
+
+			latest := ctx.Queue.Jobs()
+			active, waiting := 0, 0
+			for _, j := range latest {
+				if j.State.Active() {
+					active++
+				} else if j.State == queue.StateQueued {
+					waiting++
+				}
+			}
+			mu.Lock()
+			jobs = latest
+			mu.Unlock()
+
+			text := fmt.Sprintf(locales.Get("queue_summary"), active, waiting)
+			if status := windowStatus(ctx.Queue.Policy()); status != "" {
+				text += " • " + status
+			}
+			summary.SetText(text)
+			if len(latest) == 0 {
+				emptyLabel.Show()
+			} else {
+				emptyLabel.Hide()
+			}
+			list.Refresh()
+		}
+	}()
+
+	toolbar := container.NewBorder(nil, nil, nil, clearBtn, summary)
+	content := container.NewBorder(container.NewPadded(toolbar), nil, nil, nil, container.NewStack(list, container.NewCenter(emptyLabel)))
+
+	updateText := func() {
+		clearBtn.SetText(locales.Get("queue_clear"))
+		emptyLabel.SetText(locales.Get("queue_empty"))
+		mu.Lock()
+		dirty = true
+		mu.Unlock()
+	}
+
+	return content, updateText
+}
+
+// windowStatus describes what the download windows and speed limit
+// currently do, or "" at full speed
+func windowStatus(p queue.Policy) string {
+	now := time.Now()
+	allowed, rate := p.At(now)
+	until := formatStartAt(p.NextChange(now))
+	switch {
+	case !allowed:
+		return fmt.Sprintf(locales.Get("window_paused"), until)
+	case rate > 0 && rate != p.Limit:
+		return fmt.Sprintf(locales.Get("window_throttled"), utils.FormatBytes(rate), until)
+	case rate > 0:
+		return fmt.Sprintf(locales.Get("rate_limited"), utils.FormatBytes(rate))
+	}
+	return ""
+}
+
+func setEnabled(w fyne.Disableable, enabled bool) {
+	if enabled {
+		w.Enable()
+	} else {
+		w.Disable()
+	}
+}
+
+// priorityOptions returns the localized priorities from low to high
+func priorityOptions() []string {
+	return []string{locales.Get("priority_low"), locales.Get("priority_normal"), locales.Get("priority_high")}
+}
+
+// stateText returns the localized badge text for a job state
+func stateText(s queue.State) string {
+	return locales.Get("state_" + string(s))
+}
+
+func stateImportance(s queue.State) widget.Importance {
+	switch s {
+	case queue.StateDone:
+		return widget.SuccessImportance
+	case queue.StateFailed:
+		return widget.DangerImportance
+	case queue.StatePaused:
+		return widget.WarningImportance
+	case queue.StateCancelled, queue.StateQueued:
+		return widget.LowImportance
+	}
+	return widget.HighImportance
+}
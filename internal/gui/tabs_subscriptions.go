@@ -0,0 +1,259 @@
+package gui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gotube/internal/locales"
+	"gotube/internal/models"
+	"gotube/internal/subscriptions"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// Check intervals offered in the form, in minutes
+var subscriptionIntervals = []struct {
+	label   string
+	minutes int
+}{{"1h", 60}, {"6h", 360}, {"12h", 720}, {"24h", 1440}}
+
+func intervalLabel(minutes int) string {
+	for _, i := range subscriptionIntervals {
+		if i.minutes == minutes {
+			return i.label
+		}
+	}
+	return (time.Duration(minutes) * time.Minute).String()
+}
+
+// Returns: Content, UpdateFunc
+func buildSubscriptionsTab(ctx *AppContext) (fyne.CanvasObject, func()) {
+	var mu sync.Mutex
+	var subs []models.Subscription
+
+	// Form
+	urlEntry := widget.NewEntry()
+	formatSelect, detailSelect := createFormatSelectors()
+
+	pathEntry := widget.NewEntry()
+	pathEntry.SetText(ctx.Settings.LastSavePath)
+	pathEntry.Disable()
+	pathBtn := widget.NewButtonWithIcon("", theme.FolderOpenIcon(), func() {
+		dialog.ShowFolderOpen(func(uri fyne.ListableURI, err error) {
+			if uri != nil {
+				pathEntry.SetText(uri.Path())
+			}
+		}, ctx.Win)
+	})
+
+	var intervalLabels []string
+	for _, i := range subscriptionIntervals {
+		intervalLabels = append(intervalLabels, i.label)
+	}
+	intervalSelect := widget.NewSelect(intervalLabels, nil)
+	intervalSelect.Selected = intervalLabel(subscriptions.DefaultInterval)
+	dateEntry := widget.NewEntry()
+	dateEntry.SetPlaceHolder("20240101")
+	maxEntry := widget.NewEntry()
+	maxEntry.SetPlaceHolder("0")
+
+	intervalLabelW := widget.NewLabel("")
+	dateLabel := widget.NewLabel("")
+	maxLabel := widget.NewLabel("")
+	hintLabel := widget.NewLabel("")
+	hintLabel.Wrapping = fyne.TextWrapWord
+	emptyLabel := widget.NewLabel("")
+	emptyLabel.Alignment = fyne.TextAlignCenter
+
+	// List
+	list := widget.NewList(
+		func() int {
+			mu.Lock()
+			defer mu.Unlock()
+			return len(subs)
+		},
+		func() fyne.CanvasObject {
+			title := widget.NewLabel("Title")
+			title.TextStyle = fyne.TextStyle{Bold: true}
+			title.Truncation = fyne.TextTruncateEllipsis
+			detail := widget.NewLabel("")
+			detail.Truncation = fyne.TextTruncateEllipsis
+			enabled := widget.NewCheck("", nil)
+			checkBtn := widget.NewButtonWithIcon("", theme.ViewRefreshIcon(), nil)
+			deleteBtn := widget.NewButtonWithIcon("", theme.DeleteIcon(), nil)
+			buttons := container.NewHBox(enabled, checkBtn, deleteBtn)
+			return widget.NewCard("", "", container.NewBorder(nil, nil, nil, buttons, container.NewVBox(title, detail)))
+		},
+		nil,
+	)
+
+	reload := func() {
+		loaded, err := ctx.DB.ListSubscriptions()
+		if err != nil {
+			ctx.Logger.Write("Could not load subscriptions: " + err.Error())
+		}
+		mu.Lock()
+		subs = loaded
+		mu.Unlock()
+		if len(loaded) == 0 {
+			emptyLabel.Show()
+		} else {
+			emptyLabel.Hide()
+		}
+		list.Refresh()
+	}
+
+	checkNow := func(sub models.Subscription) {
+		go func() {
+			if _, err := ctx.Subs.Check(context.Background(), sub); err != nil {
+				dialog.ShowError(err, ctx.Win)
+			}
+		}()
+	}
+
+	list.UpdateItem = func(i int, o fyne.CanvasObject) {
+		mu.Lock()
+		if i >= len(subs) {
+			mu.Unlock()
+			return
+		}
+		sub := subs[i]
+		mu.Unlock()
+
+		card := o.(*widget.Card)
+		border := card.Content.(*fyne.Container)
+		// [0]=Labels, [1]=Buttons(Right)
+		labels := border.Objects[0].(*fyne.Container)
+		buttons := border.Objects[1].(*fyne.Container)
+
+		title := sub.Title
+		if title == "" {
+			title = sub.URL
+		}
+		labels.Objects[0].(*widget.Label).SetText(title)
+		checked := locales.Get("subscr_never")
+		if sub.LastChecked > 0 {
+			checked = time.Unix(sub.LastChecked, 0).Format("2006-01-02 15:04")
+		}
+		labels.Objects[1].(*widget.Label).SetText(fmt.Sprintf(locales.Get("subscr_detail"), intervalLabel(sub.Interval), checked))
+
+		enabled := buttons.Objects[0].(*widget.Check)
+		enabled.OnChanged = nil
+		enabled.SetChecked(sub.Enabled)
+		enabled.SetText(locales.Get("subscr_enabled"))
+		enabled.OnChanged = func(on bool) {
+			sub.Enabled = on
+			if err := ctx.DB.SaveSubscription(&sub); err != nil {
+				dialog.ShowError(err, ctx.Win)
+			}
+			reload()
+		}
+		buttons.Objects[1].(*widget.Button).OnTapped = func() { checkNow(sub) }
+		buttons.Objects[2].(*widget.Button).OnTapped = func() {
+			dialog.ShowConfirm(locales.Get("subscr_delete_title"), locales.Get("subscr_delete_msg"), func(ok bool) {
+				if ok {
+					ctx.DB.DeleteSubscription(sub.ID)
+					reload()
+				}
+			}, ctx.Win)
+		}
+	}
+
+	ctx.Subs.OnChecked = func(models.Subscription, int, error) { reload() }
+
+	addBtn := widget.NewButtonWithIcon("", theme.ContentAddIcon(), func() {
+		maxItems := 0
+		if s := strings.TrimSpace(maxEntry.Text); s != "" {
+			n, err := strconv.Atoi(s)
+			if err != nil || n < 0 {
+				dialog.ShowError(errors.New(locales.Get("invalid_number")), ctx.Win)
+				return
+			}
+			maxItems = n
+		}
+		interval := subscriptions.DefaultInterval
+		for _, i := range subscriptionIntervals {
+			if i.label == intervalSelect.Selected {
+				interval = i.minutes
+			}
+		}
+		mode := "Video"
+		if formatSelect.Selected == locales.Get("format_audio") {
+			mode = "Audio"
+		}
+
+		sub := models.Subscription{
+			URL: strings.TrimSpace(urlEntry.Text),
+			Config: models.DownloadConfig{
+				OutputPath:     pathEntry.Text,
+				DownloadMode:   mode,
+				Quality:        detailSelect.Selected,
+				Client:         ctx.Settings.ClientSpoof,
+				CookiesPath:    ctx.Settings.CookiesPath,
+				Prefs:          ctx.Settings.FormatPrefs,
+				OutputTemplate: ctx.Settings.OutputTemplate,
+			},
+			Interval:  interval,
+			DateAfter: strings.TrimSpace(dateEntry.Text),
+			MaxItems:  maxItems,
+			Enabled:   true,
+		}
+		if err := subscriptions.Validate(sub); err != nil {
+			if errors.Is(err, subscriptions.ErrBadDate) {
+				err = errors.New(locales.Get("subscr_err_date"))
+			}
+			dialog.ShowError(err, ctx.Win)
+			return
+		}
+		if err := ctx.DB.SaveSubscription(&sub); err != nil {
+			dialog.ShowError(err, ctx.Win)
+			return
+		}
+		urlEntry.SetText("")
+		reload()
+		checkNow(sub)
+	})
+	addBtn.Importance = widget.HighImportance
+
+	form := container.NewVBox(
+		urlEntry,
+		container.NewGridWithColumns(2, formatSelect, detailSelect),
+		container.NewBorder(nil, nil, nil, pathBtn, pathEntry),
+		container.NewGridWithColumns(2, intervalLabelW, intervalSelect),
+		container.NewGridWithColumns(2, dateLabel, dateEntry),
+		container.NewGridWithColumns(2, maxLabel, maxEntry),
+		hintLabel,
+		addBtn,
+	)
+	addItem := widget.NewAccordionItem("", form)
+	addItem.Open = true
+	accordion := widget.NewAccordion(addItem)
+
+	content := container.NewBorder(container.NewPadded(accordion), nil, nil, nil, container.NewStack(list, emptyLabel))
+
+	updateText := func() {
+		urlEntry.SetPlaceHolder(locales.Get("subscr_placeholder"))
+		intervalLabelW.SetText(locales.Get("subscr_interval"))
+		dateLabel.SetText(locales.Get("subscr_date_after"))
+		maxLabel.SetText(locales.Get("subscr_max_items"))
+		hintLabel.SetText(locales.Get("subscr_hint"))
+		emptyLabel.SetText(locales.Get("subscr_empty"))
+		addBtn.SetText(locales.Get("subscr_add"))
+		addItem.Title = locales.Get("subscr_add")
+		accordion.Refresh()
+
+		formatSelect.Options = []string{locales.Get("format_video"), locales.Get("format_audio")}
+		formatSelect.Selected = locales.Get("format_video")
+		formatSelect.Refresh()
+		reload()
+	}
+	return content, updateText
+}
@@ -0,0 +1,112 @@
+package gui
+
+import (
+	"errors"
+	"gotube/internal/downloader"
+	"gotube/internal/locales"
+	"gotube/internal/models"
+	"slices"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+var templateErrorKeys = map[error]string{
+	downloader.ErrTemplateEmpty:    "template_err_empty",
+	downloader.ErrTemplateAbsolute: "template_err_absolute",
+	downloader.ErrTemplateParent:   "template_err_parent",
+	downloader.ErrTemplateNoExt:    "template_err_ext",
+}
+
+// buildTemplateEditor edits ctx.Settings.OutputTemplate with presets and a
+// preview. preview returns the metadata and extension to render; with nil
+// metadata a sample video is shown. Returns the content, a func to refresh
+// the preview and one to re-apply the localized texts.
+func buildTemplateEditor(ctx *AppContext, preview func() (*models.VideoMetadata, string)) (fyne.CanvasObject, func(), func()) {
+	presetSelect := widget.NewSelect(nil, nil)
+	presets := func() {
+		presetSelect.Options = append(slices.Clone(downloader.TemplatePresets), ctx.Settings.OutputTemplates...)
+		presetSelect.Refresh()
+	}
+	presets()
+
+	entry := widget.NewEntry()
+	entry.SetText(ctx.Settings.OutputTemplate)
+	if entry.Text == "" {
+		entry.SetText(downloader.DefaultOutputTemplate)
+	}
+	entry.Validator = func(s string) error {
+		if err := downloader.ValidateTemplate(s); err != nil {
+			for target, key := range templateErrorKeys {
+				if errors.Is(err, target) {
+					return errors.New(locales.Get(key))
+				}
+			}
+			return err
+		}
+		return nil
+	}
+	previewLabel := widget.NewLabel("")
+	previewLabel.Wrapping = fyne.TextWrapBreak
+
+	refresh := func() {
+		meta, ext := preview()
+		if meta == nil {
+			meta = &models.VideoMetadata{ID: "dQw4w9WgXcQ", Title: "Video Title", Uploader: "Channel", Channel: "Channel",
+				UploadDate: time.Now().Format("20060102")}
+		}
+		tmpl := entry.Text
+		if downloader.ValidateTemplate(tmpl) != nil {
+			tmpl = downloader.DefaultOutputTemplate
+		}
+		previewLabel.SetText(locales.Get("template_preview") + " " + downloader.RenderTemplate(tmpl, downloader.TemplateFields(meta, ext)))
+	}
+	entry.OnChanged = func(s string) {
+		if downloader.ValidateTemplate(s) == nil {
+			ctx.Settings.OutputTemplate = s
+			ctx.DB.SaveSetting("OutputTemplate", s)
+		}
+		refresh()
+	}
+	presetSelect.OnChanged = func(s string) {
+		if s != "" {
+			entry.SetText(s)
+		}
+	}
+
+	saveBtn := widget.NewButtonWithIcon("", theme.DocumentSaveIcon(), func() {
+		if downloader.ValidateTemplate(entry.Text) != nil || slices.Contains(presetSelect.Options, entry.Text) {
+			return
+		}
+		ctx.Settings.OutputTemplates = append(ctx.Settings.OutputTemplates, entry.Text)
+		ctx.DB.SaveListSetting("OutputTemplates", ctx.Settings.OutputTemplates)
+		presets()
+	})
+	// Only the user's own presets can be deleted
+	deleteBtn := widget.NewButtonWithIcon("", theme.DeleteIcon(), func() {
+		i := slices.Index(ctx.Settings.OutputTemplates, presetSelect.Selected)
+		if i < 0 {
+			return
+		}
+		ctx.Settings.OutputTemplates = slices.Delete(ctx.Settings.OutputTemplates, i, i+1)
+		ctx.DB.SaveListSetting("OutputTemplates", ctx.Settings.OutputTemplates)
+		presetSelect.ClearSelected()
+		presets()
+	})
+
+	content := container.NewVBox(
+		container.NewBorder(nil, nil, nil, container.NewHBox(saveBtn, deleteBtn), presetSelect),
+		entry,
+		previewLabel,
+	)
+	updateText := func() {
+		presetSelect.PlaceHolder = locales.Get("template_presets")
+		presetSelect.Refresh()
+		refresh()
+	}
+	updateText()
+	return content, refresh, updateText
+}
@@ -1,14 +1,18 @@
 package gui
 
 import (
+	"context"
 	"fmt"
 	"gotube/internal/database"
 	"gotube/internal/downloader"
 	"gotube/internal/locales"
 	"gotube/internal/models"
+	"gotube/internal/queue"
+	"gotube/internal/subscriptions"
 	"gotube/internal/updater"
 	"gotube/internal/utils"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
@@ -30,12 +34,19 @@ type AppContext struct {
 	Win      fyne.Window
 	DB       *database.DB
 	Engine   *downloader.Engine
+	Queue    *queue.Manager
+	Subs     *subscriptions.Scheduler
 	BinMgr   *updater.BinaryManager
 	Settings models.AppSettings
 	Status   binding.String
+	Detail   binding.String
 	Progress binding.Float
 	Logger   *utils.LogBuffer
 	Console  *widget.Entry
+
+	// The Batch tab summarises many jobs, so it gets its own footer state
+	BatchStatus   binding.String
+	BatchProgress binding.Float
 }
 
 func StartApp(a fyne.App) {
@@ -48,10 +59,18 @@ func StartApp(a fyne.App) {
 	db, _ := database.InitDB()
 	binMgr := updater.NewBinaryManager()
 	engine := downloader.NewEngine(binMgr.GetYtDlpPath())
+	if yt, err := engine.Backend(downloader.BackendYtDlp); err == nil {
+		yt.(*downloader.YtDlp).SelfUpdate = binMgr.UpdateBinary
+	}
 	settings := db.LoadSettings()
 	if settings.LastSavePath == "" {
 		settings.LastSavePath, _ = os.Getwd()
 	}
+	if settings.MaxConcurrent < 1 {
+		settings.MaxConcurrent = 2
+	}
+	engine.SetRetryPolicy(downloader.RetryPolicyFromSettings(settings))
+	utils.SetNetwork(settings.Network)
 	if settings.Language != "" {
 		locales.SetLanguage(settings.Language)
 	} else {
@@ -63,46 +82,84 @@ func StartApp(a fyne.App) {
 		Win:      w,
 		DB:       db,
 		Engine:   engine,
+		Queue:    queue.NewManager(engine, db, settings.MaxConcurrent),
 		BinMgr:   binMgr,
 		Settings: settings,
 		Status:   binding.NewString(),
+		Detail:   binding.NewString(),
 		Progress: binding.NewFloat(),
 		Logger:   utils.NewLogBuffer(300),
+
+		BatchStatus:   binding.NewString(),
+		BatchProgress: binding.NewFloat(),
 	}
+	ctx.Queue.Logger = ctx.Logger
+	ctx.Queue.SetPolicy(queue.PolicyFromSettings(settings))
+	ctx.Subs = subscriptions.NewScheduler(engine, db, ctx.Queue)
+	ctx.Subs.Logger = ctx.Logger
 	ctx.Status.Set(locales.Get("ready"))
+	ctx.BatchStatus.Set(locales.Get("ready"))
 
 	// Build Tabs
-	mainTab, mainBtn, mainUpdate := buildMainTab(ctx)
-	batchTab, batchBtn, batchUpdate := buildBatchTab(ctx)
-	historyTab := buildHistoryTab(ctx)
+	mainTab, mainBtn, mainCancelBtn, mainUpdate := buildMainTab(ctx)
+	batchTab, batchBtn, batchCancelBtn, batchUpdate := buildBatchTab(ctx)
+	queueTab, queueUpdate := buildQueueTab(ctx)
+	subsTab, subsUpdate := buildSubscriptionsTab(ctx)
+	historyTab, historyUpdate := buildHistoryTab(ctx)
 	settingsTab := buildSettingsTab(ctx)
 
+	// Pick up jobs left over from the last session once the tabs are listening.
+	// If 'gotube serve' owns the saved queue, this session's queue isn't saved.
+	unlockQueue, err := database.LockQueue()
+	if err != nil {
+		unlockQueue = func() {}
+		ctx.Logger.Write("Queue won't be saved: " + err.Error())
+	} else if resumed, err := ctx.Queue.Restore(); err != nil {
+		ctx.Logger.Write("Could not restore queue: " + err.Error())
+	} else if resumed > 0 {
+		ctx.Logger.Write(fmt.Sprintf("Resuming %d queued downloads from last session", resumed))
+	}
+	subsCtx, stopSubs := context.WithCancel(context.Background())
+	go ctx.Subs.Run(subsCtx)
+
 	// Footer
 	viewLogsBtn := widget.NewButton("", func() { showLogs(ctx) })
 	statusLabel := widget.NewLabelWithData(ctx.Status)
 	statusLabel.Alignment = fyne.TextAlignCenter
 	progressContainer := container.NewPadded(widget.NewProgressBarWithData(ctx.Progress))
+	detailLabel := widget.NewLabelWithData(ctx.Detail)
+	detailLabel.Alignment = fyne.TextAlignCenter
 
-	footer1 := container.NewVBox(widget.NewSeparator(), statusLabel, progressContainer, container.NewGridWithColumns(2, viewLogsBtn, mainBtn))
+	footer1 := container.NewVBox(widget.NewSeparator(), statusLabel, progressContainer, detailLabel, container.NewGridWithColumns(3, viewLogsBtn, mainCancelBtn, mainBtn))
 
-	// Button for Batch Footer (Needs clone)
+	// Batch Footer: summary only, per-job progress lives in the Queue tab
 	viewLogsBtn2 := widget.NewButton("", func() { showLogs(ctx) })
-	footer2 := container.NewVBox(widget.NewSeparator(), statusLabel, progressContainer, container.NewGridWithColumns(2, viewLogsBtn2, batchBtn))
+	batchStatusLabel := widget.NewLabelWithData(ctx.BatchStatus)
+	batchStatusLabel.Alignment = fyne.TextAlignCenter
+	batchProgress := container.NewPadded(widget.NewProgressBarWithData(ctx.BatchProgress))
+	footer2 := container.NewVBox(widget.NewSeparator(), batchStatusLabel, batchProgress, container.NewGridWithColumns(3, viewLogsBtn2, batchCancelBtn, batchBtn))
 
 	t1Content := container.NewBorder(nil, container.NewPadded(footer1), nil, nil, mainTab)
 	t2Content := container.NewBorder(nil, container.NewPadded(footer2), nil, nil, batchTab)
 
 	t1 := container.NewTabItemWithIcon(locales.Get("tab_download"), theme.DownloadIcon(), t1Content)
 	t2 := container.NewTabItemWithIcon("Batch", theme.ListIcon(), t2Content)
+	tq := container.NewTabItemWithIcon(locales.Get("tab_queue"), theme.MenuIcon(), queueTab)
+	ts := container.NewTabItemWithIcon(locales.Get("tab_subscriptions"), theme.MediaReplayIcon(), subsTab)
 	t3 := container.NewTabItemWithIcon(locales.Get("tab_history"), theme.HistoryIcon(), historyTab)
 	t4 := container.NewTabItemWithIcon(locales.Get("tab_system"), theme.SettingsIcon(), settingsTab)
 
-	tabs := container.NewAppTabs(t1, t2, t3, t4)
+	tabs := container.NewAppTabs(t1, t2, tq, ts, t3, t4)
 
 	updateAllTexts := func() {
 		mainUpdate()
 		batchUpdate()
+		queueUpdate()
+		subsUpdate()
+		historyUpdate()
 		t1.Text = locales.Get("tab_download")
+		tq.Text = locales.Get("tab_queue")
+		ts.Text = locales.Get("tab_subscriptions")
 		t3.Text = locales.Get("tab_history")
 		t4.Text = locales.Get("tab_system")
 		viewLogsBtn.SetText(locales.Get("view_logs"))
@@ -157,6 +214,11 @@ func StartApp(a fyne.App) {
 
 	w.SetContent(tabs)
 	w.ShowAndRun()
+
+	// Stop yt-dlp but keep .part files so the queue resumes next time
+	stopSubs()
+	ctx.Queue.Shutdown()
+	unlockQueue()
 }
 
 // Helper to run the update process with UI feedback
@@ -190,6 +252,14 @@ func buildSettingsTabWithCallback(ctx *AppContext, updateFunc func()) fyne.Canva
 
 	// Dynamic labels for localization
 	langLabel := widget.NewLabel(locales.Get("language_label"))
+	parallelLabel := widget.NewLabel(locales.Get("parallel_label"))
+	parallelSelect := widget.NewSelect([]string{"1", "2", "3", "4", "5"}, func(s string) {
+		n, _ := strconv.Atoi(s)
+		ctx.Settings.MaxConcurrent = n
+		ctx.Queue.SetWorkers(n)
+		ctx.DB.SaveSetting("MaxConcurrent", s)
+	})
+	parallelSelect.Selected = strconv.Itoa(ctx.Settings.MaxConcurrent)
 	coreLabel := widget.NewLabel(locales.Get("core_label") + " " + ctx.BinMgr.GetYtDlpPath())
 	appVersionLabel := widget.NewLabel(locales.Get("app_version_label") + " " + models.AppVersion)
 
@@ -198,7 +268,10 @@ func buildSettingsTabWithCallback(ctx *AppContext, updateFunc func()) fyne.Canva
 		p := dialog.NewProgressInfinite(locales.Get("update_app_title"), locales.Get("update_core_checking"), ctx.Win)
 		p.Show()
 		go func() {
-			err := ctx.BinMgr.UpdateBinary(func(msg string) { fmt.Println(msg) })
+			backend, err := ctx.Engine.Backend(downloader.BackendYtDlp)
+			if err == nil {
+				err = backend.Update(context.Background(), func(msg string) { fmt.Println(msg) })
+			}
 			p.Hide()
 			if err != nil {
 				dialog.ShowError(err, ctx.Win)
@@ -239,27 +312,51 @@ func buildSettingsTabWithCallback(ctx *AppContext, updateFunc func()) fyne.Canva
 		}()
 	})
 
+	formatSection, formatUpdate := buildFormatSettings(ctx)
+	retrySection, retryUpdate := buildRetrySettings(ctx)
+	windowSection, windowUpdate := buildWindowSettings(ctx)
+	networkSection, networkUpdate := buildNetworkSettings(ctx)
+	sponsorSection, sponsorUpdate := buildSponsorSettings(ctx)
+
 	langSelect.OnChanged = func(s string) {
 		locales.SetLanguage(s)
 		ctx.DB.SaveSetting("Language", s)
 		// Update settings tab labels
 		langLabel.SetText(locales.Get("language_label"))
+		parallelLabel.SetText(locales.Get("parallel_label"))
 		coreLabel.SetText(locales.Get("core_label") + " " + ctx.BinMgr.GetYtDlpPath())
 		appVersionLabel.SetText(locales.Get("app_version_label") + " " + models.AppVersion)
 		updateCoreBtn.SetText(locales.Get("update_core_btn"))
 		updateAppBtn.SetText(locales.Get("update_app_btn"))
+		formatUpdate()
+		retryUpdate()
+		windowUpdate()
+		networkUpdate()
+		sponsorUpdate()
 		updateFunc()
 	}
 
-	return container.NewPadded(widget.NewCard(locales.Get("tab_system"), "", container.NewVBox(
+	return container.NewVScroll(container.NewPadded(widget.NewCard(locales.Get("tab_system"), "", container.NewVBox(
 		langLabel, langSelect,
 		widget.NewSeparator(),
+		parallelLabel, parallelSelect,
+		widget.NewSeparator(),
+		formatSection,
+		widget.NewSeparator(),
+		retrySection,
+		widget.NewSeparator(),
+		windowSection,
+		widget.NewSeparator(),
+		networkSection,
+		widget.NewSeparator(),
+		sponsorSection,
+		widget.NewSeparator(),
 		coreLabel,
 		updateCoreBtn,
 		widget.NewSeparator(),
 		appVersionLabel,
 		updateAppBtn,
-	)))
+	))))
 }
 
 func buildSettingsTab(ctx *AppContext) fyne.CanvasObject {
@@ -13,9 +13,11 @@ var en = map[string]string{
 	"playlist":     "Playlist Mode",
 	"save_to":      "Save To",
 	"adv_options":  "Advanced Options",
-	"trim_start":   "Trim Start:",
-	"trim_end":     "Trim End:",
+	"trim_label":   "Time Ranges:",
+	"trim_concat":  "Join Ranges into One File",
 	"client":       "Client:",
+	"backend":      "Backend:",
+	"backend_auto": "Auto",
 	"auth":         "Auth:",
 	"cookies":      "Load Cookies",
 	"sponsor":      "SponsorBlock",
@@ -28,7 +30,12 @@ var en = map[string]string{
 	"meta_loaded":  "Metadata loaded",
 	"success":      "Download Complete",
 	"failed":       "Failed",
-	"format_video": "Video (MP4)",
+	"cancelled":    "Cancelled",
+	"btn_cancel":   "Cancel",
+	"queued":       "Queued",
+	"batch_status": "Batch: %d/%d done, %d failed",
+	"batch_done":   "Batch complete: %d done, %d failed",
+	"format_video": "Video",
 	"format_audio": "Audio",
 	"subs_embed":   "Embed Subtitles",
 	"subs_auto":    "Auto-Generated",
@@ -42,6 +49,170 @@ var en = map[string]string{
 	"pl_select_all":  "Select All",
 	"pl_select_none": "Select None",
 
+	// Format Picker
+	"formats_btn":          "Choose Format...",
+	"formats_title":        "Choose Format",
+	"formats_selected":     "Format: %s",
+	"formats_video":        "Video:",
+	"formats_audio":        "Audio:",
+	"formats_none":         "None",
+	"formats_custom":       "Custom Selector:",
+	"formats_size":         "Estimated size: %s",
+	"formats_size_unknown": "Estimated size: unknown",
+
+	// Chapters
+	"chapters_btn":      "Chapters",
+	"chapters_all":      "Chapters: all %d",
+	"chapters_some":     "Chapters: %d of %d",
+	"chapters_title":    "Select Chapters",
+	"chapters_hint":     "Each ticked chapter is saved as its own file.",
+	"chapters_split":    "One File per Chapter",
+	"chapters_markers":  "Embed Chapter Markers",
+	"chapters_err_trim": "Pick chapters or set time ranges, not both",
+
+	// Subtitles
+	"subs_btn":        "Subtitles",
+	"subs_available":  "Subtitles: %d languages",
+	"subs_some":       "Subtitles: %s",
+	"subs_title":      "Select Subtitles",
+	"subs_filter":     "Filter languages",
+	"subs_auto_track": "auto-generated",
+	"subs_write":      "Save as Files",
+	"subs_format":     "Format:",
+
+	// SponsorBlock
+	"sponsor_title":              "SponsorBlock",
+	"sponsor_hint":               "Applies to downloads with SponsorBlock ticked. Cut segments are summarized in the log.",
+	"sponsor_remove":             "Remove",
+	"sponsor_mark":               "Mark as Chapter",
+	"sponsor_ignore":             "Ignore",
+	"sponsor_cat_sponsor":        "Sponsor",
+	"sponsor_cat_intro":          "Intermission/Intro",
+	"sponsor_cat_outro":          "Endcards/Credits",
+	"sponsor_cat_selfpromo":      "Unpaid/Self Promotion",
+	"sponsor_cat_interaction":    "Interaction Reminder",
+	"sponsor_cat_music_offtopic": "Music: Non-Music Section",
+	"sponsor_cat_preview":        "Preview/Recap",
+	"sponsor_cat_filler":         "Filler Tangent",
+
+	// Time Ranges
+	"trim_err_format":  "%s: enter ranges like 1:00-2:30, 90- or 25%%-50%%",
+	"trim_err_order":   "%s: the range ends before it starts",
+	"trim_err_long":    "%s: the range goes past the end of the video (%s)",
+	"trim_err_percent": "%s: check the URL first to use percentages",
+
+	// Output Template
+	"template_label":        "File Name",
+	"template_presets":      "Presets",
+	"template_preview":      "Preview:",
+	"template_err_empty":    "Enter a template",
+	"template_err_absolute": "Use a path relative to the save folder",
+	"template_err_parent":   "The template must not leave the save folder",
+	"template_err_ext":      "The template must contain %(ext)s",
+
+	// Archive
+	"history_downloads": "Downloads",
+	"archive_title":     "Archive",
+	"archive_force":     "Force Re-download",
+	"archive_summary":   "%d videos will be skipped",
+	"archive_clear":     "Clear Archive",
+	"archive_clear_msg": "Forget all downloaded videos? They will be downloaded again next time.",
+
+	// Queue
+	"tab_queue":             "Queue",
+	"queue_clear":           "Clear Finished",
+	"queue_empty":           "No downloads yet",
+	"queue_summary":         "%d running • %d waiting",
+	"state_queued":          "Queued",
+	"state_fetching":        "Fetching",
+	"state_downloading":     "Downloading",
+	"state_post-processing": "Processing",
+	"state_done":            "Done",
+	"state_failed":          "Failed",
+	"state_cancelled":       "Cancelled",
+	"state_paused":          "Paused",
+	"priority_low":          "Low",
+	"priority_normal":       "Normal",
+	"priority_high":         "High",
+
+	// Web UI
+	"tab_batch":             "Batch",
+	"web_token_title":       "API Token",
+	"web_token_prompt":      "Enter the API token printed by 'gotube serve'.",
+	"web_token_save":        "Connect",
+	"web_token_invalid":     "The token was not accepted.",
+	"web_batch_placeholder": "One link per line",
+	"web_batch_btn":         "Add to Queue",
+	"web_pause":             "Pause",
+	"web_resume":            "Resume",
+	"web_retry":             "Retry",
+	"web_remove":            "Remove",
+	"web_offline":           "Connection lost, reconnecting...",
+	"web_history_empty":     "No downloads yet",
+
+	// Subscriptions
+	"tab_subscriptions":   "Subscriptions",
+	"subscr_add":          "Add Subscription",
+	"subscr_placeholder":  "Channel or playlist link...",
+	"subscr_interval":     "Check Every:",
+	"subscr_date_after":   "Uploaded After (YYYYMMDD):",
+	"subscr_max_items":    "Max Videos per Check:",
+	"subscr_hint":         "Only videos uploaded from now on are downloaded. Set a maximum to also get that many of the latest ones right away.",
+	"subscr_empty":        "No subscriptions yet",
+	"subscr_detail":       "Every %s • last checked %s",
+	"subscr_never":        "never",
+	"subscr_enabled":      "Active",
+	"subscr_delete_title": "Remove Subscription",
+	"subscr_delete_msg":   "Remove this subscription? Downloaded videos are kept.",
+	"subscr_err_date":     "Enter the date as YYYYMMDD",
+
+	// Scheduling
+	"schedule_label":       "Start At:",
+	"schedule_err":         "Enter the start time as HH:MM or YYYY-MM-DD HH:MM",
+	"queue_scheduled":      "Scheduled for %s",
+	"window_title":         "Download Windows and Speed",
+	"window_ranges":        "Allowed Times:",
+	"window_mode":          "Outside Windows:",
+	"window_mode_pause":    "Pause",
+	"window_mode_throttle": "Throttle",
+	"window_rate":          "Throttle (KiB/s):",
+	"window_err":           "Enter times like 01:00-07:00, separated by commas",
+	"window_paused":        "paused until %s",
+	"window_throttled":     "limited to %s/s until %s",
+
+	// Speed Limits
+	"rate_label":   "Speed Limit:",
+	"rate_err":     "Enter a speed like 500K or 2M (bytes per second)",
+	"rate_global":  "Speed Limit, All Downloads (KiB/s, 0 = off):",
+	"rate_limited": "limited to %s/s",
+
+	// Network
+	"net_title":          "Network",
+	"net_proxy":          "Proxy:",
+	"net_source":         "Source Address:",
+	"net_ip_version":     "IP Version:",
+	"net_ca_bundle":      "Extra CA Certificates:",
+	"net_job_proxy":      "Proxy (this download):",
+	"net_job_source":     "Source Address (this download):",
+	"net_job_ip_version": "IP Version (this download):",
+	"net_job_ca_bundle":  "Extra CA Certificates (this download):",
+	"net_ip_default":     "Default",
+	"net_err_proxy":      "Enter a proxy like http://host:8080 or socks5://host:1080",
+	"net_err_source":     "Enter a local IP address",
+	"net_err_ca":         "Choose a PEM file that exists",
+
+	// Download Errors
+	"err_rate_limited":    "YouTube is rate limiting this connection. Wait a while or load cookies from a logged-in browser.",
+	"err_auth_required":   "This video requires signing in (age restriction or bot check). Load cookies from a logged-in browser.",
+	"err_geo_blocked":     "This video is not available in your country. A proxy or VPN may help.",
+	"err_unavailable":     "This video is private, removed or otherwise unavailable.",
+	"err_members_only":    "This video is for channel members only. Load cookies from an account with a membership.",
+	"err_not_started":     "This premiere or live stream has not started yet. Try again once it is live.",
+	"err_network":         "Network problem while downloading. Check your connection and retry.",
+	"err_ffmpeg_missing":  "FFmpeg is missing. Install FFmpeg to merge formats and convert audio.",
+	"err_disk_full":       "The disk is full. Free up space or choose another folder.",
+	"err_unsupported_url": "This link is not supported. Check that it is a valid video or playlist URL.",
+
 	// App Updater
 	"update_available":     "Update Available",
 	"update_version_msg":   "Version %s is available. Update now?",
@@ -58,12 +229,38 @@ var en = map[string]string{
 	"update_core_checking": "Checking GitHub...",
 	"update_core_success":  "Core updated.",
 	"language_label":       "Language",
+	"parallel_label":       "Parallel Downloads",
+	"invalid_number":       "Invalid number",
 	"core_label":           "Core:",
 	"app_version_label":    "App Version:",
 	"logs_title":           "Logs",
 	"logs_close":           "Close",
 	"btn_yes":              "Yes",
 	"btn_no":               "No",
+
+	// Format Preferences
+	"prefs_title":         "Formats",
+	"prefs_auto":          "Auto",
+	"prefs_container":     "Container:",
+	"prefs_vcodec":        "Video Codec:",
+	"prefs_acodec":        "Audio Codec:",
+	"prefs_dynamic_range": "Dynamic Range:",
+	"prefs_max_fps":       "Max FPS:",
+
+	// Retry Policy
+	"retry_title":                 "Retries",
+	"retry_attempts":              "Max Attempts:",
+	"retry_base_delay":            "Base Delay (s):",
+	"retry_max_delay":             "Max Delay (s):",
+	"retry_factor":                "Backoff Factor:",
+	"retry_jitter":                "Jitter (%):",
+	"retry_inherit":               "as above",
+	"retry_unknown_attempts":      "Other Errors, Max Attempts:",
+	"retry_unknown_delay":         "Other Errors, Base Delay (s):",
+	"retry_rate_limited_attempts": "Rate Limiting, Max Attempts:",
+	"retry_rate_limited_delay":    "Rate Limiting, Base Delay (s):",
+	"retry_network_attempts":      "Network Errors, Max Attempts:",
+	"retry_network_delay":         "Network Errors, Base Delay (s):",
 }
 
 var de = map[string]string{
@@ -77,9 +274,11 @@ var de = map[string]string{
 	"playlist":     "Playlist-Modus",
 	"save_to":      "Speichern unter",
 	"adv_options":  "Erweiterte Optionen",
-	"trim_start":   "Startzeit:",
-	"trim_end":     "Endzeit:",
+	"trim_label":   "Zeitbereiche:",
+	"trim_concat":  "Bereiche zu einer Datei verbinden",
 	"client":       "Klient:",
+	"backend":      "Backend:",
+	"backend_auto": "Automatisch",
 	"auth":         "Auth:",
 	"cookies":      "Cookies laden",
 	"sponsor":      "SponsorBlock",
@@ -92,7 +291,12 @@ var de = map[string]string{
 	"meta_loaded":  "Metadaten geladen",
 	"success":      "Download abgeschlossen",
 	"failed":       "Fehlgeschlagen",
-	"format_video": "Video (MP4)",
+	"cancelled":    "Abgebrochen",
+	"btn_cancel":   "Abbrechen",
+	"queued":       "In Warteschlange",
+	"batch_status": "Stapel: %d/%d fertig, %d fehlgeschlagen",
+	"batch_done":   "Stapel abgeschlossen: %d fertig, %d fehlgeschlagen",
+	"format_video": "Video",
 	"format_audio": "Audio",
 	"subs_embed":   "Untertitel einbetten",
 	"subs_auto":    "Automatisch generiert",
@@ -106,6 +310,170 @@ var de = map[string]string{
 	"pl_select_all":  "Alle",
 	"pl_select_none": "Keine",
 
+	// Format Picker
+	"formats_btn":          "Format wählen...",
+	"formats_title":        "Format wählen",
+	"formats_selected":     "Format: %s",
+	"formats_video":        "Video:",
+	"formats_audio":        "Audio:",
+	"formats_none":         "Keines",
+	"formats_custom":       "Eigener Selektor:",
+	"formats_size":         "Geschätzte Größe: %s",
+	"formats_size_unknown": "Geschätzte Größe: unbekannt",
+
+	// Chapters
+	"chapters_btn":      "Kapitel",
+	"chapters_all":      "Kapitel: alle %d",
+	"chapters_some":     "Kapitel: %d von %d",
+	"chapters_title":    "Kapitel auswählen",
+	"chapters_hint":     "Jedes gewählte Kapitel wird als eigene Datei gespeichert.",
+	"chapters_split":    "Eine Datei pro Kapitel",
+	"chapters_markers":  "Kapitelmarken einbetten",
+	"chapters_err_trim": "Entweder Kapitel wählen oder Zeitbereiche festlegen, nicht beides",
+
+	// Subtitles
+	"subs_btn":        "Untertitel",
+	"subs_available":  "Untertitel: %d Sprachen",
+	"subs_some":       "Untertitel: %s",
+	"subs_title":      "Untertitel auswählen",
+	"subs_filter":     "Sprachen filtern",
+	"subs_auto_track": "automatisch generiert",
+	"subs_write":      "Als Dateien speichern",
+	"subs_format":     "Format:",
+
+	// SponsorBlock
+	"sponsor_title":              "SponsorBlock",
+	"sponsor_hint":               "Gilt für Downloads mit aktiviertem SponsorBlock. Entfernte Abschnitte werden im Protokoll zusammengefasst.",
+	"sponsor_remove":             "Entfernen",
+	"sponsor_mark":               "Als Kapitel markieren",
+	"sponsor_ignore":             "Ignorieren",
+	"sponsor_cat_sponsor":        "Sponsor",
+	"sponsor_cat_intro":          "Pause/Intro",
+	"sponsor_cat_outro":          "Abspann/Endkarten",
+	"sponsor_cat_selfpromo":      "Unbezahlte Werbung/Eigenwerbung",
+	"sponsor_cat_interaction":    "Interaktionserinnerung",
+	"sponsor_cat_music_offtopic": "Musik: Nicht-Musik-Abschnitt",
+	"sponsor_cat_preview":        "Vorschau/Rückblick",
+	"sponsor_cat_filler":         "Füllmaterial/Abschweifung",
+
+	// Time Ranges
+	"trim_err_format":  "%s: Bereiche wie 1:00-2:30, 90- oder 25%%-50%% eingeben",
+	"trim_err_order":   "%s: Der Bereich endet vor seinem Beginn",
+	"trim_err_long":    "%s: Der Bereich reicht über das Videoende hinaus (%s)",
+	"trim_err_percent": "%s: Für Prozentangaben zuerst die URL prüfen",
+
+	// Output Template
+	"template_label":        "Dateiname",
+	"template_presets":      "Vorlagen",
+	"template_preview":      "Vorschau:",
+	"template_err_empty":    "Vorlage eingeben",
+	"template_err_absolute": "Pfad relativ zum Speicherordner angeben",
+	"template_err_parent":   "Die Vorlage darf den Speicherordner nicht verlassen",
+	"template_err_ext":      "Die Vorlage muss %(ext)s enthalten",
+
+	// Archive
+	"history_downloads": "Downloads",
+	"archive_title":     "Archiv",
+	"archive_force":     "Erneut herunterladen",
+	"archive_summary":   "%d Videos werden übersprungen",
+	"archive_clear":     "Archiv leeren",
+	"archive_clear_msg": "Alle heruntergeladenen Videos vergessen? Sie werden beim nächsten Mal erneut heruntergeladen.",
+
+	// Queue
+	"tab_queue":             "Warteschlange",
+	"queue_clear":           "Erledigte entfernen",
+	"queue_empty":           "Noch keine Downloads",
+	"queue_summary":         "%d aktiv • %d wartend",
+	"state_queued":          "Wartend",
+	"state_fetching":        "Lade Infos",
+	"state_downloading":     "Lädt",
+	"state_post-processing": "Verarbeitung",
+	"state_done":            "Fertig",
+	"state_failed":          "Fehlgeschlagen",
+	"state_cancelled":       "Abgebrochen",
+	"state_paused":          "Pausiert",
+	"priority_low":          "Niedrig",
+	"priority_normal":       "Normal",
+	"priority_high":         "Hoch",
+
+	// Web UI
+	"tab_batch":             "Stapel",
+	"web_token_title":       "API-Token",
+	"web_token_prompt":      "Geben Sie das von 'gotube serve' ausgegebene API-Token ein.",
+	"web_token_save":        "Verbinden",
+	"web_token_invalid":     "Das Token wurde nicht akzeptiert.",
+	"web_batch_placeholder": "Ein Link pro Zeile",
+	"web_batch_btn":         "Zur Warteschlange",
+	"web_pause":             "Pausieren",
+	"web_resume":            "Fortsetzen",
+	"web_retry":             "Wiederholen",
+	"web_remove":            "Entfernen",
+	"web_offline":           "Verbindung unterbrochen, verbinde neu...",
+	"web_history_empty":     "Noch keine Downloads",
+
+	// Subscriptions
+	"tab_subscriptions":   "Abos",
+	"subscr_add":          "Abo hinzufügen",
+	"subscr_placeholder":  "Kanal- oder Playlist-Link...",
+	"subscr_interval":     "Prüfen alle:",
+	"subscr_date_after":   "Hochgeladen nach (JJJJMMTT):",
+	"subscr_max_items":    "Max. Videos pro Prüfung:",
+	"subscr_hint":         "Es werden nur ab jetzt hochgeladene Videos geladen. Mit einem Maximum werden auch so viele der neuesten sofort geladen.",
+	"subscr_empty":        "Noch keine Abos",
+	"subscr_detail":       "Alle %s • zuletzt geprüft %s",
+	"subscr_never":        "nie",
+	"subscr_enabled":      "Aktiv",
+	"subscr_delete_title": "Abo entfernen",
+	"subscr_delete_msg":   "Dieses Abo entfernen? Heruntergeladene Videos bleiben erhalten.",
+	"subscr_err_date":     "Datum als JJJJMMTT eingeben",
+
+	// Scheduling
+	"schedule_label":       "Starten um:",
+	"schedule_err":         "Startzeit als HH:MM oder JJJJ-MM-TT HH:MM eingeben",
+	"queue_scheduled":      "Geplant für %s",
+	"window_title":         "Zeitfenster und Geschwindigkeit",
+	"window_ranges":        "Erlaubte Zeiten:",
+	"window_mode":          "Außerhalb der Fenster:",
+	"window_mode_pause":    "Pausieren",
+	"window_mode_throttle": "Drosseln",
+	"window_rate":          "Drosselung (KiB/s):",
+	"window_err":           "Zeiten wie 01:00-07:00 eingeben, durch Kommas getrennt",
+	"window_paused":        "pausiert bis %s",
+	"window_throttled":     "begrenzt auf %s/s bis %s",
+
+	// Speed Limits
+	"rate_label":   "Geschwindigkeitslimit:",
+	"rate_err":     "Geschwindigkeit wie 500K oder 2M eingeben (Bytes pro Sekunde)",
+	"rate_global":  "Limit für alle Downloads (KiB/s, 0 = aus):",
+	"rate_limited": "begrenzt auf %s/s",
+
+	// Network
+	"net_title":          "Netzwerk",
+	"net_proxy":          "Proxy:",
+	"net_source":         "Quelladresse:",
+	"net_ip_version":     "IP-Version:",
+	"net_ca_bundle":      "Zusätzliche CA-Zertifikate:",
+	"net_job_proxy":      "Proxy (dieser Download):",
+	"net_job_source":     "Quelladresse (dieser Download):",
+	"net_job_ip_version": "IP-Version (dieser Download):",
+	"net_job_ca_bundle":  "Zusätzliche CA-Zertifikate (dieser Download):",
+	"net_ip_default":     "Standard",
+	"net_err_proxy":      "Proxy wie http://host:8080 oder socks5://host:1080 eingeben",
+	"net_err_source":     "Eine lokale IP-Adresse eingeben",
+	"net_err_ca":         "Eine vorhandene PEM-Datei wählen",
+
+	// Download Errors
+	"err_rate_limited":    "YouTube drosselt diese Verbindung. Warten Sie etwas oder laden Sie Cookies aus einem angemeldeten Browser.",
+	"err_auth_required":   "Dieses Video erfordert eine Anmeldung (Altersbeschränkung oder Bot-Prüfung). Laden Sie Cookies aus einem angemeldeten Browser.",
+	"err_geo_blocked":     "Dieses Video ist in Ihrem Land nicht verfügbar. Ein Proxy oder VPN kann helfen.",
+	"err_unavailable":     "Dieses Video ist privat, entfernt oder anderweitig nicht verfügbar.",
+	"err_members_only":    "Dieses Video ist nur für Kanalmitglieder. Laden Sie Cookies eines Kontos mit Mitgliedschaft.",
+	"err_not_started":     "Diese Premiere oder dieser Livestream hat noch nicht begonnen. Versuchen Sie es später erneut.",
+	"err_network":         "Netzwerkproblem beim Herunterladen. Prüfen Sie Ihre Verbindung und versuchen Sie es erneut.",
+	"err_ffmpeg_missing":  "FFmpeg fehlt. Installieren Sie FFmpeg, um Formate zusammenzuführen und Audio zu konvertieren.",
+	"err_disk_full":       "Der Datenträger ist voll. Geben Sie Speicherplatz frei oder wählen Sie einen anderen Ordner.",
+	"err_unsupported_url": "Dieser Link wird nicht unterstützt. Prüfen Sie, ob es eine gültige Video- oder Playlist-URL ist.",
+
 	// App Updater
 	"update_available":     "Update verfügbar",
 	"update_version_msg":   "Version %s ist verfügbar. Jetzt aktualisieren?",
@@ -122,12 +490,38 @@ var de = map[string]string{
 	"update_core_checking": "Prüfe GitHub...",
 	"update_core_success":  "Core aktualisiert.",
 	"language_label":       "Sprache",
+	"parallel_label":       "Parallele Downloads",
+	"invalid_number":       "Ungültige Zahl",
 	"core_label":           "Core:",
 	"app_version_label":    "App-Version:",
 	"logs_title":           "Protokolle",
 	"logs_close":           "Schließen",
 	"btn_yes":              "Ja",
 	"btn_no":               "Nein",
+
+	// Format Preferences
+	"prefs_title":         "Formate",
+	"prefs_auto":          "Automatisch",
+	"prefs_container":     "Container:",
+	"prefs_vcodec":        "Video-Codec:",
+	"prefs_acodec":        "Audio-Codec:",
+	"prefs_dynamic_range": "Dynamikumfang:",
+	"prefs_max_fps":       "Max. FPS:",
+
+	// Retry Policy
+	"retry_title":                 "Wiederholungen",
+	"retry_attempts":              "Max. Versuche:",
+	"retry_base_delay":            "Basis-Wartezeit (s):",
+	"retry_max_delay":             "Max. Wartezeit (s):",
+	"retry_factor":                "Backoff-Faktor:",
+	"retry_jitter":                "Streuung (%):",
+	"retry_inherit":               "wie oben",
+	"retry_unknown_attempts":      "Andere Fehler, max. Versuche:",
+	"retry_unknown_delay":         "Andere Fehler, Basis-Wartezeit (s):",
+	"retry_rate_limited_attempts": "Drosselung, max. Versuche:",
+	"retry_rate_limited_delay":    "Drosselung, Basis-Wartezeit (s):",
+	"retry_network_attempts":      "Netzwerkfehler, max. Versuche:",
+	"retry_network_delay":         "Netzwerkfehler, Basis-Wartezeit (s):",
 }
 
 func SetLanguage(lang string) {
@@ -149,3 +543,17 @@ func Get(key string) string {
 	}
 	return val
 }
+
+// Dict returns a copy of every string for lang ("" for the current
+// language), e.g. to hand them to the web UI
+func Dict(lang string) map[string]string {
+	dict := en
+	if lang == "German" || lang == "de" || (lang == "" && currentLang == "de") {
+		dict = de
+	}
+	out := make(map[string]string, len(dict))
+	for k, v := range dict {
+		out[k] = v
+	}
+	return out
+}
@@ -1,5 +1,7 @@
 package models
 
+import "sort"
+
 // Set by build flags (e.g., -ldflags "-X gotube/internal/models.AppVersion=v1.5.0")
 var AppVersion = "v0.0.0-dev"
 
@@ -8,9 +10,9 @@ type DownloadConfig struct {
 	OutputPath      string
 	DownloadMode    string
 	Quality         string
-	TrimStart       string
+	TrimStart       string // Single section from older jobs; Ranges replaces it
 	TrimEnd         string
-	UseSponsorBlock bool
+	UseSponsorBlock bool // Without SponsorBlock actions: remove every category
 	Client          string
 	CookiesPath     string
 	SafeMode        bool
@@ -18,7 +20,80 @@ type DownloadConfig struct {
 	PlaylistItems   string
 	EmbedSubs       bool
 	AutoSubs        bool
-	SubLanguage     string
+	SubLanguage     string // en, de or all; SubLanguages replaces it
+	Backend         string // "" picks one from the URL
+	FormatID        string // Exact yt-dlp format selector (e.g. "137+140"); overrides Quality
+	Prefs           FormatPrefs
+	OutputTemplate  string        // yt-dlp -o template relative to OutputPath; "" is the default
+	ForceRedownload bool          // Download even if the archive lists the video
+	ArchivePath     string        // yt-dlp --download-archive file, set by the queue per run
+	DateAfter       string        // YYYYMMDD; older uploads are skipped
+	RateLimit       int64         // Bytes per second; 0 is unlimited
+	Network         NetworkConfig // Fields set here override the global settings
+
+	Chapters      []string // Titles of the chapters to download, one file each; overrides the trim
+	SplitChapters bool     // Also write one file per chapter
+	EmbedChapters bool     // Embed chapter markers in the file
+
+	Ranges       []TimeRange // Sections to download, sorted and merged; overrides TrimStart/TrimEnd
+	ConcatRanges bool        // Join the sections into one file instead of one file each
+
+	SponsorBlock map[string]string // Category → SponsorRemove, SponsorMark or SponsorIgnore
+
+	SubLanguages []string // Exact subtitle languages (SubtitleTrack.Language)
+	WriteSubs    bool     // Save subtitles next to the file, besides or instead of embedding
+	SubFormat    string   // Format of those files: srt, vtt or ass; "" is srt
+}
+
+// SponsorBlock actions for a segment category
+const (
+	SponsorRemove = "remove"
+	SponsorMark   = "mark" // As a chapter
+	SponsorIgnore = "ignore"
+)
+
+// SponsorCategories are SponsorBlock's segment categories, in display order
+var SponsorCategories = []string{"sponsor", "intro", "outro", "selfpromo", "interaction", "music_offtopic", "preview", "filler"}
+
+// TimeRange is a section of a video in seconds. An End of 0 runs to the end.
+type TimeRange struct {
+	Start float64
+	End   float64
+}
+
+// NetworkConfig routes traffic through a proxy or a local interface; empty
+// fields keep the system defaults
+type NetworkConfig struct {
+	Proxy         string // http://, https:// or socks5:// URL
+	SourceAddress string // Local IP to connect from
+	IPVersion     int    // 4 or 6 forces that protocol; 0 lets the OS choose
+	CABundle      string // PEM file of extra trusted CAs, e.g. a corporate proxy's
+}
+
+// Merge returns n with the fields set in override replacing its own
+func (n NetworkConfig) Merge(override NetworkConfig) NetworkConfig {
+	if override.Proxy != "" {
+		n.Proxy = override.Proxy
+	}
+	if override.SourceAddress != "" {
+		n.SourceAddress = override.SourceAddress
+	}
+	if override.IPVersion != 0 {
+		n.IPVersion = override.IPVersion
+	}
+	if override.CABundle != "" {
+		n.CABundle = override.CABundle
+	}
+	return n
+}
+
+// FormatPrefs steer yt-dlp's format sort; empty fields keep its defaults
+type FormatPrefs struct {
+	Container    string // mp4, mkv or webm; "" merges to mp4
+	VideoCodec   string // avc1, vp9 or av01
+	AudioCodec   string // aac or opus
+	DynamicRange string // hdr or sdr
+	MaxFPS       int
 }
 
 // ... (Rest of the file remains the same: VideoMetadata, ProgressUpdate, etc.)
@@ -26,22 +101,142 @@ type VideoMetadata struct {
 	ID           string          `json:"id"`
 	Title        string          `json:"title"`
 	Uploader     string          `json:"uploader"`
+	Channel      string          `json:"channel"`
+	UploadDate   string          `json:"upload_date"` // YYYYMMDD
 	Duration     int             `json:"duration"`
 	ThumbnailURL string          `json:"thumbnail"`
 	Type         string          `json:"_type"`
 	EntryCount   int             `json:"playlist_count"`
 	Entries      []PlaylistEntry `json:"entries"`
+	Formats      []Format        `json:"formats"` // Empty for playlists
+	Chapters     []Chapter       `json:"chapters"`
+
+	// Subtitle files by language; "automatic_captions" are auto-generated
+	Subtitles    map[string][]SubtitleFormat `json:"subtitles"`
+	AutoCaptions map[string][]SubtitleFormat `json:"automatic_captions"`
+}
+
+type SubtitleFormat struct {
+	Ext  string `json:"ext"`
+	Name string `json:"name"` // e.g. "English"
+}
+
+// SubtitleTrack is a language a video has subtitles in
+type SubtitleTrack struct {
+	Language string // yt-dlp's code, e.g. "en" or "de-DE"
+	Name     string
+	Auto     bool // Only auto-generated subtitles exist
+}
+
+// SubtitleTracks lists the subtitle languages, the ones with manual
+// subtitles first, each sorted by language
+func (m *VideoMetadata) SubtitleTracks() []SubtitleTrack {
+	var manual, auto []SubtitleTrack
+	for lang, formats := range m.Subtitles {
+		if lang != "live_chat" {
+			manual = append(manual, SubtitleTrack{Language: lang, Name: subtitleName(lang, formats)})
+		}
+	}
+	for lang, formats := range m.AutoCaptions {
+		if _, ok := m.Subtitles[lang]; !ok {
+			auto = append(auto, SubtitleTrack{Language: lang, Name: subtitleName(lang, formats), Auto: true})
+		}
+	}
+	for _, tracks := range [][]SubtitleTrack{manual, auto} {
+		sort.Slice(tracks, func(i, j int) bool { return tracks[i].Language < tracks[j].Language })
+	}
+	return append(manual, auto...)
+}
+
+func subtitleName(lang string, formats []SubtitleFormat) string {
+	for _, f := range formats {
+		if f.Name != "" {
+			return f.Name
+		}
+	}
+	return lang
+}
+
+type Chapter struct {
+	Title string  `json:"title"`
+	Start float64 `json:"start_time"` // Seconds
+	End   float64 `json:"end_time"`
+}
+
+type Format struct {
+	ID             string  `json:"format_id"`
+	Ext            string  `json:"ext"`
+	Resolution     string  `json:"resolution"`
+	Note           string  `json:"format_note"`
+	Width          int     `json:"width"`
+	Height         int     `json:"height"`
+	FPS            float64 `json:"fps"`
+	VCodec         string  `json:"vcodec"`
+	ACodec         string  `json:"acodec"`
+	TBR            float64 `json:"tbr"` // Total bitrate in kbit/s
+	ABR            float64 `json:"abr"`
+	FileSize       int64   `json:"filesize"`
+	FileSizeApprox int64   `json:"filesize_approx"`
+	DynamicRange   string  `json:"dynamic_range"` // SDR, HDR10, HLG, ...
+	Protocol       string  `json:"protocol"`
+}
+
+func (f Format) HasVideo() bool {
+	return f.VCodec != "" && f.VCodec != "none"
+}
+
+func (f Format) HasAudio() bool {
+	return f.ACodec != "" && f.ACodec != "none"
+}
+
+func (f Format) IsHDR() bool {
+	return f.DynamicRange != "" && f.DynamicRange != "SDR"
+}
+
+// Size returns the exact or approximate size in bytes, estimating it from
+// the bitrate when yt-dlp knows neither. Zero means unknown.
+func (f Format) Size(duration int) int64 {
+	switch {
+	case f.FileSize > 0:
+		return f.FileSize
+	case f.FileSizeApprox > 0:
+		return f.FileSizeApprox
+	case f.TBR > 0 && duration > 0:
+		return int64(f.TBR * 125 * float64(duration)) // kbit/s to bytes/s
+	}
+	return 0
 }
 
 type PlaylistEntry struct {
 	ID    string `json:"id"`
 	Title string `json:"title"`
+	URL   string `json:"url"`
 }
 
+type Severity int
+
+const (
+	SeverityInfo Severity = iota
+	SeverityWarning
+	SeverityError
+)
+
 type ProgressUpdate struct {
-	Percent float64
-	Text    string
-	Stage   string
+	Percent  float64
+	Text     string
+	Stage    string
+	Severity Severity // For stderr lines, which carry no Stage
+
+	// Filled from yt-dlp's progress template; zero means unknown
+	DownloadedBytes int64
+	TotalBytes      int64
+	Speed           float64 // bytes per second
+	ETA             int     // seconds
+	FragmentIndex   int
+	FragmentCount   int
+	PlaylistIndex   int
+	PlaylistCount   int
+	PostProcessor   string
 }
 
 type AppSettings struct {
@@ -50,6 +245,60 @@ type AppSettings struct {
 	CookiesPath  string
 	ClientSpoof  string
 	Language     string
+
+	MaxConcurrent int
+	FormatPrefs   FormatPrefs
+
+	OutputTemplate  string
+	OutputTemplates []string // Saved by the user, besides the built-in presets
+
+	// Download windows like "01:00-07:00"; none means always. Outside them
+	// the queue pauses or, in "throttle" mode, limits to OffPeakRate KiB/s.
+	DownloadWindows []string
+	OffPeakMode     string
+	OffPeakRate     int
+	RateLimit       int // KiB/s shared by all running downloads; 0 is unlimited
+
+	Network NetworkConfig
+
+	SponsorBlock map[string]string // Actions for downloads with SponsorBlock ticked; none removes all
+
+	// Retry policy; zero means use the built-in default. Delays are in seconds.
+	RetryMaxAttempts int
+	RetryBaseDelay   int
+	RetryMaxDelay    int
+	RetryFactor      float64
+	RetryJitter      float64                  // Percent
+	RetryOverrides   map[string]RetryOverride // By error kind name, e.g. "network"
+}
+
+// RetryOverride tunes retries for one error kind; zero fields use the
+// built-in override for the kind, or else the general settings
+type RetryOverride struct {
+	MaxAttempts int
+	BaseDelay   int // Seconds
+}
+
+// ArchiveEntry is a video recorded as downloaded into a library (save folder)
+type ArchiveEntry struct {
+	Extractor string
+	VideoID   string
+	Library   string
+	Added     int64
+}
+
+// Subscription is a channel or playlist that is checked for new uploads
+type Subscription struct {
+	ID          int
+	URL         string
+	Title       string
+	Config      DownloadConfig // Preset for new videos; URL is set per video
+	Interval    int            // Minutes between checks
+	DateAfter   string         // YYYYMMDD; older uploads are skipped
+	MaxItems    int            // New videos queued per check; 0 means all
+	LastSeenID  string         // Newest video at the last check
+	LastChecked int64
+	Enabled     bool
 }
 
 type HistoryEntry struct {
@@ -59,3 +308,18 @@ type HistoryEntry struct {
 	FilePath  string
 	Timestamp int64
 }
+
+// QueueEntry is the persisted form of a download queue job
+type QueueEntry struct {
+	ID           int
+	Config       DownloadConfig
+	Title        string
+	ThumbnailURL string
+	State        string
+	Priority     int
+	Position     int
+	Attempts     int
+	LastError    string
+	Added        int64
+	StartAt      int64 // Unix time; 0 starts right away
+}
@@ -0,0 +1,58 @@
+package queue
+
+import (
+	"gotube/internal/downloader"
+	"gotube/internal/models"
+	"time"
+)
+
+type State string
+
+const (
+	StateQueued         State = "queued"
+	StateFetching       State = "fetching"
+	StateDownloading    State = "downloading"
+	StatePostProcessing State = "post-processing"
+	StateDone           State = "done"
+	StateFailed         State = "failed"
+	StateCancelled      State = "cancelled"
+	StatePaused         State = "paused"
+)
+
+// Finished reports whether the job has reached a terminal state
+func (s State) Finished() bool {
+	return s == StateDone || s == StateFailed || s == StateCancelled
+}
+
+// Active reports whether a worker is currently busy with the job
+func (s State) Active() bool {
+	return s == StateFetching || s == StateDownloading || s == StatePostProcessing
+}
+
+const (
+	PriorityLow    = -1
+	PriorityNormal = 0
+	PriorityHigh   = 1
+)
+
+// Job is a single download tracked by the Manager.
+// Callers always receive copies; use the Manager methods to change a job.
+type Job struct {
+	ID           int
+	Config       models.DownloadConfig
+	Title        string
+	ThumbnailURL string
+	State        State
+	Priority     int
+	Attempts     int
+	Err          string
+	ErrKind      downloader.ErrorKind // Not persisted; KindUnknown after a restart
+	Progress     models.ProgressUpdate
+	Added        time.Time
+	StartAt      time.Time // Zero starts right away
+}
+
+// Scheduled reports whether a queued job waits for its start time
+func (j Job) Scheduled(now time.Time) bool {
+	return j.State == StateQueued && j.StartAt.After(now)
+}
@@ -0,0 +1,784 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gotube/internal/database"
+	"gotube/internal/downloader"
+	"gotube/internal/models"
+	"gotube/internal/utils"
+	"os"
+	"sync"
+	"time"
+)
+
+// errPaused stops a job like a shutdown does, keeping its .part files
+var errPaused = fmt.Errorf("paused: %w", downloader.ErrInterrupted)
+
+// errDeferred puts a running job back into the queue when a download window
+// closes or its yt-dlp process has to restart at another rate
+var errDeferred = fmt.Errorf("deferred: %w", downloader.ErrInterrupted)
+
+// Manager runs download jobs on a bounded pool of workers.
+// Queued jobs start in priority order, then in queue order.
+type Manager struct {
+	engine *downloader.Engine
+	db     *database.DB
+
+	// Logger receives every progress line, prefixed with the job ID. Optional.
+	Logger *utils.LogBuffer
+
+	mu           sync.Mutex
+	jobs         []*Job
+	nextID       int
+	workers      int
+	running      int
+	cancels      map[int]context.CancelCauseFunc
+	listeners    map[int]func(Job)
+	nextListener int
+	persist      bool
+	closed       bool
+	wg           sync.WaitGroup
+
+	policy   Policy
+	limiters map[int]*downloader.Limiter // Current rate limit of each running job
+	rates    map[int]int64               // Rate each running yt-dlp process was started with
+	timer    *time.Timer                 // Fires at the next start time or window boundary
+}
+
+func NewManager(engine *downloader.Engine, db *database.DB, workers int) *Manager {
+	if workers < 1 {
+		workers = 1
+	}
+	return &Manager{
+		engine:    engine,
+		db:        db,
+		nextID:    1,
+		workers:   workers,
+		cancels:   make(map[int]context.CancelCauseFunc),
+		listeners: make(map[int]func(Job)),
+		limiters:  make(map[int]*downloader.Limiter),
+		rates:     make(map[int]int64),
+	}
+}
+
+// SetWorkers changes how many jobs may run at once. Running jobs are never
+// interrupted; lowering the limit only takes effect as they finish.
+func (m *Manager) SetWorkers(n int) {
+	if n < 1 {
+		n = 1
+	}
+	m.mu.Lock()
+	m.workers = n
+	m.schedule()
+	m.mu.Unlock()
+}
+
+func (m *Manager) Workers() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.workers
+}
+
+// SetPolicy changes the download windows and speed limits. HTTP downloads
+// change speed in place. yt-dlp jobs that are no longer allowed, or whose
+// rate changes, go back into the queue and resume from their partial files
+// when they may run again.
+func (m *Manager) SetPolicy(p Policy) {
+	m.mu.Lock()
+	m.policy = p
+	m.rebalance(true)
+	m.schedule()
+	m.mu.Unlock()
+}
+
+func (m *Manager) Policy() Policy {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.policy
+}
+
+// Restore loads the jobs saved by a previous session and keeps the queue
+// table in sync from then on. Jobs that were queued or interrupted mid-download
+// are queued again; yt-dlp picks up their .part files. Paused jobs stay
+// paused. Returns how many jobs were resumed.
+func (m *Manager) Restore() (int, error) {
+	if m.db == nil {
+		return 0, errors.New("no database")
+	}
+	entries, err := m.db.LoadQueue()
+	if err != nil {
+		return 0, err
+	}
+
+	m.mu.Lock()
+	m.persist = true
+	resumed := 0
+	for _, e := range entries {
+		job := &Job{
+			ID:           e.ID,
+			Config:       e.Config,
+			Title:        e.Title,
+			ThumbnailURL: e.ThumbnailURL,
+			State:        State(e.State),
+			Priority:     e.Priority,
+			Attempts:     e.Attempts,
+			Err:          e.LastError,
+			Added:        time.Unix(e.Added, 0),
+		}
+		if e.StartAt > 0 {
+			job.StartAt = time.Unix(e.StartAt, 0)
+		}
+		if !job.State.Finished() && job.State != StatePaused {
+			job.State = StateQueued
+			resumed++
+		}
+		if job.ID >= m.nextID {
+			m.nextID = job.ID + 1
+		}
+		m.jobs = append(m.jobs, job)
+	}
+	restored := make([]Job, len(m.jobs))
+	for i, j := range m.jobs {
+		restored[i] = *j
+	}
+	m.schedule()
+	m.mu.Unlock()
+
+	for _, j := range restored {
+		m.notify(j)
+	}
+	return resumed, nil
+}
+
+// Shutdown stops all running jobs without discarding their partial files and
+// waits for the workers to exit. With Restore enabled they resume next start.
+func (m *Manager) Shutdown() {
+	m.mu.Lock()
+	m.closed = true
+	if m.timer != nil {
+		m.timer.Stop()
+	}
+	for _, cancel := range m.cancels {
+		cancel(downloader.ErrInterrupted)
+	}
+	m.mu.Unlock()
+	m.wg.Wait()
+}
+
+// Submit queues a download. meta may be nil, in which case the title is
+// fetched by the worker before downloading.
+func (m *Manager) Submit(config models.DownloadConfig, meta *models.VideoMetadata, priority int) Job {
+	return m.SubmitAt(config, meta, priority, time.Time{})
+}
+
+// SubmitAt queues a download that doesn't start before at
+func (m *Manager) SubmitAt(config models.DownloadConfig, meta *models.VideoMetadata, priority int, at time.Time) Job {
+	m.mu.Lock()
+	job := &Job{
+		ID:       m.nextID,
+		Config:   config,
+		Title:    config.URL,
+		State:    StateQueued,
+		Priority: priority,
+		Added:    time.Now(),
+		StartAt:  at,
+	}
+	if meta != nil {
+		job.Title = meta.Title
+		job.ThumbnailURL = meta.ThumbnailURL
+	}
+	m.nextID++
+	m.jobs = append(m.jobs, job)
+	snapshot := *job
+	m.save(job)
+	m.schedule()
+	m.mu.Unlock()
+
+	m.notify(snapshot)
+	return snapshot
+}
+
+// Jobs returns a copy of every job in queue order
+func (m *Manager) Jobs() []Job {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	jobs := make([]Job, len(m.jobs))
+	for i, j := range m.jobs {
+		jobs[i] = *j
+	}
+	return jobs
+}
+
+func (m *Manager) Get(id int) (Job, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if j := m.find(id); j != nil {
+		return *j, true
+	}
+	return Job{}, false
+}
+
+// Cancel stops a running job or drops a queued one
+func (m *Manager) Cancel(id int) error {
+	m.mu.Lock()
+	j := m.find(id)
+	if j == nil {
+		m.mu.Unlock()
+		return fmt.Errorf("job %d not found", id)
+	}
+	if j.State.Finished() {
+		m.mu.Unlock()
+		return nil
+	}
+	if cancel, ok := m.cancels[id]; ok {
+		// The worker records the final state once yt-dlp has exited
+		cancel(nil)
+		m.mu.Unlock()
+		return nil
+	}
+	j.State = StateCancelled
+	m.save(j)
+	snapshot := *j
+	m.mu.Unlock()
+
+	m.notify(snapshot)
+	return nil
+}
+
+// Pause stops a job but keeps its partial files so Resume can continue it
+func (m *Manager) Pause(id int) error {
+	m.mu.Lock()
+	j := m.find(id)
+	if j == nil {
+		m.mu.Unlock()
+		return fmt.Errorf("job %d not found", id)
+	}
+	if j.State.Finished() || j.State == StatePaused {
+		m.mu.Unlock()
+		return nil
+	}
+	if cancel, ok := m.cancels[id]; ok {
+		cancel(errPaused)
+		m.mu.Unlock()
+		return nil
+	}
+	j.State = StatePaused
+	m.save(j)
+	snapshot := *j
+	m.mu.Unlock()
+
+	m.notify(snapshot)
+	return nil
+}
+
+// Resume queues a paused job again
+func (m *Manager) Resume(id int) error {
+	m.mu.Lock()
+	j := m.find(id)
+	if j == nil {
+		m.mu.Unlock()
+		return fmt.Errorf("job %d not found", id)
+	}
+	if j.State != StatePaused {
+		m.mu.Unlock()
+		return fmt.Errorf("job %d is %s", id, j.State)
+	}
+	j.State = StateQueued
+	m.save(j)
+	snapshot := *j
+	m.schedule()
+	m.mu.Unlock()
+
+	m.notify(snapshot)
+	return nil
+}
+
+// SetStartAt reschedules a job that hasn't started; the zero time starts it
+// as soon as a worker is free
+func (m *Manager) SetStartAt(id int, at time.Time) error {
+	m.mu.Lock()
+	j := m.find(id)
+	if j == nil {
+		m.mu.Unlock()
+		return fmt.Errorf("job %d not found", id)
+	}
+	if j.State != StateQueued && j.State != StatePaused {
+		m.mu.Unlock()
+		return fmt.Errorf("job %d is %s", id, j.State)
+	}
+	j.StartAt = at
+	m.save(j)
+	snapshot := *j
+	m.schedule()
+	m.mu.Unlock()
+
+	m.notify(snapshot)
+	return nil
+}
+
+// Retry puts a failed or cancelled job back into the queue
+func (m *Manager) Retry(id int) error {
+	m.mu.Lock()
+	j := m.find(id)
+	if j == nil {
+		m.mu.Unlock()
+		return fmt.Errorf("job %d not found", id)
+	}
+	if j.State != StateFailed && j.State != StateCancelled {
+		m.mu.Unlock()
+		return fmt.Errorf("job %d is %s", id, j.State)
+	}
+	j.State = StateQueued
+	j.Err = ""
+	j.ErrKind = downloader.KindUnknown
+	j.Progress = models.ProgressUpdate{}
+	m.save(j)
+	snapshot := *j
+	m.schedule()
+	m.mu.Unlock()
+
+	m.notify(snapshot)
+	return nil
+}
+
+// Remove deletes a job that is not currently running
+func (m *Manager) Remove(id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, j := range m.jobs {
+		if j.ID == id {
+			if j.State.Active() {
+				return fmt.Errorf("job %d is still running", id)
+			}
+			m.jobs = append(m.jobs[:i], m.jobs[i+1:]...)
+			m.unsave(id)
+			return nil
+		}
+	}
+	return fmt.Errorf("job %d not found", id)
+}
+
+// ClearFinished removes all done, failed and cancelled jobs
+func (m *Manager) ClearFinished() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	kept := m.jobs[:0]
+	for _, j := range m.jobs {
+		if !j.State.Finished() {
+			kept = append(kept, j)
+		} else {
+			m.unsave(j.ID)
+		}
+	}
+	m.jobs = kept
+}
+
+// Move places a job at the given position in the queue
+func (m *Manager) Move(id, index int) error {
+	m.mu.Lock()
+	from := -1
+	for i, j := range m.jobs {
+		if j.ID == id {
+			from = i
+			break
+		}
+	}
+	if from < 0 {
+		m.mu.Unlock()
+		return fmt.Errorf("job %d not found", id)
+	}
+	if index < 0 {
+		index = 0
+	}
+	if index >= len(m.jobs) {
+		index = len(m.jobs) - 1
+	}
+	job := m.jobs[from]
+	m.jobs = append(m.jobs[:from], m.jobs[from+1:]...)
+	m.jobs = append(m.jobs[:index], append([]*Job{job}, m.jobs[index:]...)...)
+	for _, j := range m.jobs {
+		m.save(j)
+	}
+	snapshot := *job
+	m.mu.Unlock()
+
+	m.notify(snapshot)
+	return nil
+}
+
+// SetPriority changes which of the queued jobs start first
+func (m *Manager) SetPriority(id, priority int) error {
+	m.mu.Lock()
+	j := m.find(id)
+	if j == nil {
+		m.mu.Unlock()
+		return fmt.Errorf("job %d not found", id)
+	}
+	j.Priority = priority
+	m.save(j)
+	snapshot := *j
+	m.mu.Unlock()
+
+	m.notify(snapshot)
+	return nil
+}
+
+// Subscribe registers fn to receive a copy of a job whenever it changes.
+// fn is called from worker goroutines. The returned func unsubscribes.
+func (m *Manager) Subscribe(fn func(Job)) func() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	id := m.nextListener
+	m.nextListener++
+	m.listeners[id] = fn
+	return func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		delete(m.listeners, id)
+	}
+}
+
+func (m *Manager) find(id int) *Job {
+	for _, j := range m.jobs {
+		if j.ID == id {
+			return j
+		}
+	}
+	return nil
+}
+
+func (m *Manager) notify(job Job) {
+	m.mu.Lock()
+	fns := make([]func(Job), 0, len(m.listeners))
+	for _, fn := range m.listeners {
+		fns = append(fns, fn)
+	}
+	m.mu.Unlock()
+	for _, fn := range fns {
+		fn(job)
+	}
+}
+
+// update applies fn to a job under the lock and notifies listeners
+func (m *Manager) update(id int, fn func(*Job)) {
+	m.mu.Lock()
+	j := m.find(id)
+	if j == nil {
+		m.mu.Unlock()
+		return
+	}
+	fn(j)
+	snapshot := *j
+	m.mu.Unlock()
+	m.notify(snapshot)
+}
+
+// schedule starts queued jobs while workers are free and the download
+// windows allow it, shares out the rate budget, then arms the timer for the
+// next start time or window boundary. Caller holds m.mu.
+func (m *Manager) schedule() {
+	if m.closed {
+		return
+	}
+	now := time.Now()
+	allowed, _ := m.policy.At(now)
+	for allowed && m.running < m.workers {
+		var next *Job
+		for _, j := range m.jobs {
+			if j.State == StateQueued && !j.Scheduled(now) && (next == nil || j.Priority > next.Priority) {
+				next = j
+			}
+		}
+		if next == nil {
+			break
+		}
+		ctx, cancel := context.WithCancelCause(context.Background())
+		limiter := downloader.NewLimiter(0)
+		m.cancels[next.ID] = cancel
+		m.limiters[next.ID] = limiter
+		next.State = StateFetching
+		next.Attempts++
+		m.save(next)
+		m.running++
+		m.wg.Add(1)
+		go m.run(ctx, *next, limiter)
+	}
+	m.rebalance(false)
+	m.arm(now)
+}
+
+// arm sets the timer to the earliest start time or window boundary.
+// Caller holds m.mu.
+func (m *Manager) arm(now time.Time) {
+	wake := m.policy.NextChange(now)
+	for _, j := range m.jobs {
+		if j.Scheduled(now) && (wake.IsZero() || j.StartAt.Before(wake)) {
+			wake = j.StartAt
+		}
+	}
+	if m.timer != nil {
+		m.timer.Stop()
+		m.timer = nil
+	}
+	if wake.IsZero() {
+		return
+	}
+	m.timer = time.AfterFunc(time.Until(wake), func() {
+		m.mu.Lock()
+		m.rebalance(true)
+		m.schedule()
+		m.mu.Unlock()
+	})
+}
+
+// minShare keeps a job crawling when the budget is split among many
+const minShare = 1024
+
+// rebalance splits the policy's rate budget evenly among the running jobs,
+// capped by each job's own limit, and defers jobs the policy doesn't allow.
+// yt-dlp can't change speed, so it restarts whenever it runs faster than its
+// share, keeping the running rates within the budget, and when its share
+// has grown by half, so it picks up the bandwidth a finished job freed.
+// With force it restarts whenever its rate differs. Deferred jobs are
+// rescheduled once the worker has exited. Caller holds m.mu.
+func (m *Manager) rebalance(force bool) {
+	allowed, budget := m.policy.At(time.Now())
+	var share int64
+	if budget > 0 && len(m.limiters) > 0 {
+		share = max(budget/int64(len(m.limiters)), minShare)
+	}
+	for id, limiter := range m.limiters {
+		j := m.find(id)
+		if !allowed || j == nil {
+			m.cancels[id](errDeferred)
+			continue
+		}
+		rate := minRate(j.Config.RateLimit, share)
+		limiter.SetRate(rate)
+		started, ok := m.rates[id]
+		if !ok || started == rate {
+			continue
+		}
+		tooFast := rate > 0 && (started <= 0 || started > rate)
+		grown := started > 0 && (rate <= 0 || rate >= started+started/2)
+		if force || tooFast || grown {
+			m.cancels[id](errDeferred)
+		}
+	}
+}
+
+// fetchMetadata asks the job's own backend, which may differ from the one
+// the URL alone would pick, over the job's network
+func (m *Manager) fetchMetadata(ctx context.Context, config models.DownloadConfig) (*models.VideoMetadata, error) {
+	backend, err := m.engine.BackendFor(config)
+	if err != nil {
+		return nil, err
+	}
+	ctx = downloader.WithNetwork(ctx, utils.Network().Merge(config.Network))
+	return backend.GetMetadata(ctx, config.URL)
+}
+
+// run downloads a job at the rate the limiter sets, see rebalance
+func (m *Manager) run(ctx context.Context, job Job, limiter *downloader.Limiter) {
+	defer m.wg.Done()
+	m.notify(job)
+
+	if job.Title == job.Config.URL {
+		if meta, err := m.fetchMetadata(ctx, job.Config); err == nil {
+			m.update(job.ID, func(j *Job) {
+				j.Title = meta.Title
+				j.ThumbnailURL = meta.ThumbnailURL
+				m.save(j)
+			})
+			job.Title = meta.Title
+		}
+	}
+
+	m.update(job.ID, func(j *Job) { j.State = StateDownloading })
+	m.log(job.ID, "Starting download: "+job.Config.URL)
+	config := job.Config
+	dlCtx := ctx
+	if b, err := m.engine.BackendFor(config); err == nil && b.Name() == downloader.BackendHTTP {
+		dlCtx = downloader.WithLimiter(ctx, limiter)
+	} else {
+		m.mu.Lock()
+		config.RateLimit = limiter.Rate()
+		m.rates[job.ID] = config.RateLimit
+		m.mu.Unlock()
+	}
+	config.ArchivePath = m.exportArchive(job.ID, config)
+	err := m.engine.Download(dlCtx, config, func(u models.ProgressUpdate) {
+		m.log(job.ID, u.Text)
+		if u.Stage == "" {
+			return // stderr output is only interesting for the log
+		}
+		m.update(job.ID, func(j *Job) {
+			// Plain output lines carry no progress; keep the last known values
+			if u.Percent > 0 || u.Stage != "Downloading" {
+				j.Progress = u
+			}
+			if u.Stage == "Processing" {
+				j.State = StatePostProcessing
+			} else if u.Stage == "Downloading" {
+				j.State = StateDownloading
+			}
+		})
+	})
+
+	m.importArchive(job.ID, config)
+
+	m.mu.Lock()
+	cancelled := ctx.Err() != nil
+	paused := errors.Is(context.Cause(ctx), errPaused)
+	deferred := errors.Is(context.Cause(ctx), errDeferred)
+	interrupted := errors.Is(context.Cause(ctx), downloader.ErrInterrupted)
+	m.cancels[job.ID](nil)
+	delete(m.cancels, job.ID)
+	delete(m.limiters, job.ID)
+	delete(m.rates, job.ID)
+	m.running--
+	j := m.find(job.ID)
+	if j == nil {
+		// Removed while running is not allowed, but be defensive
+		m.schedule()
+		m.mu.Unlock()
+		return
+	}
+	switch {
+	case paused:
+		j.State = StatePaused
+	case deferred:
+		j.State = StateQueued
+		j.Attempts-- // Not a failed attempt
+	case interrupted:
+		j.State = StateQueued
+	case cancelled:
+		j.State = StateCancelled
+	case err != nil:
+		j.State = StateFailed
+		j.Err = err.Error()
+		var dlErr *downloader.Error
+		if errors.As(err, &dlErr) {
+			j.ErrKind = dlErr.Kind
+		}
+	default:
+		j.State = StateDone
+		j.Progress.Percent = 1.0
+	}
+	m.save(j)
+	snapshot := *j
+	m.schedule()
+	m.mu.Unlock()
+
+	switch snapshot.State {
+	case StatePaused:
+		m.log(job.ID, "PAUSED")
+	case StateQueued:
+		if deferred {
+			m.log(job.ID, "DEFERRED: Download window or speed limit changed; the download continues from its partial files when allowed.")
+			break
+		}
+		m.log(job.ID, "INTERRUPTED: Download will resume on next start.")
+	case StateCancelled:
+		m.log(job.ID, "CANCELLED: Download aborted by user.")
+	case StateFailed:
+		m.log(job.ID, "ERROR: "+snapshot.Err)
+	default:
+		m.log(job.ID, "SUCCESS: Download finished.")
+	}
+	m.notify(snapshot)
+	if snapshot.State == StateDone && m.db != nil {
+		m.db.SaveHistory(snapshot.Title, snapshot.Config.URL, snapshot.Config.OutputPath)
+	}
+}
+
+// exportArchive writes the job's library archive to a temp file for yt-dlp,
+// so videos downloaded before are skipped. Forced jobs get an empty file:
+// they download everything but still record what they got.
+func (m *Manager) exportArchive(id int, config models.DownloadConfig) string {
+	if m.db == nil {
+		return ""
+	}
+	// Only yt-dlp understands archive files
+	if b, err := m.engine.BackendFor(config); err != nil || b.Name() != downloader.BackendYtDlp {
+		return ""
+	}
+	f, err := os.CreateTemp("", "gotube-archive-*.txt")
+	if err != nil {
+		m.log(id, "Archive unavailable: "+err.Error())
+		return ""
+	}
+	f.Close()
+	if !config.ForceRedownload {
+		if err := m.db.WriteArchiveFile(config.OutputPath, f.Name()); err != nil {
+			m.log(id, "Archive unavailable: "+err.Error())
+		}
+	}
+	return f.Name()
+}
+
+// importArchive records the videos yt-dlp added to the temp archive, even
+// for failed jobs, since a playlist may have finished some of its items
+func (m *Manager) importArchive(id int, config models.DownloadConfig) {
+	if config.ArchivePath == "" {
+		return
+	}
+	defer os.Remove(config.ArchivePath)
+	n, err := m.db.ImportArchiveFile(config.OutputPath, config.ArchivePath)
+	if err != nil {
+		m.log(id, "Could not update archive: "+err.Error())
+	} else if n > 0 {
+		m.log(id, fmt.Sprintf("Archived %d video(s)", n))
+	}
+}
+
+// save writes a job to the queue table. Caller holds m.mu.
+func (m *Manager) save(j *Job) {
+	if !m.persist {
+		return
+	}
+	var startAt int64
+	if !j.StartAt.IsZero() {
+		startAt = j.StartAt.Unix()
+	}
+	position := 0
+	for i, other := range m.jobs {
+		if other == j {
+			position = i
+		}
+	}
+	err := m.db.SaveQueueEntry(models.QueueEntry{
+		ID:           j.ID,
+		Config:       j.Config,
+		Title:        j.Title,
+		ThumbnailURL: j.ThumbnailURL,
+		State:        string(j.State),
+		Priority:     j.Priority,
+		Position:     position,
+		Attempts:     j.Attempts,
+		LastError:    j.Err,
+		Added:        j.Added.Unix(),
+		StartAt:      startAt,
+	})
+	if err != nil {
+		m.log(j.ID, "Could not save queue entry: "+err.Error())
+	}
+}
+
+// unsave removes a job from the queue table. Caller holds m.mu.
+func (m *Manager) unsave(id int) {
+	if !m.persist {
+		return
+	}
+	if err := m.db.DeleteQueueEntry(id); err != nil {
+		m.log(id, "Could not remove queue entry: "+err.Error())
+	}
+}
+
+func (m *Manager) log(id int, text string) {
+	if m.Logger != nil {
+		m.Logger.Write(fmt.Sprintf("[#%d] %s", id, text))
+	}
+}
@@ -0,0 +1,166 @@
+package queue
+
+import (
+	"context"
+	"gotube/internal/downloader"
+	"gotube/internal/models"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeBackend stands in for yt-dlp. Downloads run until cancelled and
+// report their URL and rate on started.
+type fakeBackend struct {
+	started chan models.DownloadConfig
+}
+
+func (f *fakeBackend) Name() string { return downloader.BackendYtDlp }
+
+func (f *fakeBackend) GetMetadata(ctx context.Context, url string) (*models.VideoMetadata, error) {
+	return &models.VideoMetadata{Title: url}, nil
+}
+
+func (f *fakeBackend) ListFormats(ctx context.Context, url string) ([]models.Format, error) {
+	return nil, nil
+}
+
+func (f *fakeBackend) Download(ctx context.Context, config models.DownloadConfig, callback func(models.ProgressUpdate)) ([]string, error) {
+	f.started <- config
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (f *fakeBackend) Version(ctx context.Context) (string, error) { return "fake", nil }
+
+func (f *fakeBackend) Update(ctx context.Context, progress func(string)) error { return nil }
+
+func newTestManager(t *testing.T, workers int) (*Manager, *fakeBackend) {
+	t.Helper()
+	fake := &fakeBackend{started: make(chan models.DownloadConfig, 100)}
+	engine := downloader.NewEngine("")
+	engine.Register(fake)
+	m := NewManager(engine, nil, workers)
+	t.Cleanup(m.Shutdown)
+	return m, fake
+}
+
+// next waits for the next download to start
+func (f *fakeBackend) next(t *testing.T) models.DownloadConfig {
+	t.Helper()
+	select {
+	case c := <-f.started:
+		return c
+	case <-time.After(5 * time.Second):
+		t.Fatal("no download started")
+		return models.DownloadConfig{}
+	}
+}
+
+func submit(m *Manager, url string) Job {
+	return m.Submit(models.DownloadConfig{URL: url}, &models.VideoMetadata{Title: url}, PriorityNormal)
+}
+
+func TestMoveAndPriorityOrder(t *testing.T) {
+	m, fake := newTestManager(t, 1)
+	var mu sync.Mutex
+	notified := make(map[int]int)
+	m.Subscribe(func(j Job) {
+		mu.Lock()
+		notified[j.ID]++
+		mu.Unlock()
+	})
+
+	busy := submit(m, "busy")
+	fake.next(t)
+	submit(m, "a")
+	b, c := submit(m, "b"), submit(m, "c")
+
+	mu.Lock()
+	before := notified[c.ID]
+	mu.Unlock()
+	if err := m.Move(c.ID, 1); err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock()
+	if notified[c.ID] == before {
+		t.Error("Move did not notify listeners")
+	}
+	mu.Unlock()
+	if err := m.Move(99, 0); err == nil {
+		t.Error("Move of an unknown job succeeded")
+	}
+
+	m.Cancel(busy.ID)
+	if got := fake.next(t).URL; got != "c" {
+		t.Fatalf("after Move started %q, want c", got)
+	}
+
+	m.SetPriority(b.ID, PriorityHigh)
+	m.Cancel(c.ID)
+	if got := fake.next(t).URL; got != "b" {
+		t.Fatalf("after SetPriority started %q, want b", got)
+	}
+	m.Cancel(b.ID)
+	if got := fake.next(t).URL; got != "a" {
+		t.Fatalf("started %q, want a", got)
+	}
+}
+
+// settle collects the downloads started until none has started for a while,
+// by URL
+func (f *fakeBackend) settle(rates map[string][]int64) {
+	for {
+		select {
+		case c := <-f.started:
+			rates[c.URL] = append(rates[c.URL], c.RateLimit)
+		case <-time.After(200 * time.Millisecond):
+			return
+		}
+	}
+}
+
+// runningRates sums the rates yt-dlp processes were started with and the
+// rates their limiters now allow
+func (m *Manager) runningRates() (started, limited int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, r := range m.rates {
+		started += r
+	}
+	for _, l := range m.limiters {
+		limited += l.Rate()
+	}
+	return started, limited
+}
+
+func TestRebalanceRestarts(t *testing.T) {
+	const budget = 30000
+	m, fake := newTestManager(t, 3)
+	m.SetPolicy(Policy{Limit: budget})
+
+	// Each new job restarts the running ones at the smaller share, so the
+	// rates never add up to more than the budget
+	rates := make(map[string][]int64)
+	jobs := make(map[string]Job)
+	for _, url := range []string{"a", "b", "c"} {
+		jobs[url] = submit(m, url)
+		fake.settle(rates)
+		if started, limited := m.runningRates(); started > budget || limited > budget {
+			t.Fatalf("after %s: started %d, limited %d, over the budget", url, started, limited)
+		}
+	}
+
+	// When one finishes, the others restart with the freed bandwidth
+	m.Cancel(jobs["c"].ID)
+	fake.settle(rates)
+	if started, limited := m.runningRates(); started != budget || limited != budget {
+		t.Errorf("after c finished: started %d, limited %d, want %d", started, limited, budget)
+	}
+
+	want := map[string][]int64{"a": {30000, 15000, 10000, 15000}, "b": {15000, 10000, 15000}, "c": {10000}}
+	if !reflect.DeepEqual(rates, want) {
+		t.Errorf("rates = %v, want %v", rates, want)
+	}
+}
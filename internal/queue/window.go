@@ -0,0 +1,169 @@
+package queue
+
+import (
+	"errors"
+	"fmt"
+	"gotube/internal/models"
+	"strings"
+	"time"
+)
+
+// Off-peak modes: what the queue does outside the download windows
+const (
+	OffPeakPause    = "pause"
+	OffPeakThrottle = "throttle"
+)
+
+// DefaultOffPeakRate is the throttle in KiB/s when none is set
+const DefaultOffPeakRate = 500
+
+var ErrBadWindow = errors.New(`window must look like "01:00-07:00"`)
+
+// Window is a daily range in local time, in minutes since midnight. An End
+// before Start spans midnight.
+type Window struct {
+	Start, End int
+}
+
+// ParseWindow parses "HH:MM-HH:MM"
+func ParseWindow(s string) (Window, error) {
+	from, to, ok := strings.Cut(strings.TrimSpace(s), "-")
+	if !ok {
+		return Window{}, ErrBadWindow
+	}
+	start, err1 := parseClock(from)
+	end, err2 := parseClock(to)
+	if err1 != nil || err2 != nil || start == end {
+		return Window{}, ErrBadWindow
+	}
+	return Window{start, end}, nil
+}
+
+// ParseWindows parses a comma separated list of windows
+func ParseWindows(s string) ([]Window, error) {
+	var windows []Window
+	for _, part := range strings.Split(s, ",") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		w, err := ParseWindow(part)
+		if err != nil {
+			return nil, err
+		}
+		windows = append(windows, w)
+	}
+	return windows, nil
+}
+
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, err
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+func (w Window) String() string {
+	return fmt.Sprintf("%02d:%02d-%02d:%02d", w.Start/60, w.Start%60, w.End/60, w.End%60)
+}
+
+func (w Window) contains(minute int) bool {
+	if w.Start < w.End {
+		return minute >= w.Start && minute < w.End
+	}
+	return minute >= w.Start || minute < w.End
+}
+
+// Policy decides when and how fast the queue downloads. Rates are budgets in
+// bytes per second that the running jobs share.
+type Policy struct {
+	Windows  []Window // None means always
+	Throttle bool     // Outside the windows: throttle to Rate instead of pausing
+	Rate     int64
+	Limit    int64 // Applies at all times; 0 is unlimited
+}
+
+// PolicyFromSettings builds the policy saved in the System tab, skipping
+// malformed windows
+func PolicyFromSettings(s models.AppSettings) Policy {
+	rate := s.OffPeakRate
+	if rate <= 0 {
+		rate = DefaultOffPeakRate
+	}
+	p := Policy{
+		Throttle: s.OffPeakMode == OffPeakThrottle,
+		Rate:     int64(rate) * 1024,
+		Limit:    int64(max(s.RateLimit, 0)) * 1024,
+	}
+	for _, raw := range s.DownloadWindows {
+		if w, err := ParseWindow(raw); err == nil {
+			p.Windows = append(p.Windows, w)
+		}
+	}
+	return p
+}
+
+// At reports whether downloads may run at t and the budget they share (0 for
+// full speed)
+func (p Policy) At(t time.Time) (allowed bool, rate int64) {
+	if len(p.Windows) == 0 {
+		return true, p.Limit
+	}
+	minute := t.Hour()*60 + t.Minute()
+	for _, w := range p.Windows {
+		if w.contains(minute) {
+			return true, p.Limit
+		}
+	}
+	if p.Throttle {
+		return true, minRate(p.Rate, p.Limit)
+	}
+	return false, 0
+}
+
+// minRate returns the stricter of two limits, 0 being unlimited
+func minRate(a, b int64) int64 {
+	if a <= 0 || (b > 0 && b < a) {
+		return b
+	}
+	return a
+}
+
+// NextChange returns the next window boundary after t, or the zero time
+// without windows
+func (p Policy) NextChange(t time.Time) time.Time {
+	var next time.Time
+	for _, w := range p.Windows {
+		for _, minute := range []int{w.Start, w.End} {
+			at := time.Date(t.Year(), t.Month(), t.Day(), minute/60, minute%60, 0, 0, t.Location())
+			if !at.After(t) {
+				at = time.Date(t.Year(), t.Month(), t.Day()+1, minute/60, minute%60, 0, 0, t.Location())
+			}
+			if next.IsZero() || at.Before(next) {
+				next = at
+			}
+		}
+	}
+	return next
+}
+
+// ParseStartTime parses when a job should start: "HH:MM" is the next time
+// the clock shows it, "YYYY-MM-DD HH:MM" a fixed time. "" means now.
+func ParseStartTime(s string, now time.Time) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02 15:04", s, now.Location()); err == nil {
+		return t, nil
+	}
+	minute, err := parseClock(s)
+	if err != nil {
+		return time.Time{}, errors.New(`start time must look like "23:30" or "2024-12-31 23:30"`)
+	}
+	at := time.Date(now.Year(), now.Month(), now.Day(), minute/60, minute%60, 0, 0, now.Location())
+	if !at.After(now) {
+		at = time.Date(now.Year(), now.Month(), now.Day()+1, minute/60, minute%60, 0, 0, now.Location())
+	}
+	return at, nil
+}
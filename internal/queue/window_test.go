@@ -0,0 +1,96 @@
+package queue
+
+import (
+	"testing"
+	"time"
+)
+
+func at(h, m int) time.Time {
+	return time.Date(2024, 3, 10, h, m, 0, 0, time.Local)
+}
+
+func TestParseWindows(t *testing.T) {
+	windows, err := ParseWindows(" 01:00-07:00, 22:30-2:00 ,")
+	if err != nil || len(windows) != 2 || windows[0] != (Window{60, 420}) || windows[1].String() != "22:30-02:00" {
+		t.Fatalf("ParseWindows = %v, %v", windows, err)
+	}
+	for _, bad := range []string{"01:00", "1-7", "25:00-07:00", "07:00-07:00"} {
+		if _, err := ParseWindow(bad); err == nil {
+			t.Errorf("ParseWindow(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestPolicyAt(t *testing.T) {
+	windows, _ := ParseWindows("01:00-07:00, 23:00-00:30")
+	pause := Policy{Windows: windows}
+	throttle := Policy{Windows: windows, Throttle: true, Rate: 1000}
+	tests := []struct {
+		t       time.Time
+		allowed bool
+	}{
+		{at(0, 59), false},
+		{at(1, 0), true},
+		{at(6, 59), true},
+		{at(7, 0), false},
+		{at(23, 15), true},
+		{at(0, 15), true},
+		{at(0, 30), false},
+	}
+	for _, tt := range tests {
+		if allowed, _ := pause.At(tt.t); allowed != tt.allowed {
+			t.Errorf("pause.At(%s) = %v", tt.t.Format("15:04"), allowed)
+		}
+		allowed, rate := throttle.At(tt.t)
+		if !allowed || (rate == 0) != tt.allowed {
+			t.Errorf("throttle.At(%s) = %v, %d", tt.t.Format("15:04"), allowed, rate)
+		}
+	}
+	if allowed, rate := (Policy{}).At(at(12, 0)); !allowed || rate != 0 {
+		t.Error("no windows should always allow full speed")
+	}
+
+	// The global limit applies everywhere; throttling takes the stricter rate
+	throttle.Limit = 500
+	if _, rate := throttle.At(at(3, 0)); rate != 500 {
+		t.Errorf("in window rate = %d, want 500", rate)
+	}
+	if _, rate := throttle.At(at(12, 0)); rate != 500 {
+		t.Errorf("off-peak rate = %d, want 500", rate)
+	}
+	if _, rate := (Policy{Limit: 800}).At(at(12, 0)); rate != 800 {
+		t.Errorf("no windows rate = %d, want 800", rate)
+	}
+}
+
+func TestPolicyNextChange(t *testing.T) {
+	windows, _ := ParseWindows("01:00-07:00")
+	p := Policy{Windows: windows}
+	if got := p.NextChange(at(0, 30)); !got.Equal(at(1, 0)) {
+		t.Errorf("NextChange(00:30) = %v", got)
+	}
+	if got := p.NextChange(at(7, 0)); !got.Equal(at(1, 0).AddDate(0, 0, 1)) {
+		t.Errorf("NextChange(07:00) = %v", got)
+	}
+	if !(Policy{}).NextChange(at(7, 0)).IsZero() {
+		t.Error("no windows should never change")
+	}
+}
+
+func TestParseStartTime(t *testing.T) {
+	now := at(12, 0)
+	tests := map[string]time.Time{
+		"":                 {},
+		"13:30":            at(13, 30),
+		"11:00":            at(11, 0).AddDate(0, 0, 1),
+		"2024-03-12 08:15": time.Date(2024, 3, 12, 8, 15, 0, 0, time.Local),
+	}
+	for in, want := range tests {
+		if got, err := ParseStartTime(in, now); err != nil || !got.Equal(want) {
+			t.Errorf("ParseStartTime(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	if _, err := ParseStartTime("tomorrow", now); err == nil {
+		t.Error("ParseStartTime accepted garbage")
+	}
+}
@@ -0,0 +1,461 @@
+// Package server exposes the download queue as a local HTTP/JSON API for
+// scripts and other tools, plus a browser UI built on it. Every API request
+// needs the API token.
+package server
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gotube/internal/database"
+	"gotube/internal/downloader"
+	"gotube/internal/models"
+	"gotube/internal/queue"
+	"gotube/internal/utils"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// heartbeat keeps idle event streams from being closed by proxies
+const heartbeat = 15 * time.Second
+
+// maxBody caps request bodies; a DownloadConfig is a few hundred bytes
+const maxBody = 1 << 20
+
+type Server struct {
+	queue  *queue.Manager
+	engine *downloader.Engine
+	db     *database.DB // nil disables history and settings
+	token  string
+	mux    *http.ServeMux
+	web    http.Handler
+}
+
+// New returns a server for the given queue, fetching metadata through
+// engine. token must not be empty.
+func New(q *queue.Manager, engine *downloader.Engine, db *database.DB, token string) *Server {
+	s := &Server{queue: q, engine: engine, db: db, token: token, mux: http.NewServeMux(), web: webHandler(db)}
+	s.mux.HandleFunc("GET /api/metadata", s.metadata)
+	s.mux.HandleFunc("GET /api/jobs", s.listJobs)
+	s.mux.HandleFunc("POST /api/jobs", s.submitJob)
+	s.mux.HandleFunc("POST /api/jobs/clear", s.clearJobs)
+	s.mux.HandleFunc("GET /api/jobs/{id}", s.getJob)
+	s.mux.HandleFunc("DELETE /api/jobs/{id}", s.removeJob)
+	s.mux.HandleFunc("POST /api/jobs/{id}/cancel", s.cancelJob)
+	s.mux.HandleFunc("POST /api/jobs/{id}/pause", s.jobAction(s.queue.Pause))
+	s.mux.HandleFunc("POST /api/jobs/{id}/resume", s.jobAction(s.queue.Resume))
+	s.mux.HandleFunc("POST /api/jobs/{id}/retry", s.jobAction(s.queue.Retry))
+	s.mux.HandleFunc("GET /api/events", s.events)
+	s.mux.HandleFunc("GET /api/history", s.history)
+	s.mux.HandleFunc("GET /api/settings", s.settings)
+	s.mux.HandleFunc("GET /api/version", s.version)
+	return s
+}
+
+// NewToken returns a random API token
+func NewToken() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// ServeHTTP serves the web UI as is and checks the token for /api/ requests,
+// sent as "Authorization: Bearer <token>" or, for clients like EventSource
+// that can't set headers, as ?token=
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.URL.Path, "/api/") {
+		s.web.ServeHTTP(w, r)
+		return
+	}
+	token := r.URL.Query().Get("token")
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		token = strings.TrimPrefix(auth, "Bearer ")
+	}
+	if s.token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="gotube"`)
+		writeError(w, http.StatusUnauthorized, "missing or invalid token")
+		return
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+// jobView is the JSON form of a queue.Job
+type jobView struct {
+	ID        int                   `json:"id"`
+	URL       string                `json:"url"`
+	Title     string                `json:"title"`
+	Thumbnail string                `json:"thumbnail,omitempty"`
+	State     queue.State           `json:"state"`
+	Priority  int                   `json:"priority"`
+	Attempts  int                   `json:"attempts"`
+	Error     string                `json:"error,omitempty"`
+	ErrorKind string                `json:"error_kind,omitempty"`
+	Progress  progressView          `json:"progress"`
+	Added     time.Time             `json:"added"`
+	StartAt   *time.Time            `json:"start_at,omitempty"`
+	Config    models.DownloadConfig `json:"config"`
+}
+
+type progressView struct {
+	Percent         float64 `json:"percent"` // 0 to 1
+	Stage           string  `json:"stage,omitempty"`
+	Text            string  `json:"text,omitempty"`
+	DownloadedBytes int64   `json:"downloaded_bytes,omitempty"`
+	TotalBytes      int64   `json:"total_bytes,omitempty"`
+	Speed           float64 `json:"speed,omitempty"` // bytes per second
+	ETA             int     `json:"eta,omitempty"`   // seconds
+	PlaylistIndex   int     `json:"playlist_index,omitempty"`
+	PlaylistCount   int     `json:"playlist_count,omitempty"`
+	PostProcessor   string  `json:"post_processor,omitempty"`
+}
+
+func viewJob(j queue.Job) jobView {
+	v := jobView{
+		ID: j.ID, URL: j.Config.URL, Title: j.Title, Thumbnail: j.ThumbnailURL,
+		State: j.State, Priority: j.Priority, Attempts: j.Attempts, Error: j.Err,
+		Added: j.Added, Config: j.Config,
+		Progress: progressView{
+			Percent: j.Progress.Percent, Stage: j.Progress.Stage, Text: j.Progress.Text,
+			DownloadedBytes: j.Progress.DownloadedBytes, TotalBytes: j.Progress.TotalBytes,
+			Speed: j.Progress.Speed, ETA: j.Progress.ETA,
+			PlaylistIndex: j.Progress.PlaylistIndex, PlaylistCount: j.Progress.PlaylistCount,
+			PostProcessor: j.Progress.PostProcessor,
+		},
+	}
+	if j.State == queue.StateFailed && j.ErrKind != downloader.KindUnknown {
+		v.ErrorKind = j.ErrKind.String()
+	}
+	if !j.StartAt.IsZero() {
+		v.StartAt = &j.StartAt
+	}
+	v.Config.ArchivePath = ""
+	v.Config.Network.Proxy = utils.RedactProxy(v.Config.Network.Proxy)
+	return v
+}
+
+// metadata fetches a video's or playlist's metadata, like the Check button
+func (s *Server) metadata(w http.ResponseWriter, r *http.Request) {
+	url := strings.TrimSpace(r.URL.Query().Get("url"))
+	if url == "" {
+		writeError(w, http.StatusBadRequest, "url is required")
+		return
+	}
+	b, err := s.engine.BackendFor(models.DownloadConfig{URL: url, Backend: r.URL.Query().Get("backend")})
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	// No job yet, so only the global network settings apply
+	meta, err := b.GetMetadata(downloader.WithNetwork(r.Context(), utils.Network()), url)
+	if err != nil {
+		var dlErr *downloader.Error
+		if errors.As(err, &dlErr) && dlErr.Kind != downloader.KindUnknown {
+			writeJSON(w, http.StatusBadGateway, map[string]string{"error": err.Error(), "error_kind": dlErr.Kind.String()})
+			return
+		}
+		writeError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, meta)
+}
+
+func (s *Server) listJobs(w http.ResponseWriter, r *http.Request) {
+	jobs := s.queue.Jobs()
+	views := make([]jobView, 0, len(jobs))
+	for _, j := range jobs {
+		if state := r.URL.Query().Get("state"); state != "" && string(j.State) != state {
+			continue
+		}
+		views = append(views, viewJob(j))
+	}
+	writeJSON(w, http.StatusOK, views)
+}
+
+// submitRequest is a DownloadConfig plus queue options. Missing fields take
+// the same defaults as the GUI.
+type submitRequest struct {
+	models.DownloadConfig
+	Priority   int       `json:"priority"`    // -1 low, 0 normal, 1 high
+	StartAt    time.Time `json:"start_at"`    // RFC 3339; omitted starts right away
+	TimeRanges string    `json:"time_ranges"` // Like "1:00-2:30, 50%-"; replaces Ranges
+}
+
+func (s *Server) submitJob(w http.ResponseWriter, r *http.Request) {
+	var req submitRequest
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBody))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON: "+err.Error())
+		return
+	}
+	config := req.DownloadConfig
+	if strings.TrimSpace(config.URL) == "" {
+		writeError(w, http.StatusBadRequest, "URL is required")
+		return
+	}
+	if req.Priority < queue.PriorityLow || req.Priority > queue.PriorityHigh {
+		writeError(w, http.StatusBadRequest, "priority must be -1, 0 or 1")
+		return
+	}
+	if config.OutputTemplate != "" {
+		if err := downloader.ValidateTemplate(config.OutputTemplate); err != nil {
+			writeError(w, http.StatusBadRequest, "OutputTemplate: "+err.Error())
+			return
+		}
+	}
+	if err := utils.ValidateNetwork(config.Network); err != nil {
+		writeError(w, http.StatusBadRequest, "Network: "+err.Error())
+		return
+	}
+	for _, tr := range config.Ranges {
+		if err := downloader.ValidateRange(tr, 0); err != nil {
+			writeError(w, http.StatusBadRequest, "Ranges: "+err.Error())
+			return
+		}
+	}
+	config.Ranges = downloader.MergeRanges(config.Ranges)
+	if config.SubFormat != "" && !slices.Contains(downloader.SubFormats, config.SubFormat) {
+		writeError(w, http.StatusBadRequest, "SubFormat must be srt, vtt or ass")
+		return
+	}
+	if err := downloader.ValidateSponsorBlock(config.SponsorBlock); err != nil {
+		writeError(w, http.StatusBadRequest, "SponsorBlock: "+err.Error())
+		return
+	}
+
+	// Text ranges are checked against the video's length, and the metadata
+	// fetched for that saves the worker its own fetch
+	var meta *models.VideoMetadata
+	if strings.TrimSpace(req.TimeRanges) != "" {
+		b, err := s.engine.BackendFor(config)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		ctx := downloader.WithNetwork(r.Context(), utils.Network().Merge(config.Network))
+		if meta, err = b.GetMetadata(ctx, strings.TrimSpace(config.URL)); err != nil {
+			writeError(w, http.StatusBadGateway, err.Error())
+			return
+		}
+		if config.Ranges, err = downloader.ParseRanges(req.TimeRanges, meta.Duration); err != nil {
+			writeError(w, http.StatusBadRequest, "time_ranges: "+err.Error())
+			return
+		}
+	}
+	s.applyDefaults(&config)
+	if _, err := s.engine.BackendFor(config); err != nil {
+		writeError(w, http.StatusBadRequest, "Backend: "+err.Error())
+		return
+	}
+
+	job := s.queue.SubmitAt(config, meta, req.Priority, req.StartAt)
+	w.Header().Set("Location", fmt.Sprintf("/api/jobs/%d", job.ID))
+	writeJSON(w, http.StatusCreated, viewJob(job))
+}
+
+// applyDefaults fills what the client left out from the saved settings
+func (s *Server) applyDefaults(config *models.DownloadConfig) {
+	config.URL = strings.TrimSpace(config.URL)
+	config.ArchivePath = "" // Set by the queue
+	var settings models.AppSettings
+	if s.db != nil {
+		settings = s.db.LoadSettings()
+	}
+	if config.OutputPath == "" {
+		config.OutputPath = settings.LastSavePath
+	}
+	if config.DownloadMode == "" {
+		config.DownloadMode = "Video"
+	}
+	if config.Quality == "" {
+		config.Quality = "Best"
+		if config.DownloadMode == "Audio" {
+			config.Quality = "mp3"
+		}
+	}
+	if config.Client == "" {
+		config.Client = settings.ClientSpoof
+	}
+	if config.CookiesPath == "" {
+		config.CookiesPath = settings.CookiesPath
+	}
+	if config.Prefs == (models.FormatPrefs{}) {
+		config.Prefs = settings.FormatPrefs
+	}
+	if config.OutputTemplate == "" {
+		config.OutputTemplate = settings.OutputTemplate
+	}
+	if config.UseSponsorBlock && len(config.SponsorBlock) == 0 {
+		config.SponsorBlock = settings.SponsorBlock
+	}
+	if config.PlaylistItems != "" {
+		config.IsPlaylist = true
+	}
+}
+
+// findJob looks up the {id} path value, writing a 404 if there is no such job
+func (s *Server) findJob(w http.ResponseWriter, r *http.Request) (queue.Job, bool) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err == nil {
+		if job, ok := s.queue.Get(id); ok {
+			return job, true
+		}
+	}
+	writeError(w, http.StatusNotFound, "job not found")
+	return queue.Job{}, false
+}
+
+func (s *Server) getJob(w http.ResponseWriter, r *http.Request) {
+	if job, ok := s.findJob(w, r); ok {
+		writeJSON(w, http.StatusOK, viewJob(job))
+	}
+}
+
+func (s *Server) cancelJob(w http.ResponseWriter, r *http.Request) {
+	job, ok := s.findJob(w, r)
+	if !ok {
+		return
+	}
+	if err := s.queue.Cancel(job.ID); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	// A running job reports cancelled once yt-dlp has exited
+	job, _ = s.queue.Get(job.ID)
+	writeJSON(w, http.StatusAccepted, viewJob(job))
+}
+
+// jobAction wraps a queue method that changes a job's state
+func (s *Server) jobAction(fn func(id int) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		job, ok := s.findJob(w, r)
+		if !ok {
+			return
+		}
+		if err := fn(job.ID); err != nil {
+			writeError(w, http.StatusConflict, err.Error())
+			return
+		}
+		job, _ = s.queue.Get(job.ID)
+		writeJSON(w, http.StatusOK, viewJob(job))
+	}
+}
+
+func (s *Server) clearJobs(w http.ResponseWriter, r *http.Request) {
+	s.queue.ClearFinished()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// removeJob drops a job from the list; running jobs must be cancelled first
+func (s *Server) removeJob(w http.ResponseWriter, r *http.Request) {
+	job, ok := s.findJob(w, r)
+	if !ok {
+		return
+	}
+	if err := s.queue.Remove(job.ID); err != nil {
+		writeError(w, http.StatusConflict, err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// events streams job changes as Server-Sent Events. Every job is sent once
+// on connect; after that only the latest state of a changed job is sent, so
+// a slow client skips progress lines instead of holding up the downloads.
+func (s *Server) events(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming unsupported")
+		return
+	}
+
+	stream := newEventStream()
+	unsubscribe := s.queue.Subscribe(stream.push)
+	defer unsubscribe()
+	for _, j := range s.queue.Jobs() {
+		stream.push(j)
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	ticker := time.NewTicker(heartbeat)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		case <-stream.wake:
+			for _, j := range stream.drain() {
+				data, _ := json.Marshal(viewJob(j))
+				if _, err := fmt.Fprintf(w, "event: job\nid: %d\ndata: %s\n\n", j.ID, data); err != nil {
+					return
+				}
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+type historyView struct {
+	ID    int    `json:"id"`
+	Title string `json:"title"`
+	URL   string `json:"url"`
+	Path  string `json:"path"`
+}
+
+func (s *Server) history(w http.ResponseWriter, r *http.Request) {
+	if s.db == nil {
+		writeError(w, http.StatusServiceUnavailable, "database unavailable")
+		return
+	}
+	history := s.db.GetHistory()
+	if limit, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && limit >= 0 && limit < len(history) {
+		history = history[:limit]
+	}
+	views := make([]historyView, len(history))
+	for i, h := range history {
+		views[i] = historyView{ID: h.ID, Title: h.Title, URL: h.URL, Path: h.FilePath}
+	}
+	writeJSON(w, http.StatusOK, views)
+}
+
+// settings returns the saved settings, read-only
+func (s *Server) settings(w http.ResponseWriter, r *http.Request) {
+	if s.db == nil {
+		writeError(w, http.StatusServiceUnavailable, "database unavailable")
+		return
+	}
+	settings := s.db.LoadSettings()
+	settings.Network.Proxy = utils.RedactProxy(settings.Network.Proxy)
+	writeJSON(w, http.StatusOK, settings)
+}
+
+func (s *Server) version(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"version": models.AppVersion})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
@@ -0,0 +1,128 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"gotube/internal/downloader"
+	"gotube/internal/queue"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const testToken = "secret"
+
+func newTestServer(t *testing.T) *httptest.Server {
+	// The binary doesn't exist, so every job fails quickly
+	engine := downloader.NewEngine("/nonexistent/yt-dlp")
+	m := queue.NewManager(engine, nil, 1)
+	t.Cleanup(m.Shutdown)
+	ts := httptest.NewServer(New(m, engine, nil, testToken))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func do(t *testing.T, ts *httptest.Server, method, path, token, body string) *http.Response {
+	t.Helper()
+	req, _ := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func TestAuth(t *testing.T) {
+	ts := newTestServer(t)
+	for _, token := range []string{"", "wrong"} {
+		if resp := do(t, ts, "GET", "/api/jobs", token, ""); resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("token %q: status %d, want 401", token, resp.StatusCode)
+		}
+	}
+	if resp := do(t, ts, "GET", "/api/jobs", testToken, ""); resp.StatusCode != http.StatusOK {
+		t.Errorf("valid token: status %d", resp.StatusCode)
+	}
+	if resp := do(t, ts, "GET", "/api/jobs?token="+testToken, "", ""); resp.StatusCode != http.StatusOK {
+		t.Errorf("query token: status %d", resp.StatusCode)
+	}
+}
+
+func TestSubmitAndGet(t *testing.T) {
+	ts := newTestServer(t)
+	for _, body := range []string{`{`, `{"URL": ""}`, `{"URL": "x", "Bogus": 1}`, `{"URL": "x", "priority": 5}`, `{"URL": "x", "OutputTemplate": "/abs.%(ext)s"}`, `{"URL": "x", "Ranges": [{"Start": 60, "End": 30}]}`, `{"URL": "x", "SponsorBlock": {"ads": "remove"}}`, `{"URL": "x", "Backend": "nope"}`, `{"URL": "https://example.com/a.mp4", "Backend": "http", "WriteSubs": true}`} {
+		if resp := do(t, ts, "POST", "/api/jobs", testToken, body); resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("%s: status %d, want 400", body, resp.StatusCode)
+		}
+	}
+
+	resp := do(t, ts, "POST", "/api/jobs", testToken, `{"URL": "https://example.com/v", "DownloadMode": "Audio", "ArchivePath": "/etc/passwd"}`)
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("submit: status %d", resp.StatusCode)
+	}
+	var job jobView
+	json.NewDecoder(resp.Body).Decode(&job)
+	if job.ID == 0 || job.URL != "https://example.com/v" || job.Config.Quality != "mp3" || job.Config.ArchivePath != "" {
+		t.Errorf("submitted job = %+v", job)
+	}
+	if loc := resp.Header.Get("Location"); loc != "/api/jobs/1" {
+		t.Errorf("Location = %q", loc)
+	}
+
+	if resp := do(t, ts, "GET", "/api/jobs/1", testToken, ""); resp.StatusCode != http.StatusOK {
+		t.Errorf("get: status %d", resp.StatusCode)
+	}
+	if resp := do(t, ts, "GET", "/api/jobs/99", testToken, ""); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("get missing: status %d", resp.StatusCode)
+	}
+	if resp := do(t, ts, "GET", "/api/history", testToken, ""); resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("history without db: status %d", resp.StatusCode)
+	}
+}
+
+func TestEvents(t *testing.T) {
+	ts := newTestServer(t)
+	do(t, ts, "POST", "/api/jobs", testToken, `{"URL": "https://example.com/v"}`)
+
+	resp := do(t, ts, "GET", "/api/events", testToken, "")
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type = %q", ct)
+	}
+	// Read until the job reports a final state
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		var job jobView
+		if err := json.Unmarshal([]byte(data), &job); err != nil {
+			t.Fatal(err)
+		}
+		if job.ID != 1 {
+			t.Fatalf("event for job %d", job.ID)
+		}
+		if job.State.Finished() {
+			return
+		}
+	}
+	t.Fatal("stream ended before the job finished:", scanner.Err())
+}
+
+func TestWebUI(t *testing.T) {
+	ts := newTestServer(t)
+	resp := do(t, ts, "GET", "/", "", "")
+	if resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/html") {
+		t.Errorf("index: status %d, Content-Type %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	resp = do(t, ts, "GET", "/locale.json?lang=de", "", "")
+	var dict map[string]string
+	json.NewDecoder(resp.Body).Decode(&dict)
+	if dict["tab_queue"] != "Warteschlange" {
+		t.Errorf("locale tab_queue = %q", dict["tab_queue"])
+	}
+}
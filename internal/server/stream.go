@@ -0,0 +1,45 @@
+package server
+
+import (
+	"gotube/internal/queue"
+	"sync"
+)
+
+// eventStream coalesces job updates for one SSE client. push never blocks,
+// so a stalled client can't slow down the queue's workers.
+type eventStream struct {
+	mu      sync.Mutex
+	pending map[int]queue.Job
+	order   []int
+	wake    chan struct{}
+}
+
+func newEventStream() *eventStream {
+	return &eventStream{pending: make(map[int]queue.Job), wake: make(chan struct{}, 1)}
+}
+
+func (s *eventStream) push(job queue.Job) {
+	s.mu.Lock()
+	if _, ok := s.pending[job.ID]; !ok {
+		s.order = append(s.order, job.ID)
+	}
+	s.pending[job.ID] = job
+	s.mu.Unlock()
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// drain returns the pending jobs in the order they first changed
+func (s *eventStream) drain() []queue.Job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	jobs := make([]queue.Job, 0, len(s.order))
+	for _, id := range s.order {
+		jobs = append(jobs, s.pending[id])
+	}
+	s.pending = make(map[int]queue.Job)
+	s.order = s.order[:0]
+	return jobs
+}
@@ -0,0 +1,28 @@
+package server
+
+import (
+	"embed"
+	"gotube/internal/database"
+	"gotube/internal/locales"
+	"io/fs"
+	"net/http"
+)
+
+//go:embed web
+var webFiles embed.FS
+
+// webHandler serves the browser UI and its strings. Neither needs the token;
+// the UI asks for it before calling the API.
+func webHandler(db *database.DB) http.Handler {
+	static, _ := fs.Sub(webFiles, "web")
+	mux := http.NewServeMux()
+	mux.Handle("GET /", http.FileServerFS(static))
+	mux.HandleFunc("GET /locale.json", func(w http.ResponseWriter, r *http.Request) {
+		lang := r.URL.Query().Get("lang")
+		if lang == "" && db != nil {
+			lang = db.GetSetting("Language")
+		}
+		writeJSON(w, http.StatusOK, locales.Dict(lang))
+	})
+	return mux
+}
@@ -0,0 +1,497 @@
+// GoTube web UI. Talks to the same API as scripts do; see internal/server.
+"use strict";
+
+const $ = (id) => document.getElementById(id);
+
+const VIDEO_QUALITIES = ["Best", "4k", "1080p", "720p"];
+const AUDIO_QUALITIES = ["Best", "mp3", "m4a", "opus", "flac", "wav", "ogg"];
+
+let strings = {};
+let token = localStorage.getItem("gotube-token") || "";
+let settings = {};
+let meta = null;
+let events = null;
+const jobs = new Map();
+const batchJobs = new Set();
+
+// t looks up a string and fills in %d/%s placeholders in order
+function t(key, ...args) {
+  const s = strings[key] || key;
+  return s.replace(/%[ds]/g, () => (args.length ? args.shift() : ""));
+}
+
+function applyStrings() {
+  document.querySelectorAll("[data-i18n]").forEach((el) => {
+    el.textContent = t(el.dataset.i18n);
+  });
+  document.querySelectorAll("[data-i18n-placeholder]").forEach((el) => {
+    el.placeholder = t(el.dataset.i18nPlaceholder);
+  });
+  document.querySelectorAll(".mode-select").forEach((sel) => {
+    const value = sel.value || "Video";
+    sel.replaceChildren(new Option(t("format_video"), "Video"), new Option(t("format_audio"), "Audio"));
+    sel.value = value;
+  });
+}
+
+async function api(method, path, body) {
+  const resp = await fetch(path, {
+    method,
+    headers: { Authorization: "Bearer " + token, "Content-Type": "application/json" },
+    body: body === undefined ? undefined : JSON.stringify(body),
+  });
+  if (resp.status === 401) {
+    showLogin(token !== "");
+    throw new Error(t("web_token_invalid"));
+  }
+  const data = resp.status === 204 ? null : await resp.json();
+  if (!resp.ok) {
+    const err = new Error(data && data.error ? data.error : resp.statusText);
+    err.kind = data && data.error_kind;
+    throw err;
+  }
+  return data;
+}
+
+// errorText adds the same hint the desktop app shows for classified failures
+function errorText(message, kind) {
+  const hint = kind ? strings["err_" + kind] : "";
+  return hint ? hint + " (" + message + ")" : message;
+}
+
+// Login
+
+function showLogin(invalid) {
+  if (events) {
+    events.close();
+    events = null;
+  }
+  document.querySelectorAll(".tab").forEach((el) => (el.hidden = true));
+  $("login").hidden = false;
+  $("login-error").hidden = !invalid;
+}
+
+$("login-form").addEventListener("submit", (ev) => {
+  ev.preventDefault();
+  token = $("login-token").value.trim();
+  localStorage.setItem("gotube-token", token);
+  start();
+});
+
+// Tabs
+
+function showTab(name) {
+  document.querySelectorAll("nav button").forEach((b) => b.classList.toggle("active", b.dataset.tab === name));
+  document.querySelectorAll(".tab").forEach((el) => (el.hidden = el.id !== name));
+  if (name === "history") {
+    loadHistory();
+  }
+}
+
+document.querySelectorAll("nav button").forEach((b) => {
+  b.addEventListener("click", () => showTab(b.dataset.tab));
+});
+
+function fillQualities(modeSelect, qualitySelect) {
+  const audio = modeSelect.value === "Audio";
+  const options = audio ? AUDIO_QUALITIES : VIDEO_QUALITIES;
+  qualitySelect.replaceChildren(...options.map((q) => new Option(q, q)));
+  qualitySelect.value = audio ? "mp3" : "Best";
+}
+
+// Download tab
+
+function formatBytes(n) {
+  const units = ["B", "KiB", "MiB", "GiB", "TiB"];
+  let i = 0;
+  while (n >= 1024 && i < units.length - 1) {
+    n /= 1024;
+    i++;
+  }
+  return (i === 0 ? n : n.toFixed(1)) + " " + units[i];
+}
+
+function formatTime(seconds) {
+  const h = Math.floor(seconds / 3600);
+  const m = Math.floor((seconds % 3600) / 60);
+  const s = String(seconds % 60).padStart(2, "0");
+  return h > 0 ? h + ":" + String(m).padStart(2, "0") + ":" + s : m + ":" + s;
+}
+
+function formatLabel(f, duration) {
+  const parts = [f.format_id, f.ext];
+  if (f.vcodec && f.vcodec !== "none") {
+    parts.push(f.resolution || f.height + "p");
+    if (f.fps) parts.push(Math.round(f.fps) + "fps");
+    if (f.dynamic_range && f.dynamic_range !== "SDR") parts.push(f.dynamic_range);
+    parts.push(f.vcodec.split(".")[0]);
+  }
+  if (f.acodec && f.acodec !== "none") {
+    parts.push(f.acodec.split(".")[0]);
+  }
+  const size = f.filesize || f.filesize_approx || (f.tbr && duration ? f.tbr * 125 * duration : 0);
+  if (size) parts.push((f.filesize ? "" : "~") + formatBytes(size));
+  return parts.join(" · ");
+}
+
+function showMetadata(m) {
+  meta = m;
+  $("dl-meta").hidden = false;
+  $("dl-thumb").src = m.thumbnail || "";
+  $("dl-thumb").hidden = !m.thumbnail;
+  $("dl-title").textContent = m.title;
+  const info = [m.uploader || m.channel];
+  if (m._type !== "playlist" && m.duration) {
+    info.push(formatTime(m.duration));
+  }
+  $("dl-info").textContent = info.filter(Boolean).join(" • ");
+
+  const playlist = m._type === "playlist";
+  $("dl-playlist").hidden = !playlist;
+  $("dl-playlist-mode").checked = playlist;
+  $("dl-entries").replaceChildren(
+    ...(m.entries || []).map((e, i) => {
+      const li = document.createElement("li");
+      const label = document.createElement("label");
+      const box = document.createElement("input");
+      box.type = "checkbox";
+      box.checked = true;
+      box.value = i + 1;
+      box.addEventListener("change", updateSelectedCount);
+      label.append(box, " ", e.title || e.id);
+      li.append(label);
+      return li;
+    })
+  );
+  updateSelectedCount();
+
+  const formats = m.formats || [];
+  $("dl-format-field").hidden = formats.length === 0;
+  $("dl-format").replaceChildren(
+    new Option(t("prefs_auto"), ""),
+    ...formats.map((f) => new Option(formatLabel(f, m.duration), f.format_id))
+  );
+}
+
+function selectedEntries() {
+  return [...$("dl-entries").querySelectorAll("input:checked")].map((b) => b.value);
+}
+
+function updateSelectedCount() {
+  $("dl-playlist-count").textContent = t("pl_selected", selectedEntries().length);
+}
+
+function setAllEntries(checked) {
+  $("dl-entries").querySelectorAll("input").forEach((b) => (b.checked = checked));
+  updateSelectedCount();
+}
+
+$("dl-select-all").addEventListener("click", () => setAllEntries(true));
+$("dl-select-none").addEventListener("click", () => setAllEntries(false));
+
+$("check-form").addEventListener("submit", async (ev) => {
+  ev.preventDefault();
+  const url = $("dl-url").value.trim();
+  $("dl-status").textContent = t("fetching");
+  $("dl-status").classList.remove("error");
+  try {
+    showMetadata(await api("GET", "/api/metadata?url=" + encodeURIComponent(url)));
+    $("dl-status").textContent = t("meta_loaded");
+  } catch (err) {
+    meta = null;
+    $("dl-meta").hidden = true;
+    $("dl-playlist").hidden = true;
+    $("dl-format-field").hidden = true;
+    $("dl-status").textContent = errorText(err.message, err.kind);
+    $("dl-status").classList.add("error");
+  }
+});
+
+$("dl-url").addEventListener("input", () => {
+  // A format or playlist selection only applies to the link it was made for
+  if (meta) {
+    meta = null;
+    $("dl-meta").hidden = true;
+    $("dl-playlist").hidden = true;
+    $("dl-format-field").hidden = true;
+  }
+});
+
+$("dl-mode").addEventListener("change", () => fillQualities($("dl-mode"), $("dl-quality")));
+$("batch-mode").addEventListener("change", () => fillQualities($("batch-mode"), $("batch-quality")));
+
+$("dl-form").addEventListener("submit", async (ev) => {
+  ev.preventDefault();
+  const url = $("dl-url").value.trim();
+  if (!url) {
+    $("dl-url").reportValidity();
+    return;
+  }
+  const config = {
+    URL: url,
+    OutputPath: $("dl-output").value.trim(),
+    DownloadMode: $("dl-mode").value,
+    Quality: $("dl-quality").value,
+    time_ranges: $("dl-ranges").value.trim(),
+    ConcatRanges: $("dl-concat").checked,
+    UseSponsorBlock: $("dl-sponsor").checked,
+    Client: $("dl-client").value,
+    SafeMode: $("dl-safe").checked,
+    EmbedSubs: $("dl-subs").checked,
+    AutoSubs: $("dl-auto-subs").checked,
+    SubLanguage: $("dl-sub-lang").value,
+    WriteSubs: $("dl-write-subs").checked,
+    SubFormat: $("dl-sub-format").value,
+    OutputTemplate: $("dl-template").value.trim(),
+    ForceRedownload: $("dl-force").checked,
+  };
+  if (meta && meta._type === "playlist" && $("dl-playlist-mode").checked) {
+    config.IsPlaylist = true;
+    const items = selectedEntries();
+    if (items.length < (meta.entries || []).length) {
+      config.PlaylistItems = items.join(",");
+    }
+  } else if (meta && $("dl-format").value) {
+    config.FormatID = $("dl-format").value;
+  }
+  try {
+    const job = await api("POST", "/api/jobs", config);
+    jobs.set(job.id, job);
+    renderQueue();
+    $("dl-status").textContent = t("queued") + ": " + job.title;
+    $("dl-status").classList.remove("error");
+  } catch (err) {
+    $("dl-status").textContent = err.message;
+    $("dl-status").classList.add("error");
+  }
+});
+
+// Batch tab
+
+$("batch-form").addEventListener("submit", async (ev) => {
+  ev.preventDefault();
+  const urls = $("batch-urls").value.split("\n").map((l) => l.trim()).filter((l) => l && !l.startsWith("#"));
+  const base = {
+    OutputPath: $("batch-output").value.trim(),
+    DownloadMode: $("batch-mode").value,
+    Quality: $("batch-quality").value,
+    UseSponsorBlock: $("batch-sponsor").checked,
+    SafeMode: $("batch-safe").checked,
+    ForceRedownload: $("batch-force").checked,
+  };
+
+  // Start a fresh summary unless the previous batch is still running
+  if (![...batchJobs].some((id) => jobs.has(id) && !finished(jobs.get(id).state))) {
+    batchJobs.clear();
+  }
+  for (const url of urls) {
+    try {
+      const job = await api("POST", "/api/jobs", { ...base, URL: url });
+      jobs.set(job.id, job);
+      batchJobs.add(job.id);
+    } catch (err) {
+      $("batch-status").textContent = url + ": " + err.message;
+      return;
+    }
+  }
+  $("batch-urls").value = "";
+  renderQueue();
+});
+
+function renderBatch() {
+  const states = [...batchJobs].filter((id) => jobs.has(id)).map((id) => jobs.get(id).state);
+  if (states.length === 0) {
+    return;
+  }
+  const done = states.filter((s) => finished(s)).length;
+  const failed = states.filter((s) => s === "failed").length;
+  const ok = states.filter((s) => s === "done").length;
+  $("batch-progress").hidden = false;
+  $("batch-progress").value = done / states.length;
+  $("batch-status").textContent =
+    done === states.length ? t("batch_done", ok, failed) : t("batch_status", done, states.length, failed);
+}
+
+// Queue tab
+
+function finished(state) {
+  return state === "done" || state === "failed" || state === "cancelled";
+}
+
+function active(state) {
+  return state === "fetching" || state === "downloading" || state === "post-processing";
+}
+
+function jobDetail(job) {
+  const p = job.progress;
+  if (job.state === "failed") {
+    return errorText(job.error, job.error_kind);
+  }
+  if (job.state === "done") {
+    return job.config.OutputPath;
+  }
+  const parts = [];
+  if (p.post_processor) parts.push(p.post_processor);
+  if (p.stage === "Retrying" && p.text) parts.push(p.text);
+  if (p.speed) parts.push(formatBytes(p.speed) + "/s");
+  if (p.eta) parts.push("ETA " + formatTime(p.eta));
+  if (p.total_bytes) parts.push(formatBytes(p.downloaded_bytes) + " / " + formatBytes(p.total_bytes));
+  if (p.playlist_count > 1) parts.push("item " + p.playlist_index + "/" + p.playlist_count);
+  return parts.join(" • ");
+}
+
+function renderQueue() {
+  const list = [...jobs.values()];
+  $("queue-empty").hidden = list.length > 0;
+  const running = list.filter((j) => active(j.state)).length;
+  const waiting = list.filter((j) => j.state === "queued").length;
+  $("queue-summary").textContent = t("queue_summary", running, waiting);
+
+  const template = $("job-template").content;
+  $("queue-list").replaceChildren(
+    ...list.map((job) => {
+      const li = template.firstElementChild.cloneNode(true);
+      li.classList.add(job.state);
+      li.querySelector(".thumb").src = job.thumbnail || "";
+      li.querySelector(".thumb").hidden = !job.thumbnail;
+      li.querySelector(".title").textContent = job.title;
+      li.querySelector(".title").title = job.url;
+      li.querySelector(".badge").textContent = t("state_" + job.state);
+      li.querySelector("progress").value = job.state === "done" ? 1 : job.progress.percent;
+      li.querySelector(".detail").textContent = jobDetail(job);
+      const show = {
+        pause: job.state === "queued" || active(job.state),
+        resume: job.state === "paused",
+        cancel: !finished(job.state),
+        retry: job.state === "failed" || job.state === "cancelled",
+        remove: !active(job.state),
+      };
+      li.querySelectorAll("[data-action]").forEach((b) => {
+        b.textContent = t(b.dataset.i18n);
+        b.hidden = !show[b.dataset.action];
+        b.addEventListener("click", () => jobAction(job.id, b.dataset.action));
+      });
+      return li;
+    })
+  );
+  renderBatch();
+}
+
+// scheduleRender redraws at most once per frame while progress streams in
+let renderPending = false;
+function scheduleRender() {
+  if (!renderPending) {
+    renderPending = true;
+    requestAnimationFrame(() => {
+      renderPending = false;
+      renderQueue();
+    });
+  }
+}
+
+async function jobAction(id, action) {
+  try {
+    if (action === "remove") {
+      await api("DELETE", "/api/jobs/" + id);
+      jobs.delete(id);
+    } else {
+      const job = await api("POST", "/api/jobs/" + id + "/" + action);
+      jobs.set(job.id, job);
+    }
+  } catch (err) {
+    alert(err.message);
+  }
+  renderQueue();
+}
+
+$("queue-clear").addEventListener("click", async () => {
+  await api("POST", "/api/jobs/clear");
+  await loadJobs();
+});
+
+async function loadJobs() {
+  jobs.clear();
+  for (const job of await api("GET", "/api/jobs")) {
+    jobs.set(job.id, job);
+  }
+  renderQueue();
+}
+
+// Progress arrives as Server-Sent Events; the browser reconnects on its own
+function connectEvents() {
+  events = new EventSource("/api/events?token=" + encodeURIComponent(token));
+  events.addEventListener("job", (ev) => {
+    const job = JSON.parse(ev.data);
+    jobs.set(job.id, job);
+    scheduleRender();
+  });
+  events.addEventListener("open", () => ($("offline").hidden = true));
+  events.addEventListener("error", () => ($("offline").hidden = false));
+}
+
+// History tab
+
+async function loadHistory() {
+  const history = await api("GET", "/api/history");
+  $("history-empty").hidden = history.length > 0;
+  $("history-list").replaceChildren(
+    ...history.map((h) => {
+      const tr = document.createElement("tr");
+      const title = document.createElement("td");
+      const link = document.createElement("a");
+      link.href = h.url;
+      link.target = "_blank";
+      link.rel = "noopener";
+      link.textContent = h.title;
+      title.append(link);
+      const path = document.createElement("td");
+      path.className = "muted";
+      path.textContent = h.path;
+      tr.append(title, path);
+      return tr;
+    })
+  );
+}
+
+// Startup
+
+async function start() {
+  try {
+    settings = await api("GET", "/api/settings");
+  } catch (err) {
+    if ($("login").hidden) {
+      $("dl-status").textContent = err.message;
+    }
+    return;
+  }
+  $("login").hidden = true;
+  document.querySelectorAll(".output-path").forEach((el) => (el.value = el.value || settings.LastSavePath || ""));
+  $("dl-template").value = $("dl-template").value || settings.OutputTemplate || "";
+  if (settings.ClientSpoof) {
+    $("dl-client").value = settings.ClientSpoof;
+  }
+  showTab("download");
+  await loadJobs();
+  connectEvents();
+}
+
+async function init() {
+  // "gotube serve" prints a link with the token in the fragment
+  const match = location.hash.match(/token=([^&]+)/);
+  if (match) {
+    token = decodeURIComponent(match[1]);
+    localStorage.setItem("gotube-token", token);
+    history.replaceState(null, "", location.pathname);
+  }
+  strings = await (await fetch("locale.json")).json();
+  applyStrings();
+  fillQualities($("dl-mode"), $("dl-quality"));
+  fillQualities($("batch-mode"), $("batch-quality"));
+  if (!token) {
+    showLogin(false);
+    return;
+  }
+  start();
+}
+
+init();
@@ -0,0 +1,229 @@
+// Package subscriptions checks channels and playlists for new uploads and
+// queues them with each subscription's download preset.
+package subscriptions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gotube/internal/database"
+	"gotube/internal/downloader"
+	"gotube/internal/models"
+	"gotube/internal/queue"
+	"gotube/internal/utils"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultInterval is how often a new subscription is checked, in minutes
+const DefaultInterval = 360
+
+// tick is how often the scheduler looks for subscriptions that are due
+const tick = time.Minute
+
+var (
+	ErrNotPlaylist = errors.New("not a channel or playlist")
+	ErrBadDate     = errors.New("date must be YYYYMMDD")
+)
+
+var dateRegex = regexp.MustCompile(`^\d{8}$`)
+
+// channelRegex matches a YouTube channel's home page, which yt-dlp lists as
+// its tabs (Videos, Shorts, Live) rather than as videos
+var channelRegex = regexp.MustCompile(`^/(@[^/]+|channel/[^/]+|c/[^/]+|user/[^/]+)/?$`)
+
+// Scheduler checks enabled subscriptions when they are due. Checks of the
+// same subscription never overlap.
+type Scheduler struct {
+	engine *downloader.Engine
+	db     *database.DB
+	queue  *queue.Manager
+
+	// Logger receives a line per check. Optional.
+	Logger *utils.LogBuffer
+	// OnChecked is called after every check with the updated subscription,
+	// the number of queued videos and the error, if any. Optional.
+	OnChecked func(sub models.Subscription, queued int, err error)
+
+	mu       sync.Mutex
+	checking map[int]bool
+}
+
+func NewScheduler(engine *downloader.Engine, db *database.DB, q *queue.Manager) *Scheduler {
+	return &Scheduler{engine: engine, db: db, queue: q, checking: make(map[int]bool)}
+}
+
+// Validate checks the user-editable fields of a subscription
+func Validate(sub models.Subscription) error {
+	u, err := url.Parse(strings.TrimSpace(sub.URL))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid URL %q", sub.URL)
+	}
+	if sub.DateAfter != "" && !dateRegex.MatchString(sub.DateAfter) {
+		return ErrBadDate
+	}
+	if sub.Interval < 1 || sub.MaxItems < 0 {
+		return errors.New("interval must be positive and max items not negative")
+	}
+	return nil
+}
+
+// Run checks due subscriptions until ctx is cancelled
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+	for {
+		s.CheckDue(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// CheckDue checks every enabled subscription whose interval has passed
+func (s *Scheduler) CheckDue(ctx context.Context) {
+	subs, err := s.db.ListSubscriptions()
+	if err != nil {
+		s.log("Could not load subscriptions: " + err.Error())
+		return
+	}
+	now := time.Now().Unix()
+	for _, sub := range subs {
+		if ctx.Err() != nil {
+			return
+		}
+		if sub.Enabled && now >= sub.LastChecked+int64(sub.Interval)*60 {
+			s.Check(ctx, sub)
+		}
+	}
+}
+
+// Check fetches the subscription's video list, queues the entries that are
+// newer than the last check and saves the result. See NewEntries for the
+// first check.
+func (s *Scheduler) Check(ctx context.Context, sub models.Subscription) (int, error) {
+	s.mu.Lock()
+	if s.checking[sub.ID] {
+		s.mu.Unlock()
+		return 0, nil
+	}
+	s.checking[sub.ID] = true
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.checking, sub.ID)
+		s.mu.Unlock()
+	}()
+
+	queued, err := s.check(ctx, &sub)
+	if ctx.Err() != nil {
+		return queued, err // Check again next time
+	}
+	sub.LastChecked = time.Now().Unix()
+	if saveErr := s.db.SaveSubscription(&sub); saveErr != nil && err == nil {
+		err = saveErr
+	}
+
+	name := sub.Title
+	if name == "" {
+		name = sub.URL
+	}
+	if err != nil {
+		s.log(fmt.Sprintf("Subscription %s: %v", name, err))
+	} else {
+		s.log(fmt.Sprintf("Subscription %s: %d new video(s) queued", name, queued))
+	}
+	if s.OnChecked != nil {
+		s.OnChecked(sub, queued, err)
+	}
+	return queued, err
+}
+
+func (s *Scheduler) check(ctx context.Context, sub *models.Subscription) (int, error) {
+	// The listing goes through the same network as the videos will
+	ctx = downloader.WithNetwork(ctx, utils.Network().Merge(sub.Config.Network))
+	meta, err := s.engine.GetMetadata(ctx, ListURL(sub.URL))
+	if err != nil {
+		return 0, err
+	}
+	if meta.Type != "playlist" {
+		return 0, ErrNotPlaylist
+	}
+	if meta.Title != "" {
+		sub.Title = meta.Title
+	}
+
+	entries := NewEntries(meta.Entries, sub.LastSeenID, sub.MaxItems)
+	// Oldest first, so the queue follows the upload order
+	queued := 0
+	for i := len(entries) - 1; i >= 0; i-- {
+		e := entries[i]
+		if e.URL == "" {
+			continue // Not downloadable on its own
+		}
+		config := sub.Config
+		config.URL = e.URL
+		config.IsPlaylist = false
+		config.PlaylistItems = ""
+		config.DateAfter = sub.DateAfter
+		s.queue.Submit(config, nil, queue.PriorityNormal)
+		queued++
+	}
+	if len(meta.Entries) > 0 {
+		sub.LastSeenID = meta.Entries[0].ID
+	}
+	return queued, nil
+}
+
+// Entries queued when the last seen video has vanished from the list and
+// new ones can't be told from old ones
+const resyncItems = 5
+
+// NewEntries returns the entries listed before lastSeen, newest first as
+// channels list them, capped at maxItems (0 means no cap). The first check,
+// without lastSeen, returns only the maxItems newest, so a channel is never
+// fetched whole. If lastSeen has vanished from the list (e.g. the video was
+// deleted or made private) it returns at most the resyncItems newest.
+func NewEntries(entries []models.PlaylistEntry, lastSeen string, maxItems int) []models.PlaylistEntry {
+	if lastSeen == "" && maxItems == 0 {
+		return nil
+	}
+	fresh := entries
+	found := false
+	for i, e := range entries {
+		if lastSeen != "" && e.ID == lastSeen {
+			fresh = entries[:i]
+			found = true
+			break
+		}
+	}
+	if lastSeen != "" && !found && (maxItems == 0 || maxItems > resyncItems) {
+		maxItems = resyncItems
+	}
+	if maxItems > 0 && len(fresh) > maxItems {
+		fresh = fresh[:maxItems]
+	}
+	return fresh
+}
+
+// ListURL points a YouTube channel's home page at its Videos tab
+func ListURL(raw string) string {
+	raw = strings.TrimSpace(raw)
+	u, err := url.Parse(raw)
+	if err != nil || !strings.HasSuffix(u.Hostname(), "youtube.com") || !channelRegex.MatchString(u.Path) {
+		return raw
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/videos"
+	return u.String()
+}
+
+func (s *Scheduler) log(text string) {
+	if s.Logger != nil {
+		s.Logger.Write(text)
+	}
+}
@@ -0,0 +1,224 @@
+package subscriptions
+
+import (
+	"context"
+	"errors"
+	"gotube/internal/database"
+	"gotube/internal/downloader"
+	"gotube/internal/models"
+	"gotube/internal/queue"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// fakeBackend stands in for yt-dlp. It lists the playlists in lists and
+// treats every other URL as a video. A listing in block waits for its
+// channel to close, or for ctx.
+type fakeBackend struct {
+	lists   map[string]*models.VideoMetadata
+	block   map[string]chan struct{}
+	listing chan string // Receives every listed URL
+}
+
+func (f *fakeBackend) Name() string { return downloader.BackendYtDlp }
+
+func (f *fakeBackend) GetMetadata(ctx context.Context, url string) (*models.VideoMetadata, error) {
+	meta, ok := f.lists[url]
+	if !ok {
+		return &models.VideoMetadata{Title: url, Type: "video"}, nil
+	}
+	f.listing <- url
+	if wait, ok := f.block[url]; ok {
+		select {
+		case <-wait:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	return meta, nil
+}
+
+func (f *fakeBackend) ListFormats(ctx context.Context, url string) ([]models.Format, error) {
+	return nil, nil
+}
+
+func (f *fakeBackend) Download(ctx context.Context, config models.DownloadConfig, callback func(models.ProgressUpdate)) ([]string, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (f *fakeBackend) Version(ctx context.Context) (string, error) { return "fake", nil }
+
+func (f *fakeBackend) Update(ctx context.Context, progress func(string)) error { return nil }
+
+// newTestScheduler runs a scheduler against a database in a temp home
+func newTestScheduler(t *testing.T, fake *fakeBackend) (*Scheduler, *database.DB, *queue.Manager) {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	db, err := database.InitDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fake.listing = make(chan string, 10)
+	engine := downloader.NewEngine("")
+	engine.Register(fake)
+	m := queue.NewManager(engine, nil, 1)
+	t.Cleanup(m.Shutdown)
+	return NewScheduler(engine, db, m), db, m
+}
+
+// addSubscription saves a subscription and returns it with its new ID
+func addSubscription(t *testing.T, db *database.DB, sub models.Subscription) models.Subscription {
+	t.Helper()
+	sub.Interval, sub.Enabled = DefaultInterval, true
+	if err := db.SaveSubscription(&sub); err != nil {
+		t.Fatal(err)
+	}
+	return sub
+}
+
+// saved loads the stored copy of a subscription
+func saved(t *testing.T, db *database.DB, id int) models.Subscription {
+	t.Helper()
+	subs, err := db.ListSubscriptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range subs {
+		if s.ID == id {
+			return s
+		}
+	}
+	t.Fatalf("subscription %d not saved", id)
+	return models.Subscription{}
+}
+
+func TestNewEntries(t *testing.T) {
+	entries := []models.PlaylistEntry{{ID: "h"}, {ID: "g"}, {ID: "f"}, {ID: "e"}, {ID: "d"}, {ID: "c"}, {ID: "b"}, {ID: "a"}}
+	ids := func(es []models.PlaylistEntry) []string {
+		var out []string
+		for _, e := range es {
+			out = append(out, e.ID)
+		}
+		return out
+	}
+	tests := []struct {
+		name     string
+		lastSeen string
+		max      int
+		want     []string
+	}{
+		{"first check", "", 0, nil},
+		{"first check with max", "", 2, []string{"h", "g"}},
+		{"two new", "f", 0, []string{"h", "g"}},
+		{"two new capped", "f", 1, []string{"h"}},
+		{"nothing new", "h", 0, nil},
+		{"last seen gone", "x", 0, []string{"h", "g", "f", "e", "d"}},
+		{"last seen gone with max", "x", 3, []string{"h", "g", "f"}},
+		{"last seen gone with high max", "x", 7, []string{"h", "g", "f", "e", "d"}},
+	}
+	for _, tt := range tests {
+		if got := ids(NewEntries(entries, tt.lastSeen, tt.max)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestListURL(t *testing.T) {
+	tests := map[string]string{
+		"https://www.youtube.com/@chan":             "https://www.youtube.com/@chan/videos",
+		"https://www.youtube.com/channel/UC123/":    "https://www.youtube.com/channel/UC123/videos",
+		"https://www.youtube.com/@chan/streams":     "https://www.youtube.com/@chan/streams",
+		"https://www.youtube.com/playlist?list=PL1": "https://www.youtube.com/playlist?list=PL1",
+		"https://vimeo.com/@chan":                   "https://vimeo.com/@chan",
+	}
+	for in, want := range tests {
+		if got := ListURL(in); got != want {
+			t.Errorf("ListURL(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestCheckQueuesOldestFirst(t *testing.T) {
+	fake := &fakeBackend{lists: map[string]*models.VideoMetadata{
+		"https://example.com/list": {Title: "Uploads", Type: "playlist", Entries: []models.PlaylistEntry{
+			{ID: "c", URL: "https://example.com/c"},
+			{ID: "b", URL: "https://example.com/b"},
+			{ID: "a", URL: "https://example.com/a"},
+		}},
+	}}
+	s, db, m := newTestScheduler(t, fake)
+	sub := addSubscription(t, db, models.Subscription{URL: "https://example.com/list", LastSeenID: "a"})
+
+	before := time.Now().Unix()
+	queued, err := s.Check(context.Background(), sub)
+	if err != nil || queued != 2 {
+		t.Fatalf("Check = %d, %v, want 2 queued", queued, err)
+	}
+	var urls []string
+	for _, j := range m.Jobs() {
+		urls = append(urls, j.Config.URL)
+	}
+	if want := []string{"https://example.com/b", "https://example.com/c"}; !reflect.DeepEqual(urls, want) {
+		t.Errorf("queued %v, want %v", urls, want)
+	}
+	got := saved(t, db, sub.ID)
+	if got.LastSeenID != "c" || got.Title != "Uploads" || got.LastChecked < before {
+		t.Errorf("saved %+v, want last seen c, title Uploads, checked now", got)
+	}
+}
+
+func TestCheckRejectsVideo(t *testing.T) {
+	fake := &fakeBackend{lists: map[string]*models.VideoMetadata{
+		"https://example.com/watch": {Title: "One video", Type: "video"},
+	}}
+	s, db, m := newTestScheduler(t, fake)
+	sub := addSubscription(t, db, models.Subscription{URL: "https://example.com/watch"})
+
+	if _, err := s.Check(context.Background(), sub); !errors.Is(err, ErrNotPlaylist) {
+		t.Fatalf("Check error = %v, want ErrNotPlaylist", err)
+	}
+	if n := len(m.Jobs()); n != 0 {
+		t.Errorf("%d jobs queued", n)
+	}
+	if saved(t, db, sub.ID).LastChecked == 0 {
+		t.Error("failed check not recorded")
+	}
+}
+
+func TestCheckCancelledAndOverlapping(t *testing.T) {
+	list := "https://example.com/list"
+	fake := &fakeBackend{
+		lists: map[string]*models.VideoMetadata{list: {Type: "playlist", Entries: []models.PlaylistEntry{{ID: "a", URL: "https://example.com/a"}}}},
+		block: map[string]chan struct{}{list: make(chan struct{})},
+	}
+	s, db, _ := newTestScheduler(t, fake)
+	sub := addSubscription(t, db, models.Subscription{URL: list})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		_, err := s.Check(ctx, sub)
+		done <- err
+	}()
+	<-fake.listing
+
+	// A second check of the same subscription returns at once
+	if queued, err := s.Check(context.Background(), sub); queued != 0 || err != nil {
+		t.Errorf("overlapping Check = %d, %v", queued, err)
+	}
+	select {
+	case url := <-fake.listing:
+		t.Errorf("overlapping Check listed %s", url)
+	default:
+	}
+
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("cancelled Check error = %v", err)
+	}
+	if got := saved(t, db, sub.ID); got.LastChecked != 0 {
+		t.Errorf("cancelled check was saved: %+v", got)
+	}
+}
@@ -4,8 +4,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"gotube/internal/models"
+	"gotube/internal/utils"
 	"io"
-	"net/http"
 	"os"
 	"os/exec"
 	"runtime"
@@ -30,7 +30,11 @@ func isAppImage() bool {
 
 // CheckAppUpdate returns the new version tag and download URL if an update exists
 func CheckAppUpdate() (string, string, error) {
-	resp, err := http.Get(repoURL)
+	client, err := utils.HTTPClient(0)
+	if err != nil {
+		return "", "", err
+	}
+	resp, err := client.Get(repoURL)
 	if err != nil {
 		return "", "", err
 	}
@@ -94,7 +98,11 @@ func DoAppUpdate(url string, progress func(float64)) error {
 	}
 
 	// 2. Download to .new file
-	resp, err := http.Get(url)
+	client, err := utils.HTTPClient(0)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Get(url)
 	if err != nil {
 		return err
 	}
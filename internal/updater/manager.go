@@ -42,7 +42,9 @@ func (bm *BinaryManager) UpdateBinary(progress func(string)) error {
 	destPath := filepath.Join(bm.ConfigDir, localName)
 
 	progress(fmt.Sprintf("Fetching from: %s", url))
-	resp, err := http.Get(url)
+	client, err := utils.HTTPClient(0)
+	if err != nil { return fmt.Errorf("network settings: %v", err) }
+	resp, err := client.Get(url)
 	if err != nil { return fmt.Errorf("network error: %v", err) }
 	defer resp.Body.Close()
 